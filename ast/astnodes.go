@@ -1,6 +1,13 @@
-package main
+// Package ast defines the PArL abstract syntax tree and its visitor interface.
+package ast
 
-import "fmt"
+import (
+	"fmt"
+
+	"github.com/giuszeppe/compiler-theory/diag"
+	"github.com/giuszeppe/compiler-theory/lexer"
+	"github.com/giuszeppe/compiler-theory/types"
+)
 
 // ==== Visitor Interface ====
 
@@ -35,17 +42,110 @@ type ASTVisitor interface {
 	VisitColorNode(node *ASTColorNode)
 	VisitReturnNode(node *ASTReturnNode)
 	VisitArrayNode(node *ASTArrayNode)
+	VisitErrorNode(node *ASTErrorNode)
 }
 
 // ==== AST Node Interface ====
 
 type ASTNode interface {
 	Accept(visitor ASTVisitor)
+	Span() diag.Span
+	SetSpan(span diag.Span)
+}
+
+// Loc is the source range a node was parsed from. Every node embeds one;
+// the parser fills it in as rules are reduced.
+type Loc struct {
+	Range diag.Span
+}
+
+func (l *Loc) Span() diag.Span {
+	return l.Range
+}
+
+func (l *Loc) SetSpan(span diag.Span) {
+	l.Range = span
+}
+
+// Typed is the type semantic analysis found for an expression. Every
+// expression node embeds one, so that later passes read the checked type
+// rather than working it out again.
+type Typed struct {
+	typ types.Type
+}
+
+// ExprType returns the checked type of the expression: nil before
+// checking, or if the expression has no value, and types.Invalid if it is
+// in error.
+func (t *Typed) ExprType() types.Type {
+	return t.typ
+}
+
+func (t *Typed) SetExprType(typ types.Type) {
+	t.typ = typ
+}
+
+// Expr is an expression node.
+type Expr interface {
+	ASTNode
+	ExprType() types.Type
+	SetExprType(typ types.Type)
+}
+
+// Slot is where a variable lives at run time: slot Index of the frame
+// declared Depth frames inside the outermost one. Arrays take one slot per
+// element, starting at Index.
+type Slot struct {
+	Depth, Index int
+}
+
+// Capture is a variable declared outside a function that the function
+// reads or assigns. Its value is passed in after the arguments of each
+// call, into slot Index of the function's frame. If Written, the function
+// assigns it, itself or through the functions it calls, and passes the
+// value in slot Index back when it returns.
+type Capture struct {
+	Decl    *ASTVarDeclNode
+	Index   int
+	Written bool
+}
+
+// Cover returns the smallest span enclosing the spans of nodes. Nodes
+// without a position are skipped, as are empty spans (such as those of ε
+// productions) unless nothing else is left.
+func Cover(nodes ...ASTNode) diag.Span {
+	var span, empty diag.Span
+	for _, n := range nodes {
+		if n == nil {
+			continue
+		}
+		s := n.Span()
+		if !s.Start.IsValid() {
+			continue
+		}
+		if s.Start.Offset == s.End.Offset {
+			if !empty.Start.IsValid() {
+				empty = s
+			}
+			continue
+		}
+		if !span.Start.IsValid() || s.Start.Offset < span.Start.Offset {
+			span.Start = s.Start
+		}
+		if !span.End.IsValid() || s.End.Offset > span.End.Offset {
+			span.End = s.End
+		}
+	}
+	if !span.Start.IsValid() {
+		return empty
+	}
+	return span
 }
 
 // ==== AST Node Structs ====
 
 type ASTProgramNode struct {
+	Loc
 	Block ASTBlockNode
 }
 
@@ -54,6 +154,8 @@ func (p *ASTProgramNode) Accept(visitor ASTVisitor) {
 }
 
 type ASTIntegerNode struct {
+	Loc
+	Typed
 	Name  string
 	Value int
 }
@@ -63,8 +165,12 @@ func (n *ASTIntegerNode) Accept(visitor ASTVisitor) {
 }
 
 type ASTVariableNode struct {
-	Token  Token
+	Loc
+	Typed
+	Token  lexer.Token
 	Offset ASTNode
+	// Decl is the variable the name refers to, once it is resolved.
+	Decl *ASTVarDeclNode
 }
 
 func (n *ASTVariableNode) Accept(visitor ASTVisitor) {
@@ -72,6 +178,7 @@ func (n *ASTVariableNode) Accept(visitor ASTVisitor) {
 }
 
 type ASTAssignmentNode struct {
+	Loc
 	Id   ASTVariableNode // usually a VariableNode
 	Expr ASTNode         // usually an Expression Node
 }
@@ -81,6 +188,7 @@ func (n *ASTAssignmentNode) Accept(visitor ASTVisitor) {
 }
 
 type ASTBlockNode struct {
+	Loc
 	Name  string
 	Stmts []ASTNode
 }
@@ -90,16 +198,20 @@ func (n *ASTBlockNode) Accept(visitor ASTVisitor) {
 }
 
 type ASTTypeNode struct {
-	Name string
+	Loc
+	Type types.Type
 }
 
 func (n *ASTTypeNode) Accept(visitor ASTVisitor) {
 }
 
 type ASTVarDeclNode struct {
-	Token      Token
-	Type       string
+	Loc
+	Token      lexer.Token
+	Type       types.Type
 	Expression ASTNode
+	// Slot is assigned when names are resolved.
+	Slot Slot
 }
 
 func (n *ASTVarDeclNode) Accept(visitor ASTVisitor) {
@@ -107,8 +219,9 @@ func (n *ASTVarDeclNode) Accept(visitor ASTVisitor) {
 }
 
 type ASTExpressionNode struct {
+	Loc
+	Typed
 	Expr ASTNode
-	Type string
 }
 
 func (n *ASTExpressionNode) Accept(visitor ASTVisitor) {
@@ -116,14 +229,16 @@ func (n *ASTExpressionNode) Accept(visitor ASTVisitor) {
 }
 
 type ASTLiteralNode struct {
-	Token Token
+	Loc
+	Token lexer.Token
 }
 
 func (n *ASTLiteralNode) Accept(visitor ASTVisitor) {
 }
 
 type ASTSimpleExpression struct {
-	Token Token
+	Loc
+	Token lexer.Token
 }
 
 func (n *ASTSimpleExpression) Accept(visitor ASTVisitor) {
@@ -131,7 +246,9 @@ func (n *ASTSimpleExpression) Accept(visitor ASTVisitor) {
 }
 
 type ASTBinaryOpNode struct {
-	Token    Token
+	Loc
+	Typed
+	Token    lexer.Token
 	Operator string
 	Left     ASTNode
 	Right    ASTNode
@@ -143,6 +260,7 @@ func (b *ASTBinaryOpNode) Accept(visitor ASTVisitor) {
 }
 
 type ASTPrintNode struct {
+	Loc
 	Expr ASTExpressionNode
 }
 
@@ -151,15 +269,18 @@ func (b *ASTPrintNode) Accept(visitor ASTVisitor) {
 	visitor.VisitPrintNode(b)
 }
 
-type ASTEpsilon struct{}
+type ASTEpsilon struct {
+	Loc
+}
 
 func (b *ASTEpsilon) Accept(visitor ASTVisitor) {
 	visitor.VisitEpsilon(b)
 }
 
 type ASTOpList struct {
+	Loc
 	Pairs []struct {
-		Op    Token
+		Op    lexer.Token
 		Right ASTNode
 	}
 }
@@ -169,6 +290,7 @@ func (b *ASTOpList) Accept(visitor ASTVisitor) {
 }
 
 type ASTIfNode struct {
+	Loc
 	Condition ASTNode
 	ThenBlock ASTNode
 	ElseBlock ASTNode
@@ -179,6 +301,7 @@ func (n *ASTIfNode) Accept(visitor ASTVisitor) {
 }
 
 type ASTWhileNode struct {
+	Loc
 	Condition ASTNode
 	Block     ASTNode
 }
@@ -188,7 +311,9 @@ func (n *ASTWhileNode) Accept(visitor ASTVisitor) {
 }
 
 type ASTTypeCastNode struct {
-	Type string
+	Loc
+	Typed
+	Type types.Type
 	Expr ASTNode
 }
 
@@ -197,6 +322,7 @@ func (n *ASTTypeCastNode) Accept(visitor ASTVisitor) {
 }
 
 type ASTForNode struct {
+	Loc
 	VarDecl   ASTNode
 	Condition ASTNode
 	Increment ASTNode
@@ -208,6 +334,7 @@ func (n *ASTForNode) Accept(visitor ASTVisitor) {
 }
 
 type ASTFormalParamsNode struct {
+	Loc
 	Params []ASTNode
 }
 
@@ -216,10 +343,13 @@ func (n *ASTFormalParamsNode) Accept(visitor ASTVisitor) {
 }
 
 type ASTFuncDeclNode struct {
-	Token      Token
-	ReturnType string
+	Loc
+	Token      lexer.Token
+	ReturnType types.Type
 	Params     ASTNode
 	Block      ASTNode
+	// Captures are found when names are resolved.
+	Captures []Capture
 }
 
 func (n *ASTFuncDeclNode) Accept(visitor ASTVisitor) {
@@ -227,8 +357,9 @@ func (n *ASTFuncDeclNode) Accept(visitor ASTVisitor) {
 }
 
 type ASTFormalParamNode struct {
+	Loc
 	Name string
-	Type string
+	Type types.Type
 }
 
 func (n *ASTFormalParamNode) Accept(visitor ASTVisitor) {
@@ -236,6 +367,8 @@ func (n *ASTFormalParamNode) Accept(visitor ASTVisitor) {
 }
 
 type ASTFloatNode struct {
+	Loc
+	Typed
 	Name  string
 	Value float64
 }
@@ -245,7 +378,9 @@ func (n *ASTFloatNode) Accept(visitor ASTVisitor) {
 }
 
 type ASTBuiltinFuncNode struct {
-	Token Token
+	Loc
+	Typed
+	Token lexer.Token
 	Args  []ASTNode
 }
 
@@ -254,8 +389,12 @@ func (n *ASTBuiltinFuncNode) Accept(visitor ASTVisitor) {
 }
 
 type ASTFuncCallNode struct {
-	Name   Token
+	Loc
+	Typed
+	Name   lexer.Token
 	Params ASTNode
+	// Decl is the function called, once the name is resolved.
+	Decl *ASTFuncDeclNode
 }
 
 func (n *ASTFuncCallNode) Accept(visitor ASTVisitor) {
@@ -263,6 +402,7 @@ func (n *ASTFuncCallNode) Accept(visitor ASTVisitor) {
 }
 
 type ASTActualParamsNode struct {
+	Loc
 	Params []ASTNode
 }
 
@@ -271,8 +411,9 @@ func (n *ASTActualParamsNode) Accept(visitor ASTVisitor) {
 }
 
 type ASTActualParamNode struct {
+	Loc
 	Value ASTNode
-	Type  string
+	Type  types.Type
 }
 
 func (n *ASTActualParamNode) Accept(visitor ASTVisitor) {
@@ -280,6 +421,8 @@ func (n *ASTActualParamNode) Accept(visitor ASTVisitor) {
 }
 
 type ASTUnaryOpNode struct {
+	Loc
+	Typed
 	Operator string
 	Operand  ASTNode
 }
@@ -290,6 +433,8 @@ func (n *ASTUnaryOpNode) Accept(visitor ASTVisitor) {
 }
 
 type ASTBooleanNode struct {
+	Loc
+	Typed
 	Value bool
 }
 
@@ -299,7 +444,9 @@ func (n *ASTBooleanNode) Accept(visitor ASTVisitor) {
 }
 
 type ASTColorNode struct {
-	Token Token
+	Loc
+	Typed
+	Token lexer.Token
 	Value string
 }
 
@@ -309,7 +456,8 @@ func (n *ASTColorNode) Accept(visitor ASTVisitor) {
 }
 
 type ASTReturnNode struct {
-	Token Token
+	Loc
+	Token lexer.Token
 	Expr  ASTNode
 }
 
@@ -319,13 +467,29 @@ func (n *ASTReturnNode) Accept(visitor ASTVisitor) {
 }
 
 type ASTArrayNode struct {
-	Type  string
+	Loc
+	Typed
+	Type  types.Type
 	Items []ASTNode
 	Size  int
-	Token Token
+	// Sized reports whether the declaration gives the length, as in
+	// int[3], rather than leaving it to the items, as in int[].
+	Sized bool
+	Token lexer.Token
 }
 
 func (n *ASTArrayNode) Accept(visitor ASTVisitor) {
 	// Implement the Accept method for ASTArrayNode
 	visitor.VisitArrayNode(n)
 }
+
+// ASTErrorNode stands in for a statement the parser could not parse; Skipped
+// holds the tokens that were discarded while resynchronising.
+type ASTErrorNode struct {
+	Loc
+	Skipped []lexer.Token
+}
+
+func (n *ASTErrorNode) Accept(visitor ASTVisitor) {
+	visitor.VisitErrorNode(n)
+}
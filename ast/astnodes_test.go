@@ -1,4 +1,4 @@
-package main
+package ast
 
 // func TestPrintingVisitor(t *testing.T) {
 // 	printVisitor := NewPrintNodesVisitor()
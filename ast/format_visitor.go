@@ -0,0 +1,405 @@
+package ast
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/giuszeppe/compiler-theory/lexer"
+	"github.com/giuszeppe/compiler-theory/types"
+)
+
+// FormatVisitor prints a program back as canonical PArL source: four-space
+// indentation, one statement per line, spaced binary operators and only the
+// parentheses the grammar needs.
+//
+// Comments are not part of the AST, so the visitor is given the comment
+// tokens of the source and weaves them back in by position. A comment before
+// a statement stays on its own line above it, one on the line a statement
+// ends on (or inside a simple statement) is printed after it, and one before
+// a closing brace stays at the end of the block. Runs of blank lines between
+// statements are kept as a single blank line.
+type FormatVisitor struct {
+	Name     string
+	Out      io.Writer
+	TabCount int
+
+	comments []lexer.Token
+	// lastLine is the source line the last printed statement or comment
+	// ended on, used to keep blank lines.
+	lastLine int
+}
+
+func NewFormatVisitor(comments []lexer.Token) *FormatVisitor {
+	return &FormatVisitor{Name: "Format Visitor", Out: os.Stdout, comments: comments}
+}
+
+// Operator precedence, loosest first. A subexpression looser than its
+// context is parenthesised.
+const (
+	precCast = iota
+	precRelational
+	precAdditive
+	precMultiplicative
+	precUnary
+)
+
+func precedence(node ASTNode) int {
+	switch n := node.(type) {
+	case *ASTTypeCastNode:
+		return precCast
+	case *ASTBinaryOpNode:
+		switch n.Token.Type {
+		case lexer.RelOpToken:
+			return precRelational
+		case lexer.PlusToken, lexer.MinusToken, lexer.OrToken:
+			return precAdditive
+		default:
+			return precMultiplicative
+		}
+	}
+	return precUnary
+}
+
+func (v *FormatVisitor) write(s string) {
+	fmt.Fprint(v.Out, s)
+}
+
+func (v *FormatVisitor) newline() {
+	v.write("\n")
+}
+
+func (v *FormatVisitor) indent() {
+	v.write(strings.Repeat("    ", v.TabCount))
+}
+
+// expr prints node, parenthesised if it binds looser than min.
+func (v *FormatVisitor) expr(node ASTNode, min int) {
+	if precedence(node) < min {
+		v.write("(")
+		node.Accept(v)
+		v.write(")")
+		return
+	}
+	node.Accept(v)
+}
+
+func (v *FormatVisitor) exprList(nodes []ASTNode) {
+	for i, n := range nodes {
+		if i > 0 {
+			v.write(", ")
+		}
+		v.expr(n, precCast)
+	}
+}
+
+func isEpsilon(node ASTNode) bool {
+	_, ok := node.(*ASTEpsilon)
+	return node == nil || ok
+}
+
+// needsSemicolon reports whether stmt is a simple statement ended by ';'.
+func needsSemicolon(stmt ASTNode) bool {
+	switch stmt.(type) {
+	case *ASTIfNode, *ASTWhileNode, *ASTForNode, *ASTFuncDeclNode, *ASTBlockNode:
+		return false
+	}
+	return true
+}
+
+func commentEndLine(c lexer.Token) int {
+	return c.Line + strings.Count(c.Lexeme, "\n")
+}
+
+func commentText(c lexer.Token) string {
+	if c.Type == lexer.CommentSingleLine {
+		return strings.TrimRight(c.Lexeme, " \t\r")
+	}
+	return c.Lexeme
+}
+
+// commentLines prints, each on its own line, the pending comments that start
+// before offset. first reports whether nothing has been printed yet in the
+// enclosing block; it is cleared once something is.
+func (v *FormatVisitor) commentLines(offset int, first *bool) {
+	for len(v.comments) > 0 && v.comments[0].Offset < offset {
+		c := v.comments[0]
+		v.comments = v.comments[1:]
+		if !*first && c.Line > v.lastLine+1 {
+			v.newline()
+		}
+		v.indent()
+		v.write(commentText(c))
+		v.newline()
+		v.lastLine = commentEndLine(c)
+		*first = false
+	}
+}
+
+// stmts prints the statements of a block, one per line, with the comments
+// that come before end.
+func (v *FormatVisitor) stmts(stmts []ASTNode, end int) {
+	first := true
+	for _, stmt := range stmts {
+		span := stmt.Span()
+		v.commentLines(span.Start.Offset, &first)
+		if !first && span.Start.Line > v.lastLine+1 {
+			v.newline()
+		}
+		first = false
+
+		v.indent()
+		stmt.Accept(v)
+		if needsSemicolon(stmt) {
+			v.write(";")
+		}
+		v.lastLine = span.End.Line
+
+		// comments left inside the statement or after it on the same line;
+		// a line comment runs to the end of the line, so everything after
+		// one goes on a line of its own, where formatting again keeps it
+		inline := true
+		for len(v.comments) > 0 {
+			c := v.comments[0]
+			if c.Offset >= span.End.Offset && c.Line != span.End.Line {
+				break
+			}
+			v.comments = v.comments[1:]
+			if inline {
+				v.write(" ")
+			} else {
+				v.newline()
+				v.indent()
+			}
+			v.write(commentText(c))
+			v.lastLine = max(v.lastLine, commentEndLine(c))
+			inline = inline && c.Type != lexer.CommentSingleLine
+		}
+		v.newline()
+	}
+	v.commentLines(end, &first)
+}
+
+// block prints a braced block. The opening brace goes on the current line.
+func (v *FormatVisitor) block(node ASTNode) {
+	blk, ok := node.(*ASTBlockNode)
+	if !ok {
+		node.Accept(v)
+		return
+	}
+	end := blk.Span().End.Offset
+	if len(blk.Stmts) == 0 && (len(v.comments) == 0 || v.comments[0].Offset >= end) {
+		v.write("{}")
+		return
+	}
+	v.write("{")
+	v.newline()
+	v.TabCount++
+	v.stmts(blk.Stmts, end)
+	v.TabCount--
+	v.indent()
+	v.write("}")
+}
+
+// VisitProgramNode prints the program's statements without braces. A
+// program wrapped in a single block parses to the same tree as the bare
+// statements, so the braces are dropped.
+func (v *FormatVisitor) VisitProgramNode(node *ASTProgramNode) {
+	v.stmts(node.Block.Stmts, int(^uint(0)>>1))
+}
+
+func (v *FormatVisitor) VisitBlockNode(node *ASTBlockNode) {
+	v.block(node)
+}
+
+// VisitVarDeclNode prints the declared type as written: an array whose
+// length is left to its items stays unsized.
+func (v *FormatVisitor) VisitVarDeclNode(node *ASTVarDeclNode) {
+	typ := node.Type.String()
+	if arr, ok := node.Expression.(*ASTArrayNode); ok && !arr.Sized {
+		typ = arr.Type.(types.Array).Elem.String() + "[]"
+	}
+	fmt.Fprintf(v.Out, "let %s:%s", node.Token.Lexeme, typ)
+	if !isEpsilon(node.Expression) {
+		v.write(" = ")
+		v.expr(node.Expression, precCast)
+	}
+}
+
+func (v *FormatVisitor) VisitAssignmentNode(node *ASTAssignmentNode) {
+	node.Id.Accept(v)
+	v.write(" = ")
+	v.expr(node.Expr, precCast)
+}
+
+func (v *FormatVisitor) VisitIfNode(node *ASTIfNode) {
+	v.write("if (")
+	v.expr(node.Condition, precCast)
+	v.write(") ")
+	v.block(node.ThenBlock)
+	if !isEpsilon(node.ElseBlock) {
+		v.write(" else ")
+		v.block(node.ElseBlock)
+	}
+}
+
+func (v *FormatVisitor) VisitWhileNode(node *ASTWhileNode) {
+	v.write("while (")
+	v.expr(node.Condition, precCast)
+	v.write(") ")
+	v.block(node.Block)
+}
+
+func (v *FormatVisitor) VisitForNode(node *ASTForNode) {
+	v.write("for (")
+	if !isEpsilon(node.VarDecl) {
+		node.VarDecl.Accept(v)
+	}
+	v.write("; ")
+	v.expr(node.Condition, precCast)
+	v.write(";")
+	if !isEpsilon(node.Increment) {
+		v.write(" ")
+		node.Increment.Accept(v)
+	}
+	v.write(") ")
+	v.block(node.Block)
+}
+
+func (v *FormatVisitor) VisitFuncDeclNode(node *ASTFuncDeclNode) {
+	fmt.Fprintf(v.Out, "fun %s(", node.Token.Lexeme)
+	node.Params.Accept(v)
+	fmt.Fprintf(v.Out, ") -> %s ", node.ReturnType)
+	v.block(node.Block)
+}
+
+func (v *FormatVisitor) VisitFormalParamsNode(node *ASTFormalParamsNode) {
+	for i, param := range node.Params {
+		if i > 0 {
+			v.write(", ")
+		}
+		if decl, ok := param.(*ASTVarDeclNode); ok {
+			fmt.Fprintf(v.Out, "%s:%s", decl.Token.Lexeme, decl.Type)
+			continue
+		}
+		param.Accept(v)
+	}
+}
+
+func (v *FormatVisitor) VisitFormalParamNode(node *ASTFormalParamNode) {
+	fmt.Fprintf(v.Out, "%s:%s", node.Name, node.Type)
+}
+
+func (v *FormatVisitor) VisitReturnNode(node *ASTReturnNode) {
+	v.write("return ")
+	v.expr(node.Expr, precCast)
+}
+
+func (v *FormatVisitor) VisitBuiltinFuncNode(node *ASTBuiltinFuncNode) {
+	v.write(node.Token.Lexeme)
+	switch node.Token.Type {
+	case lexer.PadWidth, lexer.PadHeight:
+	case lexer.PadRead, lexer.PadRandI:
+		v.write("(")
+		v.exprList(node.Args)
+		v.write(")")
+	default:
+		v.write(" ")
+		v.exprList(node.Args)
+	}
+}
+
+func (v *FormatVisitor) VisitPrintNode(node *ASTPrintNode) {
+	v.write("__print ")
+	v.expr(&node.Expr, precCast)
+}
+
+func (v *FormatVisitor) VisitErrorNode(node *ASTErrorNode) {
+	lexemes := make([]string, len(node.Skipped))
+	for i, tok := range node.Skipped {
+		lexemes[i] = tok.Lexeme
+	}
+	v.write(strings.Join(lexemes, " "))
+}
+
+func (v *FormatVisitor) VisitBinaryOpNode(node *ASTBinaryOpNode) {
+	prec := precedence(node)
+	// operators are left associative
+	v.expr(node.Left, prec)
+	fmt.Fprintf(v.Out, " %s ", node.Operator)
+	v.expr(node.Right, prec+1)
+}
+
+func (v *FormatVisitor) VisitUnaryOpNode(node *ASTUnaryOpNode) {
+	v.write(node.Operator)
+	if node.Operator == "not" {
+		v.write(" ")
+	}
+	v.expr(node.Operand, precUnary)
+}
+
+func (v *FormatVisitor) VisitTypeCastNode(node *ASTTypeCastNode) {
+	v.expr(node.Expr, precRelational)
+	fmt.Fprintf(v.Out, " as %s", node.Type)
+}
+
+func (v *FormatVisitor) VisitExpressionNode(node *ASTExpressionNode) {
+	v.expr(node.Expr, precCast)
+}
+
+func (v *FormatVisitor) VisitVariableNode(node *ASTVariableNode) {
+	v.write(node.Token.Lexeme)
+	if !isEpsilon(node.Offset) {
+		v.write("[")
+		v.expr(node.Offset, precCast)
+		v.write("]")
+	}
+}
+
+func (v *FormatVisitor) VisitFuncCallNode(node *ASTFuncCallNode) {
+	v.write(node.Name.Lexeme)
+	v.write("(")
+	node.Params.Accept(v)
+	v.write(")")
+}
+
+func (v *FormatVisitor) VisitActualParamsNode(node *ASTActualParamsNode) {
+	v.exprList(node.Params)
+}
+
+func (v *FormatVisitor) VisitActualParamNode(node *ASTActualParamNode) {
+	v.expr(node.Value, precCast)
+}
+
+func (v *FormatVisitor) VisitArrayNode(node *ASTArrayNode) {
+	v.write("[")
+	v.exprList(node.Items)
+	v.write("]")
+}
+
+func (v *FormatVisitor) VisitIntegerNode(node *ASTIntegerNode) {
+	v.write(node.Name)
+}
+
+func (v *FormatVisitor) VisitFloatNode(node *ASTFloatNode) {
+	v.write(node.Name)
+}
+
+func (v *FormatVisitor) VisitBooleanNode(node *ASTBooleanNode) {
+	fmt.Fprint(v.Out, node.Value)
+}
+
+func (v *FormatVisitor) VisitColorNode(node *ASTColorNode) {
+	v.write(node.Value)
+}
+
+func (v *FormatVisitor) VisitSimpleExpressionNode(node *ASTSimpleExpression) {
+	v.write(node.Token.Lexeme)
+}
+
+func (v *FormatVisitor) VisitTypeNode(node *ASTTypeNode) {
+	v.write(node.Type.String())
+}
+
+func (v *FormatVisitor) VisitEpsilon(node *ASTEpsilon) {}
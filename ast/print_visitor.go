@@ -1,7 +1,9 @@
-package main
+package ast
 
 import (
 	"fmt"
+	"io"
+	"os"
 	"strings"
 )
 
@@ -9,10 +11,11 @@ type PrintNodesVisitor struct {
 	Name      string
 	NodeCount int
 	TabCount  int
+	Out       io.Writer
 }
 
 func NewPrintNodesVisitor() *PrintNodesVisitor {
-	return &PrintNodesVisitor{Name: "Print Tree Visitor"}
+	return &PrintNodesVisitor{Name: "Print Tree Visitor", Out: os.Stdout}
 }
 
 func (v *PrintNodesVisitor) IncTabCount() {
@@ -25,17 +28,17 @@ func (v *PrintNodesVisitor) DecTabCount() {
 
 func (v *PrintNodesVisitor) VisitIntegerNode(node *ASTIntegerNode) {
 	v.NodeCount++
-	fmt.Println(strings.Repeat("\t", v.TabCount), "Integer value::", node.Value)
+	fmt.Fprintln(v.Out, strings.Repeat("\t", v.TabCount), "Integer value::", node.Value)
 }
 
 func (v *PrintNodesVisitor) VisitVariableNode(node *ASTVariableNode) {
 	v.NodeCount++
-	fmt.Println(strings.Repeat("\t", v.TabCount), "Variable =>", node.Token.Lexeme)
+	fmt.Fprintln(v.Out, strings.Repeat("\t", v.TabCount), "Variable =>", node.Token.Lexeme)
 }
 
 func (v *PrintNodesVisitor) VisitAssignmentNode(node *ASTAssignmentNode) {
 	v.NodeCount++
-	fmt.Println(strings.Repeat("\t", v.TabCount), "Assignment node =>")
+	fmt.Fprintln(v.Out, strings.Repeat("\t", v.TabCount), "Assignment node =>")
 	v.IncTabCount()
 	node.Id.Accept(v)
 	node.Expr.Accept(v)
@@ -43,8 +46,8 @@ func (v *PrintNodesVisitor) VisitAssignmentNode(node *ASTAssignmentNode) {
 }
 func (v *PrintNodesVisitor) VisitVarDeclNode(node *ASTVarDeclNode) {
 	v.NodeCount++
-	fmt.Print(strings.Repeat("\t", v.TabCount), "Var decl node => ")
-	fmt.Printf("%v %v\n", node.Token.Lexeme, node.Type)
+	fmt.Fprint(v.Out, strings.Repeat("\t", v.TabCount), "Var decl node => ")
+	fmt.Fprintf(v.Out, "%v %v\n", node.Token.Lexeme, node.Type)
 	v.IncTabCount()
 	node.Expression.Accept(v)
 
@@ -53,7 +56,7 @@ func (v *PrintNodesVisitor) VisitVarDeclNode(node *ASTVarDeclNode) {
 
 func (v *PrintNodesVisitor) VisitBlockNode(node *ASTBlockNode) {
 	v.NodeCount++
-	fmt.Println(strings.Repeat("\t", v.TabCount), "New Block =>")
+	fmt.Fprintln(v.Out, strings.Repeat("\t", v.TabCount), "New Block =>")
 	v.IncTabCount()
 	for _, stmt := range node.Stmts {
 		stmt.Accept(v)
@@ -63,7 +66,7 @@ func (v *PrintNodesVisitor) VisitBlockNode(node *ASTBlockNode) {
 
 func (v *PrintNodesVisitor) VisitBinaryOpNode(node *ASTBinaryOpNode) {
 	v.NodeCount++
-	fmt.Println(strings.Repeat("\t", v.TabCount), "Binary Op node =>", node.Operator)
+	fmt.Fprintln(v.Out, strings.Repeat("\t", v.TabCount), "Binary Op node =>", node.Operator)
 	v.IncTabCount()
 	node.Left.Accept(v)
 	node.Right.Accept(v)
@@ -71,28 +74,31 @@ func (v *PrintNodesVisitor) VisitBinaryOpNode(node *ASTBinaryOpNode) {
 }
 func (v *PrintNodesVisitor) VisitExpressionNode(node *ASTExpressionNode) {
 	v.NodeCount++
-	fmt.Print(strings.Repeat("\t", v.TabCount), "Expression node =>")
-	fmt.Printf("%v\n", node.Type)
+	fmt.Fprint(v.Out, strings.Repeat("\t", v.TabCount), "Expression node =>")
+	if typ := node.ExprType(); typ != nil {
+		fmt.Fprint(v.Out, typ)
+	}
+	fmt.Fprintln(v.Out)
 	v.IncTabCount()
 	node.Expr.Accept(v)
 	v.DecTabCount()
 }
 func (v *PrintNodesVisitor) VisitSimpleExpressionNode(node *ASTSimpleExpression) {
 	v.NodeCount++
-	fmt.Println(strings.Repeat("\t", v.TabCount), "Simple node =>")
+	fmt.Fprintln(v.Out, strings.Repeat("\t", v.TabCount), "Simple node =>")
 	v.IncTabCount()
 	v.DecTabCount()
 }
 func (v *PrintNodesVisitor) VisitProgramNode(node *ASTProgramNode) {
 	v.NodeCount++
-	fmt.Println(strings.Repeat("\t", v.TabCount), "Program node =>")
+	fmt.Fprintln(v.Out, strings.Repeat("\t", v.TabCount), "Program node =>")
 	node.Block.Accept(v)
 	v.IncTabCount()
 	v.DecTabCount()
 }
 func (v *PrintNodesVisitor) VisitPrintNode(node *ASTPrintNode) {
 	v.NodeCount++
-	fmt.Println(strings.Repeat("\t", v.TabCount), "Print node =>")
+	fmt.Fprintln(v.Out, strings.Repeat("\t", v.TabCount), "Print node =>")
 	node.Expr.Accept(v)
 	v.IncTabCount()
 	v.DecTabCount()
@@ -100,13 +106,13 @@ func (v *PrintNodesVisitor) VisitPrintNode(node *ASTPrintNode) {
 
 func (v *PrintNodesVisitor) VisitIfNode(node *ASTIfNode) {
 	v.NodeCount++
-	fmt.Println(strings.Repeat("\t", v.TabCount), "If node =>")
+	fmt.Fprintln(v.Out, strings.Repeat("\t", v.TabCount), "If node =>")
 	v.IncTabCount()
 	node.Condition.Accept(v)
 	node.ThenBlock.Accept(v)
 	v.DecTabCount()
 	v.DecTabCount()
-	fmt.Println(strings.Repeat("\t", v.TabCount), "Else Block =>")
+	fmt.Fprintln(v.Out, strings.Repeat("\t", v.TabCount), "Else Block =>")
 	v.IncTabCount()
 	if node.ElseBlock != nil {
 		node.ElseBlock.Accept(v)
@@ -116,7 +122,7 @@ func (v *PrintNodesVisitor) VisitIfNode(node *ASTIfNode) {
 
 func (v *PrintNodesVisitor) VisitWhileNode(node *ASTWhileNode) {
 	v.NodeCount++
-	fmt.Println(strings.Repeat("\t", v.TabCount), "While node =>")
+	fmt.Fprintln(v.Out, strings.Repeat("\t", v.TabCount), "While node =>")
 	v.IncTabCount()
 	node.Condition.Accept(v)
 	node.Block.Accept(v)
@@ -125,39 +131,39 @@ func (v *PrintNodesVisitor) VisitWhileNode(node *ASTWhileNode) {
 
 func (v *PrintNodesVisitor) VisitTypeCastNode(node *ASTTypeCastNode) {
 	v.NodeCount++
-	fmt.Println(strings.Repeat("\t", v.TabCount), "Type cast node:: ", node.Type, " =>")
+	fmt.Fprintln(v.Out, strings.Repeat("\t", v.TabCount), "Type cast node:: ", node.Type, " =>")
 	v.IncTabCount()
 	node.Expr.Accept(v)
 	v.DecTabCount()
 }
 func (v *PrintNodesVisitor) VisitEpsilon(node *ASTEpsilon) {
 	v.NodeCount++
-	fmt.Println(strings.Repeat("\t", v.TabCount), "Epsilon node")
+	fmt.Fprintln(v.Out, strings.Repeat("\t", v.TabCount), "Epsilon node")
 	v.IncTabCount()
 	v.DecTabCount()
 }
 
 func (v *PrintNodesVisitor) VisitForNode(node *ASTForNode) {
 	v.NodeCount++
-	fmt.Println(strings.Repeat("\t", v.TabCount), "For node =>")
+	fmt.Fprintln(v.Out, strings.Repeat("\t", v.TabCount), "For node =>")
 	v.IncTabCount()
 
-	fmt.Println(strings.Repeat("\t", v.TabCount), "For var decl =>")
+	fmt.Fprintln(v.Out, strings.Repeat("\t", v.TabCount), "For var decl =>")
 	v.IncTabCount()
 	node.VarDecl.Accept(v)
 	v.DecTabCount()
 
-	fmt.Println(strings.Repeat("\t", v.TabCount), "For condition =>")
+	fmt.Fprintln(v.Out, strings.Repeat("\t", v.TabCount), "For condition =>")
 	v.IncTabCount()
 	node.Condition.Accept(v)
 	v.DecTabCount()
 
-	fmt.Println(strings.Repeat("\t", v.TabCount), "For increment =>")
+	fmt.Fprintln(v.Out, strings.Repeat("\t", v.TabCount), "For increment =>")
 	v.IncTabCount()
 	node.Increment.Accept(v)
 	v.DecTabCount()
 
-	fmt.Println(strings.Repeat("\t", v.TabCount), "For block =>")
+	fmt.Fprintln(v.Out, strings.Repeat("\t", v.TabCount), "For block =>")
 	v.IncTabCount()
 	node.Block.Accept(v)
 	v.DecTabCount()
@@ -165,22 +171,22 @@ func (v *PrintNodesVisitor) VisitForNode(node *ASTForNode) {
 
 func (v *PrintNodesVisitor) VisitFuncDeclNode(node *ASTFuncDeclNode) {
 	v.NodeCount++
-	fmt.Println(strings.Repeat("\t", v.TabCount), "Function decl node =>", node.Token.Lexeme, ":", node.ReturnType)
+	fmt.Fprintln(v.Out, strings.Repeat("\t", v.TabCount), "Function decl node =>", node.Token.Lexeme, ":", node.ReturnType)
 	v.IncTabCount()
 
-	fmt.Println(strings.Repeat("\t", v.TabCount), "Function params =>")
+	fmt.Fprintln(v.Out, strings.Repeat("\t", v.TabCount), "Function params =>")
 	v.IncTabCount()
 	node.Params.Accept(v)
 	v.DecTabCount()
 
-	fmt.Println(strings.Repeat("\t", v.TabCount), "Function block =>")
+	fmt.Fprintln(v.Out, strings.Repeat("\t", v.TabCount), "Function block =>")
 	v.IncTabCount()
 	node.Block.Accept(v)
 	v.DecTabCount()
 }
 func (v *PrintNodesVisitor) VisitFormalParamsNode(node *ASTFormalParamsNode) {
 	v.NodeCount++
-	fmt.Println(strings.Repeat("\t", v.TabCount), "Formal params node =>")
+	fmt.Fprintln(v.Out, strings.Repeat("\t", v.TabCount), "Formal params node =>")
 	v.IncTabCount()
 	for _, param := range node.Params {
 		param.Accept(v)
@@ -189,24 +195,24 @@ func (v *PrintNodesVisitor) VisitFormalParamsNode(node *ASTFormalParamsNode) {
 }
 func (v *PrintNodesVisitor) VisitFormalParamNode(node *ASTFormalParamNode) {
 	v.NodeCount++
-	fmt.Println(strings.Repeat("\t", v.TabCount), "Formal param node:: ", node.Name, ":", node.Type)
+	fmt.Fprintln(v.Out, strings.Repeat("\t", v.TabCount), "Formal param node:: ", node.Name, ":", node.Type)
 }
 func (v *PrintNodesVisitor) VisitTypeNode(node *ASTTypeNode) {
 	v.NodeCount++
-	fmt.Println(strings.Repeat("\t", v.TabCount), "Type node =>")
+	fmt.Fprintln(v.Out, strings.Repeat("\t", v.TabCount), "Type node =>")
 	v.IncTabCount()
-	fmt.Printf("%v\n", node.Name)
+	fmt.Fprintf(v.Out, "%v\n", node.Type)
 	v.DecTabCount()
 }
 
 func (v *PrintNodesVisitor) VisitFloatNode(node *ASTFloatNode) {
 	v.NodeCount++
-	fmt.Println(strings.Repeat("\t", v.TabCount), "Float value::", node.Value)
+	fmt.Fprintln(v.Out, strings.Repeat("\t", v.TabCount), "Float value::", node.Value)
 }
 
 func (v *PrintNodesVisitor) VisitBuiltinFuncNode(node *ASTBuiltinFuncNode) {
 	v.NodeCount++
-	fmt.Println(strings.Repeat("\t", v.TabCount), "Builtin function node =>", node.Token.Lexeme)
+	fmt.Fprintln(v.Out, strings.Repeat("\t", v.TabCount), "Builtin function node =>", node.Token.Lexeme)
 	v.IncTabCount()
 	for _, arg := range node.Args {
 		arg.Accept(v)
@@ -216,14 +222,14 @@ func (v *PrintNodesVisitor) VisitBuiltinFuncNode(node *ASTBuiltinFuncNode) {
 
 func (v *PrintNodesVisitor) VisitFuncCallNode(node *ASTFuncCallNode) {
 	v.NodeCount++
-	fmt.Println(strings.Repeat("\t", v.TabCount), "Function call node =>", node.Name)
+	fmt.Fprintln(v.Out, strings.Repeat("\t", v.TabCount), "Function call node =>", node.Name)
 	v.IncTabCount()
 	node.Params.Accept(v)
 	v.DecTabCount()
 }
 func (v *PrintNodesVisitor) VisitActualParamsNode(node *ASTActualParamsNode) {
 	v.NodeCount++
-	fmt.Println(strings.Repeat("\t", v.TabCount), "Actual params node =>")
+	fmt.Fprintln(v.Out, strings.Repeat("\t", v.TabCount), "Actual params node =>")
 	v.IncTabCount()
 	for _, param := range node.Params {
 		param.Accept(v)
@@ -232,41 +238,46 @@ func (v *PrintNodesVisitor) VisitActualParamsNode(node *ASTActualParamsNode) {
 }
 func (v *PrintNodesVisitor) VisitActualParamNode(node *ASTActualParamNode) {
 	v.NodeCount++
-	fmt.Println(strings.Repeat("\t", v.TabCount), "Actual param node =>")
+	fmt.Fprintln(v.Out, strings.Repeat("\t", v.TabCount), "Actual param node =>")
 	v.IncTabCount()
 	v.DecTabCount()
 }
 
 func (v *PrintNodesVisitor) VisitUnaryOpNode(node *ASTUnaryOpNode) {
 	v.NodeCount++
-	fmt.Println(strings.Repeat("\t", v.TabCount), "Unary Op node =>", node.Operator)
+	fmt.Fprintln(v.Out, strings.Repeat("\t", v.TabCount), "Unary Op node =>", node.Operator)
 	v.IncTabCount()
 	node.Operand.Accept(v)
 	v.DecTabCount()
 }
 func (v *PrintNodesVisitor) VisitReturnNode(node *ASTReturnNode) {
 	v.NodeCount++
-	fmt.Println(strings.Repeat("\t", v.TabCount), "Return node =>")
+	fmt.Fprintln(v.Out, strings.Repeat("\t", v.TabCount), "Return node =>")
 	v.IncTabCount()
 	node.Expr.Accept(v)
 	v.DecTabCount()
 }
 func (v *PrintNodesVisitor) VisitColorNode(node *ASTColorNode) {
 	v.NodeCount++
-	fmt.Println(strings.Repeat("\t", v.TabCount), "Color node :: ", node.Value)
+	fmt.Fprintln(v.Out, strings.Repeat("\t", v.TabCount), "Color node :: ", node.Value)
 }
 
 func (v *PrintNodesVisitor) VisitBooleanNode(node *ASTBooleanNode) {
 	v.NodeCount++
-	fmt.Println(strings.Repeat("\t", v.TabCount), "Boolean value::", node.Value)
+	fmt.Fprintln(v.Out, strings.Repeat("\t", v.TabCount), "Boolean value::", node.Value)
 }
 
 func (v *PrintNodesVisitor) VisitArrayNode(node *ASTArrayNode) {
 	v.NodeCount++
-	fmt.Println(strings.Repeat("\t", v.TabCount), "Array node =>")
+	fmt.Fprintln(v.Out, strings.Repeat("\t", v.TabCount), "Array node =>")
 	v.IncTabCount()
 	for _, item := range node.Items {
 		item.Accept(v)
 	}
 	v.DecTabCount()
 }
+
+func (v *PrintNodesVisitor) VisitErrorNode(node *ASTErrorNode) {
+	v.NodeCount++
+	fmt.Fprintln(v.Out, strings.Repeat("\t", v.TabCount), "Error node :: skipped", len(node.Skipped), "tokens")
+}
@@ -0,0 +1,406 @@
+// Command prlc is the command line front end for the PArL compiler.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	compiler "github.com/giuszeppe/compiler-theory"
+	"github.com/giuszeppe/compiler-theory/ast"
+	"github.com/giuszeppe/compiler-theory/diag"
+	"github.com/giuszeppe/compiler-theory/interp"
+	"github.com/giuszeppe/compiler-theory/ir"
+	"github.com/giuszeppe/compiler-theory/lexer"
+	"github.com/giuszeppe/compiler-theory/optimize"
+	"github.com/giuszeppe/compiler-theory/parir"
+	"github.com/giuszeppe/compiler-theory/parser"
+	"github.com/giuszeppe/compiler-theory/vm"
+)
+
+// Exit codes. Each compiler phase fails with its own code so that build
+// scripts can tell a typo from a type error from a missing file.
+const (
+	exitOK       = 0
+	exitFailure  = 1 // bad usage, runtime error, grammar conflicts or compiler bug
+	exitLexical  = 2
+	exitSyntax   = 3
+	exitSemantic = 4
+	exitIO       = 5
+)
+
+const usage = `Usage: program <command> [flags] <source_file>
+
+Commands:
+  tokens    print the token stream
+  ast       print the abstract syntax tree
+  check     run semantic analysis and report diagnostics
+  ir        print the program lowered to the mid-level IR (-dump-cfg prints
+            its control flow graphs in Graphviz DOT instead)
+  emit      print the generated PArIR (-o writes it to a file)
+  run       compile and execute the program on the VM (-interp runs the
+            checked AST with the interpreter instead)
+  repl      check and run statements, declarations and expressions as they
+            are typed (:help lists its commands); it takes no source file
+  grammar   print the grammar's nullable, FIRST and FOLLOW sets and parsing table
+
+emit and run take -O<n> to optimise the generated code: -O0 (the default)
+leaves it as generated, -O1 applies local rewrites and -O2 also removes
+unreachable code.
+
+check, ir, emit, run and repl report warnings, such as W003 for a variable that
+is never read. -A, -W and -D take comma-separated codes to allow (hide),
+warn about or deny (report as errors) those warnings, and -Werror denies
+every warning not named otherwise. A "// prl:ignore W003" comment hides the
+listed warnings on its line, or on the next line if it stands alone.
+
+-diagnostics-format=human prints each diagnostic with the source line it
+refers to and any notes, in colour on a terminal (-color=always|never
+overrides this). json prints them as a JSON array and sarif as a SARIF
+2.1.0 log, for other tools to read. The default, short, prints one line
+per diagnostic. Diagnostics always go to standard error. grammar prints
+its conflicts with the rest of its report and takes neither flag.
+
+A source file of "-" is read from standard input. run also accepts PArIR
+written by emit, in a file ending in .parir.
+`
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+// run executes the command line args and returns the process exit code.
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	if len(args) < 1 {
+		fmt.Fprint(stderr, usage)
+		return exitFailure
+	}
+
+	cmd, args := args[0], args[1:]
+	fs := flag.NewFlagSet(cmd, flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	outPath := ""
+	padPath := ""
+	optLevel := 0
+	dumpCFG := false
+	interpret := false
+	switch cmd {
+	case "ir":
+		fs.BoolVar(&dumpCFG, "dump-cfg", false, "print the control flow graphs in DOT")
+	case "emit":
+		fs.StringVar(&outPath, "o", "", "write PArIR to `file` instead of stdout")
+	case "run":
+		fs.StringVar(&padPath, "pad", "", "write the final pad to `file` as a PPM image")
+		fs.BoolVar(&interpret, "interp", false, "interpret the checked AST instead of compiling it")
+	}
+	if cmd == "emit" || cmd == "run" {
+		fs.IntVar(&optLevel, "O", 0, "optimisation `level`")
+	}
+	format := "short"
+	colour := "auto"
+	// grammar reports conflicts in its table, not diagnostics
+	if cmd != "grammar" {
+		fs.StringVar(&format, "diagnostics-format", format, "how to print diagnostics: short, human, json or sarif")
+		fs.StringVar(&colour, "color", colour, "colour human diagnostics: auto, always or never")
+	}
+	var warnings diag.WarningConfig
+	switch cmd {
+	case "check", "ir", "emit", "run", "repl":
+		fs.BoolVar(&warnings.Error, "Werror", false, "report warnings as errors")
+		fs.Func("A", "allow (do not report) the warnings with these comma-separated `codes`", warningLevel(&warnings, diag.LevelAllow))
+		fs.Func("W", "report the warnings with these `codes` as warnings, even with -Werror", warningLevel(&warnings, diag.LevelWarn))
+		fs.Func("D", "deny (report as errors) the warnings with these `codes`", warningLevel(&warnings, diag.LevelDeny))
+	}
+	if err := fs.Parse(optFlags(args)); err != nil {
+		return exitFailure
+	}
+
+	switch format {
+	case "short", "human", "json", "sarif":
+	default:
+		fmt.Fprintf(stderr, "unknown diagnostics format %q\n", format)
+		return exitFailure
+	}
+	switch colour {
+	case "auto", "always", "never":
+	default:
+		fmt.Fprintf(stderr, "unknown -color setting %q\n", colour)
+		return exitFailure
+	}
+
+	if cmd == "repl" {
+		if fs.NArg() != 0 {
+			fmt.Fprint(stderr, usage)
+			return exitFailure
+		}
+		rep := reporter{format: format, path: "<input>", colour: colour == "always" || colour == "auto" && isTerminal(stderr)}
+		return runREPL(stdin, stdout, stderr, rep, warnings)
+	}
+
+	if cmd == "grammar" {
+		grammar := parser.NewGrammar()
+		grammar.WriteReport(stdout)
+		if len(grammar.Conflicts) > 0 {
+			return exitFailure
+		}
+		return exitOK
+	}
+
+	switch cmd {
+	case "tokens", "ast", "check", "ir", "emit", "run":
+	default:
+		fmt.Fprintf(stderr, "unknown command %q\n\n%s", cmd, usage)
+		return exitFailure
+	}
+	if fs.NArg() != 1 {
+		fmt.Fprint(stderr, usage)
+		return exitFailure
+	}
+
+	path := fs.Arg(0)
+	src, err := readSource(path, stdin)
+	if err != nil {
+		fmt.Fprintf(stderr, "Error reading file: %v\n", err)
+		return exitIO
+	}
+
+	rep := reporter{format: format, path: path, src: src, colour: colour == "always" || colour == "auto" && isTerminal(stderr)}
+	if cmd == "tokens" {
+		return printTokens(src, rep, stdout, stderr)
+	}
+	if cmd == "run" && filepath.Ext(path) == ".parir" {
+		if interpret {
+			fmt.Fprintf(stderr, "%s: -interp needs a PArL source file\n", path)
+			return exitFailure
+		}
+		prog, err := parir.Parse(src)
+		if err != nil {
+			fmt.Fprintf(stderr, "%s: %v\n", path, err)
+			return exitSyntax
+		}
+		return execute(optimize.Peephole(prog, optLevel), padPath, stdout, stderr)
+	}
+
+	stopAfter := compiler.PhaseCodegen
+	switch {
+	case cmd == "ast":
+		stopAfter = compiler.PhaseParse
+	case cmd == "check", interpret:
+		stopAfter = compiler.PhaseCheck
+	}
+	res, diags := compiler.Compile(src, compiler.Options{StopAfter: stopAfter, OptLevel: optLevel, Warnings: warnings})
+	rep.report(stderr, diags)
+	if code := exitCodeFor(diags); code != exitOK {
+		return code
+	}
+
+	switch cmd {
+	case "ast":
+		printVisitor := ast.NewPrintNodesVisitor()
+		printVisitor.Out = stdout
+		res.AST.Accept(printVisitor)
+		return exitOK
+	case "check":
+		return exitOK
+	case "ir":
+		if dumpCFG {
+			ir.WriteDOT(stdout, res.IR)
+			return exitOK
+		}
+		ir.Print(stdout, res.IR)
+		return exitOK
+	case "emit":
+		return emit(res.Instructions, outPath, stdout, stderr)
+	}
+	if interpret {
+		return interpretAST(res.AST, padPath, stdout, stderr)
+	}
+	return execute(res.Instructions, padPath, stdout, stderr)
+}
+
+// interpretAST runs the checked program node with the tree-walking
+// interpreter, writing the final pad to padPath if it is set.
+func interpretAST(node ast.ASTNode, padPath string, stdout, stderr io.Writer) int {
+	in := interp.NewInterpreterVisitor()
+	in.Out = stdout
+	in.Sleep = time.Sleep
+	if err := in.Run(node); err != nil {
+		fmt.Fprintln(stderr, err)
+		return exitFailure
+	}
+	return writePad(in.Pad, padPath, stderr)
+}
+
+// execute runs prog on the VM, writing the final pad to padPath if it is
+// set.
+func execute(prog []parir.Instruction, padPath string, stdout, stderr io.Writer) int {
+	machine, err := vm.NewVM(prog)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return exitFailure
+	}
+	machine.Out = stdout
+	machine.Sleep = time.Sleep
+	if err := machine.Run(); err != nil {
+		fmt.Fprintln(stderr, err)
+		return exitFailure
+	}
+	return writePad(machine.Pad, padPath, stderr)
+}
+
+// writePad writes pad to padPath as a PPM image, if padPath is set.
+func writePad(pad *vm.Pad, padPath string, stderr io.Writer) int {
+	if padPath == "" {
+		return exitOK
+	}
+	var ppm strings.Builder
+	pad.WritePPM(&ppm)
+	if err := os.WriteFile(padPath, []byte(ppm.String()), 0o644); err != nil {
+		fmt.Fprintf(stderr, "Error writing pad: %v\n", err)
+		return exitIO
+	}
+	return exitOK
+}
+
+// optFlags rewrites the conventional -O1 spelling of the optimisation flag
+// to -O=1, which is how the flag package expects it.
+func optFlags(args []string) []string {
+	out := make([]string, len(args))
+	for i, arg := range args {
+		if level, ok := strings.CutPrefix(arg, "-O"); ok && level != "" && level[0] >= '0' && level[0] <= '9' {
+			arg = "-O=" + level
+		}
+		out[i] = arg
+	}
+	return out
+}
+
+// warningLevel returns a flag function that sets the warnings listed in its
+// argument to level.
+func warningLevel(warnings *diag.WarningConfig, level diag.Level) func(string) error {
+	return func(arg string) error {
+		for _, code := range strings.Split(arg, ",") {
+			code = strings.TrimSpace(code)
+			if !strings.HasPrefix(code, "W") {
+				return fmt.Errorf("%q is not a warning code", code)
+			}
+			warnings.SetLevel(code, level)
+		}
+		return nil
+	}
+}
+
+// exitCodeFor returns the exit code for the earliest phase that reported an
+// error in diags, or exitOK if there are none.
+func exitCodeFor(diags []diag.Diagnostic) int {
+	phase := compiler.Phase(0)
+	for _, d := range diags {
+		if d.Severity != diag.SeverityError {
+			continue
+		}
+		if p := compiler.PhaseOf(d); phase == 0 || p < phase {
+			phase = p
+		}
+	}
+	switch phase {
+	case 0:
+		return exitOK
+	case compiler.PhaseLex:
+		return exitLexical
+	case compiler.PhaseParse:
+		return exitSyntax
+	case compiler.PhaseCheck:
+		return exitSemantic
+	default:
+		return exitFailure
+	}
+}
+func readSource(path string, stdin io.Reader) (string, error) {
+	var content []byte
+	var err error
+	if path == "-" {
+		content, err = io.ReadAll(stdin)
+	} else {
+		content, err = os.ReadFile(path)
+	}
+	return string(content), err
+}
+
+// reporter prints diagnostics about one source file in the format chosen
+// on the command line.
+type reporter struct {
+	format    string // "short", "human", "json" or "sarif"
+	path, src string
+	colour    bool
+}
+
+// report prints diags. It is called once per source file, even when diags
+// is empty, since the json and sarif formats always write a document.
+func (r reporter) report(w io.Writer, diags []diag.Diagnostic) {
+	switch r.format {
+	case "human":
+		renderer := &diag.Renderer{Path: r.path, Src: r.src, Colour: r.colour}
+		for _, d := range diags {
+			renderer.Render(w, d)
+		}
+		return
+	case "json":
+		diag.WriteJSON(w, r.path, diags)
+		return
+	case "sarif":
+		diag.WriteSARIF(w, "prlc", r.path, diags)
+		return
+	}
+	for _, d := range diags {
+		fmt.Fprintf(w, "%s: %s[%s]: %v\n", r.path, d.Severity, d.Code, d)
+	}
+}
+
+// isTerminal reports whether w is a terminal that colours can be used on,
+// which NO_COLOR in the environment rules out.
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok || os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// printTokens writes one line per significant token: its position, type and
+// lexeme. Whitespace and newlines are left out.
+func printTokens(src string, rep reporter, stdout, stderr io.Writer) int {
+	lex := lexer.NewLexer()
+	var diags []diag.Diagnostic
+	for _, tok := range lex.GenerateTokens(src) {
+		if tok.Type == lexer.WhitespaceToken || tok.Type == lexer.NewLineToken {
+			continue
+		}
+		fmt.Fprintf(stdout, "%d:%d\t%-18s %q\n", tok.Line, tok.Column, tok.Type, tok.Lexeme)
+		if tok.Type == lexer.Error {
+			diags = append(diags, parser.ErrInvalidToken(tok))
+		}
+	}
+	rep.report(stderr, diags)
+	return exitCodeFor(diags)
+}
+
+func emit(prog []parir.Instruction, outPath string, stdout, stderr io.Writer) int {
+	var text strings.Builder
+	parir.Print(&text, prog)
+	var err error
+	if outPath == "" {
+		_, err = io.WriteString(stdout, text.String())
+	} else {
+		err = os.WriteFile(outPath, []byte(text.String()), 0o644)
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "Error writing output: %v\n", err)
+		return exitIO
+	}
+	return exitOK
+}
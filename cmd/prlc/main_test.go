@@ -0,0 +1,241 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func runCLI(t *testing.T, src string, args ...string) (int, string, string) {
+	t.Helper()
+	var stdout, stderr bytes.Buffer
+	code := run(args, strings.NewReader(src), &stdout, &stderr)
+	return code, stdout.String(), stderr.String()
+}
+
+func TestCLIExitCodes(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		cmd  string
+		want int
+	}{
+		{"valid", "let x:int = 1;", "check", exitOK},
+		{"lexical", "let x:int = 1; @", "check", exitLexical},
+		{"syntax", "let x:int = ;", "check", exitSyntax},
+		{"semantic", "let x:int = true;", "check", exitSemantic},
+		{"tokens lexical", "x @", "tokens", exitLexical},
+		{"ast skips semantics", "let x:int = true;", "ast", exitOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, _, stderr := runCLI(t, tt.src, tt.cmd, "-")
+			if code != tt.want {
+				t.Fatalf("Expected exit code %d, got %d (stderr: %s)", tt.want, code, stderr)
+			}
+		})
+	}
+}
+
+func TestCLIWarnings(t *testing.T) {
+	const src = "let x:int = 1;\nlet y:int = 2; // prl:ignore W003\n"
+	tests := []struct {
+		name  string
+		flags []string
+		want  int
+		count int // warnings and errors reported
+	}{
+		{"default", nil, exitOK, 1},
+		{"allow", []string{"-A", "W003"}, exitOK, 0},
+		{"Werror", []string{"-Werror"}, exitSemantic, 1},
+		{"warn overrides Werror", []string{"-Werror", "-W", "W003"}, exitOK, 1},
+		{"deny", []string{"-D", "W001,W003"}, exitSemantic, 1},
+		{"not a warning", []string{"-A", "E001"}, exitFailure, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args := append(append([]string{"check"}, tt.flags...), "-")
+			code, _, stderr := runCLI(t, src, args...)
+			if code != tt.want {
+				t.Fatalf("Expected exit code %d, got %d (stderr: %s)", tt.want, code, stderr)
+			}
+			if n := strings.Count(stderr, "[W003]"); n != tt.count {
+				t.Fatalf("Expected %d reports of W003, got:\n%s", tt.count, stderr)
+			}
+		})
+	}
+}
+
+func TestCLIHumanDiagnostics(t *testing.T) {
+	code, _, stderr := runCLI(t, "let p1_score:int = 0;\n__print p1_scor;\n", "check", "-diagnostics-format=human", "-")
+	if code != exitSemantic {
+		t.Fatalf("Expected exit code %d, got %d (stderr: %s)", exitSemantic, code, stderr)
+	}
+	for _, want := range []string{"error[E001]: Variable not declared: p1_scor", " --> -:2:9", "2 | __print p1_scor;", "  |         ^^^^^^^", "= help: did you mean `p1_score`?"} {
+		if !strings.Contains(stderr, want) {
+			t.Fatalf("Expected %q in:\n%s", want, stderr)
+		}
+	}
+	if strings.Contains(stderr, "\x1b[") {
+		t.Fatalf("Expected no colours when not writing to a terminal:\n%s", stderr)
+	}
+}
+
+func TestCLIMachineReadableDiagnostics(t *testing.T) {
+	for _, format := range []string{"json", "sarif"} {
+		for _, cmd := range []string{"tokens", "ast", "check", "emit", "run"} {
+			code, _, stderr := runCLI(t, "let x:int = 1;", cmd, "--diagnostics-format="+format, "-")
+			if code != exitOK || !json.Valid([]byte(stderr)) {
+				t.Fatalf("%s %s: expected a JSON document, got %d:\n%s", cmd, format, code, stderr)
+			}
+		}
+	}
+
+	code, _, stderr := runCLI(t, "let x:int = 1;\nlet x:int = 2;\n", "check", "--diagnostics-format=sarif", "-")
+	if code != exitSemantic || !strings.Contains(stderr, `"ruleId": "E002"`) || !strings.Contains(stderr, `"relatedLocations"`) {
+		t.Fatalf("Expected E002 with a related location, got %d:\n%s", code, stderr)
+	}
+}
+
+func TestCLIGrammar(t *testing.T) {
+	code, stdout, stderr := runCLI(t, "", "grammar")
+	if code != exitOK || !strings.HasPrefix(stdout, "Nullable:\n") || strings.Contains(stdout, "Conflicts:") {
+		t.Fatalf("Expected a report without conflicts, got %d (stderr: %s):\n%s", code, stderr, stdout)
+	}
+	// conflicts are part of the report, not diagnostics
+	for _, flag := range []string{"-diagnostics-format=json", "-color=always"} {
+		code, stdout, stderr := runCLI(t, "", "grammar", flag)
+		if code != exitFailure || stdout != "" || !strings.Contains(stderr, "flag provided but not defined") {
+			t.Errorf("%s: expected a usage error, got %d %q:\n%s", flag, code, stdout, stderr)
+		}
+	}
+}
+
+func TestCLIMissingFile(t *testing.T) {
+	code, _, _ := runCLI(t, "", "check", filepath.Join(t.TempDir(), "missing.prl"))
+	if code != exitIO {
+		t.Fatalf("Expected exit code %d, got %d", exitIO, code)
+	}
+}
+
+func TestCLIUnknownCommand(t *testing.T) {
+	code, _, stderr := runCLI(t, "", "frobnicate", "-")
+	if code != exitFailure || !strings.Contains(stderr, "unknown command") {
+		t.Fatalf("Expected unknown command failure, got %d: %s", code, stderr)
+	}
+}
+
+func TestCLIRun(t *testing.T) {
+	code, stdout, stderr := runCLI(t, "__print 1 + 2;", "run", "-")
+	if code != exitOK {
+		t.Fatalf("Expected success, got %d: %s", code, stderr)
+	}
+	if stdout != "3\n" {
+		t.Fatalf("Expected output %q, got %q", "3\n", stdout)
+	}
+}
+
+func TestCLIRunInterp(t *testing.T) {
+	pad := filepath.Join(t.TempDir(), "pad.ppm")
+	code, stdout, stderr := runCLI(t, "__write 0, 0, #ff0000; __print 1 + 2;", "run", "-interp", "-pad", pad, "-")
+	if code != exitOK {
+		t.Fatalf("Expected success, got %d: %s", code, stderr)
+	}
+	if stdout != "3\n" {
+		t.Fatalf("Expected output %q, got %q", "3\n", stdout)
+	}
+	if _, err := os.Stat(pad); err != nil {
+		t.Fatalf("Expected the pad to be written: %v", err)
+	}
+
+	code, _, stderr = runCLI(t, "let x:int = 0; __print 1 / x;", "run", "-interp", "-")
+	if code != exitFailure || !strings.Contains(stderr, "division by zero") {
+		t.Fatalf("Expected a runtime error, got %d: %s", code, stderr)
+	}
+}
+
+func TestCLIEmitToFile(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "out.parir")
+	code, stdout, stderr := runCLI(t, "__print 1;", "emit", "-o", out, "-")
+	if code != exitOK {
+		t.Fatalf("Expected success, got %d: %s", code, stderr)
+	}
+	if stdout != "" {
+		t.Fatalf("Expected nothing on stdout, got %q", stdout)
+	}
+	content, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(content), "print") || !strings.HasSuffix(string(content), "halt\n") {
+		t.Fatalf("Unexpected PArIR:\n%s", content)
+	}
+}
+
+func TestCLIOptimise(t *testing.T) {
+	src := "__print -(1 + 2);"
+	_, plain, _ := runCLI(t, src, "emit", "-")
+	code, optimised, stderr := runCLI(t, src, "emit", "-O1", "-")
+	if code != exitOK {
+		t.Fatalf("Expected success, got %d: %s", code, stderr)
+	}
+	if !strings.Contains(optimised, "push -3\nprint") || len(optimised) >= len(plain) {
+		t.Fatalf("Expected folded constants, got:\n%s", optimised)
+	}
+
+	code, stdout, stderr := runCLI(t, src, "run", "-O2", "-")
+	if code != exitOK || stdout != "-3\n" {
+		t.Fatalf("Expected -3, got %d %q (stderr: %s)", code, stdout, stderr)
+	}
+}
+
+func TestCLIIR(t *testing.T) {
+	code, stdout, stderr := runCLI(t, "let x:int = 2; __print x * 3;", "ir", "-")
+	if code != exitOK {
+		t.Fatalf("Expected success, got %d: %s", code, stderr)
+	}
+	if !strings.HasPrefix(stdout, "func main() {\nentry1:\n    frame.open 1\n") || !strings.Contains(stdout, "= mul %") {
+		t.Fatalf("Expected the IR of main, got:\n%s", stdout)
+	}
+
+	code, stdout, stderr = runCLI(t, "if (true) { __print 1; }", "ir", "-dump-cfg", "-")
+	if code != exitOK {
+		t.Fatalf("Expected success, got %d: %s", code, stderr)
+	}
+	if !strings.HasPrefix(stdout, "digraph cfg {") || !strings.Contains(stdout, `[label="true"]`) {
+		t.Fatalf("Expected a DOT graph, got:\n%s", stdout)
+	}
+}
+
+func TestCLIRunPArIR(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "prog.parir")
+	if code, _, stderr := runCLI(t, "__print 6 * 7;", "emit", "-o", path, "-"); code != exitOK {
+		t.Fatalf("Expected success, got %d: %s", code, stderr)
+	}
+	code, stdout, stderr := runCLI(t, "", "run", path)
+	if code != exitOK || stdout != "42\n" {
+		t.Fatalf("Expected 42, got %d %q (stderr: %s)", code, stdout, stderr)
+	}
+
+	os.WriteFile(path, []byte("push 1\nfrobnicate\n"), 0o644)
+	if code, _, stderr := runCLI(t, "", "run", path); code != exitSyntax || !strings.Contains(stderr, "line 2") {
+		t.Fatalf("Expected a syntax error on line 2, got %d: %s", code, stderr)
+	}
+}
+
+func TestCLITokens(t *testing.T) {
+	code, stdout, _ := runCLI(t, "x = 1;", "tokens", "-")
+	if code != exitOK {
+		t.Fatalf("Expected success, got %d", code)
+	}
+	lines := strings.Split(strings.TrimSpace(stdout), "\n")
+	if len(lines) != 5 {
+		t.Fatalf("Expected 5 tokens (including end), got %d:\n%s", len(lines), stdout)
+	}
+	if !strings.Contains(lines[0], "Identifier") || !strings.Contains(lines[0], `"x"`) {
+		t.Fatalf("Unexpected first token line %q", lines[0])
+	}
+}
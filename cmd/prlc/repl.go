@@ -0,0 +1,288 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	compiler "github.com/giuszeppe/compiler-theory"
+	"github.com/giuszeppe/compiler-theory/ast"
+	"github.com/giuszeppe/compiler-theory/diag"
+	"github.com/giuszeppe/compiler-theory/interp"
+	"github.com/giuszeppe/compiler-theory/lexer"
+	"github.com/giuszeppe/compiler-theory/parser"
+	"github.com/giuszeppe/compiler-theory/sema"
+	"github.com/giuszeppe/compiler-theory/types"
+)
+
+const replHelp = `Enter statements, fun declarations or expressions. Declarations carry
+over to later inputs, and an expression prints its value and type. An
+input with an unclosed { continues on the next line.
+
+  :ast     print the syntax tree of the last input
+  :tokens  print the tokens of the last input
+  :parir   print the PArIR of the whole session, not just the last input:
+           every input that passed the checker, compiled as one program
+  :reset   forget every declaration and start over
+  :help    print this help
+  :quit    leave (end of input does too)
+`
+
+// exprPrefix turns an expression into a statement the grammar accepts.
+const exprPrefix = "__print "
+
+// repl checks and runs one input at a time, keeping a single symbol table
+// and runtime state for the whole session.
+type repl struct {
+	out, errOut io.Writer
+	rep         reporter
+	warnings    diag.WarningConfig
+	grammar     *parser.Grammar
+
+	checker *sema.SemanticVisitor
+	machine *interp.InterpreterVisitor
+	// session holds the inputs that passed the checker, as statements, so
+	// that :parir can compile them together.
+	session []string
+	// last is the source of the last input and lastAST its tree, or nil
+	// if it did not parse.
+	last    string
+	lastAST ast.ASTNode
+}
+
+func newREPL(rep reporter, warnings diag.WarningConfig, stdout, stderr io.Writer) *repl {
+	r := &repl{out: stdout, errOut: stderr, rep: rep, warnings: warnings, grammar: parser.NewGrammar()}
+	r.reset()
+	return r
+}
+
+func (r *repl) reset() {
+	r.checker = sema.NewSemanticVisitor()
+	r.machine = interp.NewInterpreterVisitor()
+	r.machine.Out = r.out
+	r.machine.Sleep = time.Sleep
+	r.session, r.last, r.lastAST = nil, "", nil
+}
+
+// runREPL reads inputs from stdin until it ends or :quit.
+func runREPL(stdin io.Reader, stdout, stderr io.Writer, rep reporter, warnings diag.WarningConfig) int {
+	r := newREPL(rep, warnings, stdout, stderr)
+	sc := bufio.NewScanner(stdin)
+	var input strings.Builder
+	for {
+		if input.Len() == 0 {
+			fmt.Fprint(stdout, "> ")
+		} else {
+			fmt.Fprint(stdout, "... ")
+		}
+		if !sc.Scan() {
+			fmt.Fprintln(stdout)
+			break
+		}
+		input.WriteString(sc.Text())
+		input.WriteByte('\n')
+		if openBraces(input.String()) > 0 {
+			continue
+		}
+		src := input.String()
+		input.Reset()
+		if !r.handle(src) {
+			break
+		}
+	}
+	if err := sc.Err(); err != nil {
+		fmt.Fprintf(stderr, "Error reading input: %v\n", err)
+		return exitIO
+	}
+	return exitOK
+}
+
+// openBraces returns how many more { than } there are in src.
+func openBraces(src string) int {
+	lex := lexer.NewLexer()
+	depth := 0
+	for _, tok := range lex.GenerateTokens(src) {
+		switch tok.Type {
+		case lexer.LeftCurlyToken:
+			depth++
+		case lexer.RightCurlyToken:
+			depth--
+		}
+	}
+	return depth
+}
+
+// handle runs a meta-command or a PArL input, and reports whether the
+// session goes on.
+func (r *repl) handle(src string) bool {
+	line := strings.TrimSpace(src)
+	if line == "" {
+		return true
+	}
+	if strings.HasPrefix(line, ":") {
+		return r.command(line)
+	}
+
+	r.last, r.lastAST = src, nil
+	stmts, expr, diags := r.parse(src)
+	if stmts == nil {
+		r.report(src, diags)
+		return true
+	}
+	if expr != nil {
+		r.lastAST = expr
+	} else {
+		r.lastAST = &ast.ASTProgramNode{Block: ast.ASTBlockNode{Stmts: stmts}}
+	}
+
+	diags = r.checker.AnalyzeIncremental(stmts)
+	if expr != nil {
+		diags = shiftDiagnostics(diags, -len(exprPrefix))
+	}
+	lex := lexer.NewLexer()
+	diags = r.warnings.Apply(diags, diag.ParseIgnores(lex.GenerateTokens(src)))
+	r.report(src, diags)
+	if diag.HasErrors(diags) {
+		return true
+	}
+	if expr != nil {
+		r.session = append(r.session, exprPrefix+strings.TrimSuffix(line, ";")+";")
+	} else {
+		r.session = append(r.session, src)
+	}
+
+	if expr == nil {
+		if err := r.machine.Exec(stmts); err != nil {
+			fmt.Fprintln(r.errOut, err)
+		}
+		return true
+	}
+	val, err := r.machine.Eval(expr)
+	if err != nil {
+		fmt.Fprintln(r.errOut, err)
+		return true
+	}
+	// bools and colours are numbers at run time; show them as they are
+	// written in the source
+	typ := expr.(ast.Expr).ExprType()
+	switch typ {
+	case types.Bool:
+		val = strconv.FormatBool(val != "0")
+	case types.Colour:
+		if n, err := strconv.ParseFloat(val, 64); err == nil {
+			val = fmt.Sprintf("#%06x", int(n))
+		}
+	}
+	fmt.Fprintf(r.out, "%s : %s\n", val, typ)
+	return true
+}
+
+// parse parses src as a list of statements or, failing that, as a bare
+// expression with or without a semicolon. It returns the statements, the
+// expression if src is one, and the syntax errors of the statements
+// otherwise.
+func (r *repl) parse(src string) ([]ast.ASTNode, ast.ASTNode, []diag.Diagnostic) {
+	p := parser.NewParser(src)
+	node, err := p.Parse(r.grammar)
+	if err == nil {
+		return node.(*ast.ASTProgramNode).Block.Stmts, nil, nil
+	}
+
+	// The prefix goes on the same line, so only offsets and the first
+	// line's columns move, which shiftDiagnostics undoes. Leading space is
+	// kept so that it moves them by the prefix alone.
+	wrapped := exprPrefix + strings.TrimSuffix(strings.TrimRightFunc(src, unicode.IsSpace), ";") + ";"
+	ep := parser.NewParser(wrapped)
+	if node, err := ep.Parse(r.grammar); err == nil {
+		stmts := node.(*ast.ASTProgramNode).Block.Stmts
+		if print, ok := stmts[0].(*ast.ASTBuiltinFuncNode); ok && len(stmts) == 1 {
+			return stmts, print.Args[0], nil
+		}
+	}
+	return nil, nil, p.Errors
+}
+
+// shiftDiagnostics moves the spans of diags, found in an input that was
+// given a prefix on its first line, by delta bytes.
+func shiftDiagnostics(diags []diag.Diagnostic, delta int) []diag.Diagnostic {
+	shift := func(pos diag.Pos) diag.Pos {
+		if !pos.IsValid() {
+			return pos
+		}
+		pos.Offset += delta
+		if pos.Line == 1 {
+			pos.Column += delta
+		}
+		return pos
+	}
+	out := make([]diag.Diagnostic, len(diags))
+	for i, d := range diags {
+		d.Span = diag.Span{Start: shift(d.Span.Start), End: shift(d.Span.End)}
+		notes := make([]diag.Note, len(d.Notes))
+		for j, n := range d.Notes {
+			n.Span = diag.Span{Start: shift(n.Span.Start), End: shift(n.Span.End)}
+			notes[j] = n
+		}
+		d.Notes = notes
+		out[i] = d
+	}
+	return out
+}
+
+func (r *repl) report(src string, diags []diag.Diagnostic) {
+	if len(diags) == 0 {
+		return
+	}
+	rep := r.rep
+	rep.src = src
+	rep.report(r.errOut, diags)
+}
+
+// command runs a meta-command, and reports whether the session goes on.
+func (r *repl) command(line string) bool {
+	switch line {
+	case ":quit", ":q":
+		return false
+	case ":help":
+		fmt.Fprint(r.out, replHelp)
+	case ":reset":
+		r.reset()
+	case ":ast":
+		if r.lastAST == nil {
+			fmt.Fprintln(r.errOut, "the last input has no syntax tree")
+			break
+		}
+		printVisitor := ast.NewPrintNodesVisitor()
+		printVisitor.Out = r.out
+		r.lastAST.Accept(printVisitor)
+	case ":tokens":
+		if r.last == "" {
+			fmt.Fprintln(r.errOut, "there is no input yet")
+			break
+		}
+		rep := r.rep
+		rep.src = r.last
+		printTokens(r.last, rep, r.out, r.errOut)
+	case ":parir":
+		if len(r.session) == 0 {
+			fmt.Fprintln(r.errOut, "there is no input yet")
+			break
+		}
+		// Unlike :ast and :tokens, this shows the whole session: the last
+		// input alone may use what earlier ones declared, and PArIR has
+		// no way to refer to code that is not in the same program.
+		res, diags := compiler.Compile(strings.Join(r.session, "\n"), compiler.Options{})
+		if diag.HasErrors(diags) {
+			r.report(strings.Join(r.session, "\n"), diags)
+			break
+		}
+		emit(res.Instructions, "", r.out, r.errOut)
+	default:
+		fmt.Fprintf(r.errOut, "unknown command %s; :help lists them\n", line)
+	}
+	return true
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestREPLCarriesDeclarations(t *testing.T) {
+	input := `let x:int = 4;
+x * 2
+fun sq(n:int) -> int {
+	return n * n;
+}
+sq(x);
+x < 3
+#ff0000
+__print x;
+`
+	code, stdout, stderr := runCLI(t, input, "repl")
+	if code != exitOK || stderr != "" {
+		t.Fatalf("Expected success, got %d: %s", code, stderr)
+	}
+	for _, want := range []string{"8 : int\n", "16 : int\n", "false : bool\n", "#ff0000 : colour\n", "> 4\n", "... "} {
+		if !strings.Contains(stdout, want) {
+			t.Errorf("Expected %q in the output, got:\n%s", want, stdout)
+		}
+	}
+}
+
+func TestREPLErrors(t *testing.T) {
+	input := `let x:int = true;
+x
+y + 1
+let z:int = 0;
+1 / z
+z
+`
+	code, stdout, stderr := runCLI(t, input, "repl")
+	if code != exitOK {
+		t.Fatalf("Expected the session to go on after errors, got %d", code)
+	}
+	// x was not declared, since its declaration had an error
+	for _, want := range []string{"Variable not declared: x (at line 1, column 1)", "Variable not declared: y (at line 1, column 1)", "runtime error: division by zero"} {
+		if !strings.Contains(stderr, want) {
+			t.Errorf("Expected %q in the errors, got:\n%s", want, stderr)
+		}
+	}
+	if !strings.Contains(stdout, "0 : int\n") {
+		t.Errorf("Expected the session to go on after a runtime error, got:\n%s", stdout)
+	}
+}
+
+func TestREPLIndentedInput(t *testing.T) {
+	input := "    y + 1\n\t  x * true;\n"
+	_, _, stderr := runCLI(t, input, "repl")
+	for _, want := range []string{"Variable not declared: y (at line 1, column 5)", "Variable not declared: x (at line 1, column 4)"} {
+		if !strings.Contains(stderr, want) {
+			t.Errorf("Expected %q in the errors, got:\n%s", want, stderr)
+		}
+	}
+}
+
+func TestREPLCommands(t *testing.T) {
+	input := `let x:int = 1;
+x + 2
+:tokens
+:ast
+:parir
+:reset
+x
+:bogus
+:quit
+x
+`
+	code, stdout, stderr := runCLI(t, input, "repl")
+	if code != exitOK {
+		t.Fatalf("Expected success, got %d: %s", code, stderr)
+	}
+	for _, want := range []string{"1:3\tPlus", "Binary Op node => +", ".main\n", "add\n"} {
+		if !strings.Contains(stdout, want) {
+			t.Errorf("Expected %q in the output, got:\n%s", want, stdout)
+		}
+	}
+	if !strings.Contains(stderr, "Variable not declared: x") || !strings.Contains(stderr, "unknown command :bogus") {
+		t.Errorf("Expected :reset to forget x and :bogus to be rejected, got:\n%s", stderr)
+	}
+	if strings.Count(stderr, "Variable not declared") != 1 {
+		t.Errorf("Expected :quit to end the session, got:\n%s", stderr)
+	}
+}
+
+func TestREPLPArIRShowsTheWholeSession(t *testing.T) {
+	input := `let x:int = 41;
+fun inc(n:int) -> int { return n + 1; }
+let y:bool = 1;
+__print inc(x);
+:parir
+`
+	code, stdout, stderr := runCLI(t, input, "repl")
+	if code != exitOK {
+		t.Fatalf("Expected success, got %d: %s", code, stderr)
+	}
+	// the earlier inputs are compiled along with the last, and the one
+	// that failed the checker is left out
+	out := stdout[strings.Index(stdout, ".main\n"):]
+	for _, want := range []string{"push 41\n", ".inc\n", "call\n", "print\n"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Expected %q in the PArIR, got:\n%s", want, out)
+		}
+	}
+	if !strings.Contains(out, "push 1\noframe\n") {
+		t.Errorf("Expected a frame for x alone, without y, got:\n%s", out)
+	}
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// diffContext is the number of unchanged lines shown around each change.
+const diffContext = 3
+
+type edit struct {
+	op   byte // ' ', '-' or '+'
+	line string
+}
+
+// splitLines splits s into lines, each keeping its trailing newline.
+func splitLines(s string) []string {
+	lines := strings.SplitAfter(s, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// diffLines returns the shortest edit script turning a into b, found from
+// their longest common subsequence.
+func diffLines(a, b []string) []edit {
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var edits []edit
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			edits = append(edits, edit{' ', a[i]})
+			i++
+			j++
+		case i < len(a) && (j == len(b) || lcs[i+1][j] >= lcs[i][j+1]):
+			edits = append(edits, edit{'-', a[i]})
+			i++
+		default:
+			edits = append(edits, edit{'+', b[j]})
+			j++
+		}
+	}
+	return edits
+}
+
+// writeDiff writes a unified diff from a, named oldName, to b, named newName.
+func writeDiff(w io.Writer, oldName, newName, a, b string) {
+	edits := diffLines(splitLines(a), splitLines(b))
+	// aLine[k] and bLine[k] count the lines of a and b before edits[k]
+	aLine := make([]int, len(edits)+1)
+	bLine := make([]int, len(edits)+1)
+	for k, e := range edits {
+		aLine[k+1], bLine[k+1] = aLine[k], bLine[k]
+		if e.op != '+' {
+			aLine[k+1]++
+		}
+		if e.op != '-' {
+			bLine[k+1]++
+		}
+	}
+
+	fmt.Fprintf(w, "--- %s\n+++ %s\n", oldName, newName)
+	for start := 0; start < len(edits); {
+		change := start
+		for change < len(edits) && edits[change].op == ' ' {
+			change++
+		}
+		if change == len(edits) {
+			break
+		}
+		// grow the hunk while the next change is close enough to share
+		// context with this one
+		last := change
+		for {
+			for last < len(edits) && edits[last].op != ' ' {
+				last++
+			}
+			next := last
+			for next < len(edits) && edits[next].op == ' ' {
+				next++
+			}
+			if next == len(edits) || next-last > 2*diffContext {
+				break
+			}
+			last = next
+		}
+		lo := max(change-diffContext, start)
+		hi := min(last+diffContext, len(edits))
+
+		fmt.Fprintf(w, "@@ -%s +%s @@\n", hunkRange(aLine[lo], aLine[hi]), hunkRange(bLine[lo], bLine[hi]))
+		for _, e := range edits[lo:hi] {
+			fmt.Fprintf(w, "%c%s", e.op, e.line)
+			if !strings.HasSuffix(e.line, "\n") {
+				fmt.Fprint(w, "\n\\ No newline at end of file\n")
+			}
+		}
+		start = hi
+	}
+}
+
+// hunkRange formats the lines [from, to) of a file for a hunk header.
+func hunkRange(from, to int) string {
+	if to == from {
+		return fmt.Sprintf("%d,0", from)
+	}
+	if to-from == 1 {
+		return fmt.Sprintf("%d", from+1)
+	}
+	return fmt.Sprintf("%d,%d", from+1, to-from)
+}
@@ -0,0 +1,163 @@
+// Command prlfmt formats PArL source files.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	compiler "github.com/giuszeppe/compiler-theory"
+	"github.com/giuszeppe/compiler-theory/diag"
+)
+
+// Exit codes, numbered as for prlc.
+const (
+	exitOK      = 0
+	exitFailure = 1 // bad usage, or files need formatting under --check
+	exitLexical = 2
+	exitSyntax  = 3
+	exitIO      = 5
+)
+
+const usage = `Usage: prlfmt [flags] [path ...]
+
+Prints PArL source in canonical form, keeping comments. Directories are
+searched for .prl files. With no path, standard input is formatted to
+standard output.
+
+Flags:
+`
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+type formatter struct {
+	write, diff, check bool
+	stdout, stderr     io.Writer
+
+	// code is the exit code of the first file that failed to format
+	code int
+	// unformatted is set when --check finds a file that needs formatting
+	unformatted bool
+}
+
+// run executes the command line args and returns the process exit code.
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("prlfmt", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		fmt.Fprint(stderr, usage)
+		flags.PrintDefaults()
+	}
+	f := &formatter{stdout: stdout, stderr: stderr}
+	flags.BoolVar(&f.write, "w", false, "write the result to the source file instead of standard output")
+	flags.BoolVar(&f.diff, "d", false, "print a diff instead of the formatted source")
+	flags.BoolVar(&f.check, "check", false, "list files that need formatting and exit with status 1 if there are any")
+	if err := flags.Parse(args); err != nil {
+		return exitFailure
+	}
+
+	if flags.NArg() == 0 {
+		if f.write {
+			fmt.Fprintln(stderr, "prlfmt: cannot use -w with standard input")
+			return exitFailure
+		}
+		src, err := io.ReadAll(stdin)
+		if err != nil {
+			fmt.Fprintf(stderr, "Error reading input: %v\n", err)
+			return exitIO
+		}
+		f.format("<standard input>", string(src), 0)
+	}
+	for _, path := range flags.Args() {
+		f.walk(path)
+	}
+
+	if f.code != exitOK {
+		return f.code
+	}
+	if f.unformatted {
+		return exitFailure
+	}
+	return exitOK
+}
+
+func (f *formatter) fail(code int) {
+	if f.code == exitOK {
+		f.code = code
+	}
+}
+
+// walk formats path, or every .prl file under it if it is a directory.
+func (f *formatter) walk(path string) {
+	err := filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || (p != path && filepath.Ext(p) != ".prl") {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		src, err := os.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		f.format(p, string(src), info.Mode().Perm())
+		return nil
+	})
+	if err != nil {
+		fmt.Fprintf(f.stderr, "Error reading file: %v\n", err)
+		f.fail(exitIO)
+	}
+}
+
+// format formats src, read from path, as the flags ask.
+func (f *formatter) format(path, src string, perm fs.FileMode) {
+	out, diags := compiler.Format(src)
+	if diags != nil {
+		for _, d := range diags {
+			fmt.Fprintf(f.stderr, "%s: %s[%s]: %v\n", path, d.Severity, d.Code, d)
+		}
+		f.fail(exitCodeFor(diags))
+		return
+	}
+
+	if !f.write && !f.diff && !f.check {
+		io.WriteString(f.stdout, out)
+		return
+	}
+	if out == src {
+		return
+	}
+	if f.check {
+		fmt.Fprintln(f.stdout, path)
+		f.unformatted = true
+	}
+	if f.diff {
+		writeDiff(f.stdout, path+".orig", path, src, out)
+	}
+	if f.write {
+		if err := os.WriteFile(path, []byte(out), perm); err != nil {
+			fmt.Fprintf(f.stderr, "Error writing file: %v\n", err)
+			f.fail(exitIO)
+		}
+	}
+}
+
+// exitCodeFor returns the exit code for the earliest phase that reported an
+// error in diags.
+func exitCodeFor(diags []diag.Diagnostic) int {
+	for _, d := range diags {
+		if compiler.PhaseOf(d) == compiler.PhaseLex {
+			return exitLexical
+		}
+	}
+	return exitSyntax
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const (
+	messy     = "let x:int=1;\nif(x<2){__print x;}\n"
+	formatted = "let x:int = 1;\nif (x < 2) {\n    __print x;\n}\n"
+)
+
+func runCLI(t *testing.T, stdin string, args ...string) (int, string, string) {
+	t.Helper()
+	var stdout, stderr bytes.Buffer
+	code := run(args, strings.NewReader(stdin), &stdout, &stderr)
+	return code, stdout.String(), stderr.String()
+}
+
+func writeFile(t *testing.T, dir, name, content string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestFormatStdin(t *testing.T) {
+	code, stdout, stderr := runCLI(t, messy)
+	if code != exitOK || stdout != formatted {
+		t.Fatalf("Expected formatted output, got %d %q (stderr: %s)", code, stdout, stderr)
+	}
+}
+
+func TestFormatErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want int
+	}{
+		{"lexical", "let x:int = 1; @", exitLexical},
+		{"syntax", "let x:int = ;", exitSyntax},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, stdout, stderr := runCLI(t, tt.src)
+			if code != tt.want || stdout != "" || stderr == "" {
+				t.Fatalf("Expected exit code %d and a diagnostic, got %d %q %q", tt.want, code, stdout, stderr)
+			}
+		})
+	}
+}
+
+func TestFormatCheck(t *testing.T) {
+	dir := t.TempDir()
+	bad := writeFile(t, dir, "bad.prl", messy)
+	writeFile(t, dir, "good.prl", formatted)
+	writeFile(t, dir, "notes.txt", "not PArL")
+
+	code, stdout, _ := runCLI(t, "", "--check", dir)
+	if code != exitFailure || stdout != bad+"\n" {
+		t.Fatalf("Expected %s to be listed, got %d %q", bad, code, stdout)
+	}
+	content, _ := os.ReadFile(bad)
+	if string(content) != messy {
+		t.Fatal("--check must not modify files")
+	}
+
+	code, stdout, _ = runCLI(t, "", "--check", filepath.Join(dir, "good.prl"))
+	if code != exitOK || stdout != "" {
+		t.Fatalf("Expected a formatted file to pass, got %d %q", code, stdout)
+	}
+}
+
+func TestFormatWrite(t *testing.T) {
+	path := writeFile(t, t.TempDir(), "prog.prl", messy)
+	code, stdout, stderr := runCLI(t, "", "-w", path)
+	if code != exitOK || stdout != "" {
+		t.Fatalf("Expected a silent success, got %d %q (stderr: %s)", code, stdout, stderr)
+	}
+	content, _ := os.ReadFile(path)
+	if string(content) != formatted {
+		t.Fatalf("Expected the file to be rewritten, got %q", content)
+	}
+
+	if code, _, _ := runCLI(t, "", "-w"); code != exitFailure {
+		t.Fatalf("Expected -w on standard input to fail, got %d", code)
+	}
+}
+
+func TestFormatDiff(t *testing.T) {
+	path := writeFile(t, t.TempDir(), "prog.prl", messy)
+	code, stdout, _ := runCLI(t, "", "-d", path)
+	want := "--- " + path + ".orig\n+++ " + path + "\n" +
+		"@@ -1,2 +1,4 @@\n" +
+		"-let x:int=1;\n" +
+		"-if(x<2){__print x;}\n" +
+		"+let x:int = 1;\n" +
+		"+if (x < 2) {\n" +
+		"+    __print x;\n" +
+		"+}\n"
+	if code != exitOK || stdout != want {
+		t.Fatalf("Expected diff:\n%s\ngot:\n%s", want, stdout)
+	}
+}
+
+func TestWriteDiffHunks(t *testing.T) {
+	a := "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12\n13\n14\n15"
+	b := "1\n2\n3\n4\nfive\n6\n7\n8\n9\n10\n11\n12\n13\n14\n15\n"
+	var out bytes.Buffer
+	writeDiff(&out, "a", "b", a, b)
+	want := "--- a\n+++ b\n" +
+		"@@ -2,7 +2,7 @@\n 2\n 3\n 4\n-5\n+five\n 6\n 7\n 8\n" +
+		"@@ -12,4 +12,4 @@\n 12\n 13\n 14\n-15\n\\ No newline at end of file\n+15\n"
+	if out.String() != want {
+		t.Fatalf("Expected:\n%s\ngot:\n%s", want, out.String())
+	}
+}
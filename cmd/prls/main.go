@@ -0,0 +1,17 @@
+// Command prls is the PArL language server. Editors start it and talk to it
+// over stdin and stdout.
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/giuszeppe/compiler-theory/lsp"
+)
+
+func main() {
+	if err := lsp.NewServer().Serve(os.Stdin, os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, "prls:", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,64 @@
+package codegen
+
+import (
+	"fmt"
+
+	"github.com/giuszeppe/compiler-theory/parir"
+)
+
+// Control flow is generated with symbolic labels rather than hand-counted
+// offsets. A label is placed with a mark, "@name", and referred to by
+// pushing its name, "push @name", just before a jmp or cjmp. Assemble
+// removes the marks and turns each reference into the relative #PC+n or
+// #PC-n address the VM expects.
+
+// placeLabel marks the next instruction as the target of label.
+func (g *generator) placeLabel(label string) {
+	g.instrs = append(g.instrs, parir.Instruction{Op: parir.OpMark, Arg: parir.Local(label)})
+}
+
+// label declares the function label name, which unlike a mark is kept in
+// the assembled code.
+func (g *generator) label(name string) {
+	g.instrs = append(g.instrs, parir.Instruction{Op: parir.OpLabel, Arg: parir.Label(name)})
+}
+
+// emitJump emits op, a jmp or cjmp, to label.
+func (g *generator) emitJump(op parir.Op, label string) {
+	g.push(parir.Local(label))
+	g.emit(op)
+}
+
+// Assemble resolves the symbolic labels in instrs. It fails if a label is
+// placed twice or referred to but never placed.
+func Assemble(instrs []parir.Instruction) ([]parir.Instruction, error) {
+	targets := make(map[string]int)
+	pos := 0
+	for _, instr := range instrs {
+		if instr.Op != parir.OpMark {
+			pos++
+			continue
+		}
+		label := instr.Arg.Name
+		if _, dup := targets[label]; dup {
+			return nil, fmt.Errorf("label @%s placed twice", label)
+		}
+		targets[label] = pos
+	}
+
+	out := make([]parir.Instruction, 0, pos)
+	for _, instr := range instrs {
+		if instr.Op == parir.OpMark {
+			continue
+		}
+		if instr.Arg.Kind == parir.KindLocal {
+			target, ok := targets[instr.Arg.Name]
+			if !ok {
+				return nil, fmt.Errorf("undefined label @%s", instr.Arg.Name)
+			}
+			instr.Arg = parir.PC(target - len(out))
+		}
+		out = append(out, instr)
+	}
+	return out, nil
+}
@@ -0,0 +1,38 @@
+package codegen
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/giuszeppe/compiler-theory/parir"
+)
+
+func parse(t *testing.T, src string) []parir.Instruction {
+	t.Helper()
+	prog, err := parir.Parse(strings.ReplaceAll(src, "; ", "\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return prog
+}
+
+func TestAssemble(t *testing.T) {
+	in := parse(t, "@top; push [0:0]; push @end; cjmp; push @top; jmp; @end; @also; halt; push @also; jmp")
+	want := parse(t, "push [0:0]; push #PC+4; cjmp; push #PC-3; jmp; halt; push #PC-1; jmp")
+	got, err := Assemble(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("Expected:\n%v\ngot:\n%v", want, got)
+	}
+}
+
+func TestAssembleErrors(t *testing.T) {
+	for _, src := range []string{"push @missing; jmp", "@a; @a; halt"} {
+		if _, err := Assemble(parse(t, src)); err == nil {
+			t.Errorf("Expected an error assembling %q", src)
+		}
+	}
+}
@@ -0,0 +1,248 @@
+// Package codegen generates PArIR from the mid-level IR.
+package codegen
+
+import (
+	"fmt"
+
+	"github.com/giuszeppe/compiler-theory/ir"
+	"github.com/giuszeppe/compiler-theory/parir"
+	"github.com/giuszeppe/compiler-theory/types"
+)
+
+// generator holds the state of Generate.
+type generator struct {
+	instrs []parir.Instruction
+	// stack mirrors the temporaries on the VM's operand stack, top last.
+	stack []*ir.Temp
+}
+
+// Generate translates prog to PArIR. main comes first, after the usual
+// entry sequence, and each other function follows under its label.
+//
+// Every temporary is left on the operand stack by the instruction that
+// defines it and taken off by the one that uses it, which relies on the
+// operand order described in package ir. Generate panics if prog breaks
+// it, since that is a bug in whatever produced prog.
+func Generate(prog *ir.Program) []parir.Instruction {
+	g := &generator{}
+	g.label("main")
+	g.push(parir.PC(3))
+	g.emit(parir.OpJmp)
+	g.emit(parir.OpHalt)
+
+	for i, fn := range prog.Funcs {
+		if i > 0 {
+			g.label(fn.Name)
+			g.push(parir.Int(fn.Locals))
+			g.emit(parir.OpAlloc)
+		}
+		for j, blk := range fn.Blocks {
+			var next *ir.Block
+			if j+1 < len(fn.Blocks) {
+				next = fn.Blocks[j+1]
+			}
+			g.block(blk, next)
+		}
+	}
+
+	instrs, err := Assemble(g.instrs)
+	if err != nil {
+		panic(err)
+	}
+	return instrs
+}
+
+func (g *generator) emit(op parir.Op) {
+	g.instrs = append(g.instrs, parir.Instruction{Op: op})
+}
+
+func (g *generator) push(arg parir.Operand) {
+	g.instrs = append(g.instrs, parir.Instruction{Op: parir.OpPush, Arg: arg})
+}
+
+// use takes temps off the mirrored stack, temps[0] from the top.
+func (g *generator) use(in fmt.Stringer, temps ...*ir.Temp) {
+	for _, t := range temps {
+		if len(g.stack) == 0 || g.stack[len(g.stack)-1] != t {
+			panic(fmt.Sprintf("codegen: %s is not on top of the stack at %q", t, in))
+		}
+		g.stack = g.stack[:len(g.stack)-1]
+	}
+}
+
+// def records that t is now on top of the stack.
+func (g *generator) def(t *ir.Temp) {
+	if t != nil {
+		g.stack = append(g.stack, t)
+	}
+}
+
+// block generates blk, which is followed by next in the layout, or by
+// nothing if next is nil.
+func (g *generator) block(blk *ir.Block, next *ir.Block) {
+	g.placeLabel(blk.Name)
+	for _, in := range blk.Instrs {
+		g.instr(in)
+	}
+
+	switch t := blk.Term.(type) {
+	case *ir.Jump:
+		if t.Target != next {
+			g.emitJump(parir.OpJmp, t.Target.Name)
+		}
+	case *ir.Branch:
+		g.use(t, t.Cond)
+		g.emitJump(parir.OpCJmp, t.Then.Name)
+		if t.Else != next {
+			g.emitJump(parir.OpJmp, t.Else.Name)
+		}
+	case *ir.Return:
+		// a function that passes values back returns them all, with its
+		// result, as one array
+		g.use(t, append(t.Outs, t.Value)...)
+		if types.IsArray(t.Value.Type) || len(t.Outs) > 0 {
+			n := types.Size(t.Value.Type)
+			for _, out := range t.Outs {
+				n += types.Size(out.Type)
+			}
+			g.push(parir.Int(n))
+			g.emit(parir.OpRetA)
+		} else {
+			g.emit(parir.OpRet)
+		}
+	case *ir.Halt, *ir.Unreachable:
+		g.emit(parir.OpHalt)
+	}
+	if len(g.stack) != 0 {
+		panic(fmt.Sprintf("codegen: %d temporaries left on the stack at the end of %s", len(g.stack), blk.Name))
+	}
+}
+
+func (g *generator) instr(in ir.Instr) {
+	switch in := in.(type) {
+	case *ir.Const:
+		switch in.Dst.Type {
+		case types.Float:
+			g.push(parir.Float(in.Value))
+		case types.Colour:
+			g.push(parir.Colour(int(in.Value)))
+		default:
+			g.push(parir.Int(int(in.Value)))
+		}
+		g.def(in.Dst)
+	case *ir.Load:
+		switch {
+		case in.Index != nil:
+			g.use(in, in.Index)
+			g.push(parir.IndexedSlot(in.Slot.Index, in.Slot.Level))
+		case types.IsArray(in.Dst.Type):
+			g.push(parir.Int(types.Size(in.Dst.Type)))
+			g.instrs = append(g.instrs, parir.Instruction{Op: parir.OpPushA, Arg: parir.Slot(in.Slot.Index, in.Slot.Level)})
+		default:
+			g.push(parir.Slot(in.Slot.Index, in.Slot.Level))
+		}
+		g.def(in.Dst)
+	case *ir.Store:
+		switch {
+		case in.Index != nil:
+			// st takes the address from the stack, so compute it there
+			g.use(in, in.Index, in.Value)
+			g.push(parir.Int(in.Slot.Index))
+			g.emit(parir.OpAdd)
+			g.push(parir.Int(in.Slot.Level))
+			g.emit(parir.OpSt)
+		case types.IsArray(in.Value.Type):
+			g.use(in, in.Value)
+			g.push(parir.Int(types.Size(in.Value.Type)))
+			g.push(parir.Int(in.Slot.Index))
+			g.push(parir.Int(in.Slot.Level))
+			g.emit(parir.OpStA)
+		default:
+			g.use(in, in.Value)
+			g.push(parir.Int(in.Slot.Index))
+			g.push(parir.Int(in.Slot.Level))
+			g.emit(parir.OpSt)
+		}
+	case *ir.Unary:
+		g.use(in, in.X)
+		switch in.Op {
+		case ir.OpNeg:
+			g.push(parir.Int(0))
+			g.emit(parir.OpSub)
+		case ir.OpNot:
+			g.emit(parir.OpNot)
+		}
+		g.def(in.Dst)
+	case *ir.Binary:
+		g.use(in, in.X, in.Y)
+		if in.Op == ir.OpNe {
+			g.emit(parir.OpEq)
+			g.emit(parir.OpNot)
+		} else {
+			g.emit(binaryOps[in.Op])
+		}
+		g.def(in.Dst)
+	case *ir.Cast:
+		g.use(in, in.X)
+		g.def(in.Dst)
+	case *ir.MakeArray:
+		g.use(in, in.Elems...)
+		g.def(in.Dst)
+	case *ir.Call:
+		g.use(in, in.Args...)
+		n := 0
+		for _, arg := range in.Args {
+			n += types.Size(arg.Type)
+		}
+		g.push(parir.Int(n))
+		g.push(parir.Label(in.Func))
+		g.emit(parir.OpCall)
+		g.def(in.Dst)
+		for i := len(in.Outs) - 1; i >= 0; i-- {
+			g.def(in.Outs[i])
+		}
+	case *ir.Builtin:
+		g.use(in, in.Args...)
+		if in.Name == "print" && types.IsArray(in.Args[0].Type) {
+			g.push(parir.Int(types.Size(in.Args[0].Type)))
+			g.emit(parir.OpPrintA)
+		} else {
+			g.emit(builtinOps[in.Name])
+		}
+		g.def(in.Dst)
+	case *ir.OpenFrame:
+		g.push(parir.Int(in.Size))
+		g.emit(parir.OpOFrame)
+	case *ir.CloseFrame:
+		g.emit(parir.OpCFrame)
+	default:
+		panic(fmt.Sprintf("codegen: unexpected instruction %T", in))
+	}
+}
+
+var binaryOps = map[ir.Op]parir.Op{
+	ir.OpAdd: parir.OpAdd,
+	ir.OpSub: parir.OpSub,
+	ir.OpMul: parir.OpMul,
+	ir.OpDiv: parir.OpDiv,
+	ir.OpMod: parir.OpMod,
+	ir.OpAnd: parir.OpAnd,
+	ir.OpOr:  parir.OpOr,
+	ir.OpEq:  parir.OpEq,
+	ir.OpLt:  parir.OpLt,
+	ir.OpLe:  parir.OpLe,
+	ir.OpGt:  parir.OpGt,
+	ir.OpGe:  parir.OpGe,
+}
+
+var builtinOps = map[string]parir.Op{
+	"print":      parir.OpPrint,
+	"delay":      parir.OpDelay,
+	"width":      parir.OpWidth,
+	"height":     parir.OpHeight,
+	"clear":      parir.OpClear,
+	"write":      parir.OpWrite,
+	"write_box":  parir.OpWriteBox,
+	"read":       parir.OpRead,
+	"random_int": parir.OpIRnd,
+}
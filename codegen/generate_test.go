@@ -0,0 +1,123 @@
+package codegen
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/giuszeppe/compiler-theory/diag"
+	"github.com/giuszeppe/compiler-theory/ir"
+	"github.com/giuszeppe/compiler-theory/parser"
+	"github.com/giuszeppe/compiler-theory/sema"
+	"github.com/giuszeppe/compiler-theory/types"
+	"github.com/giuszeppe/compiler-theory/vm"
+)
+
+func TestGenerate(t *testing.T) {
+	// if x != 2 then print x, with x in slot 0 of main's frame
+	x, two, ne, px := &ir.Temp{ID: 0, Type: types.Int}, &ir.Temp{ID: 1, Type: types.Int}, &ir.Temp{ID: 2, Type: types.Bool}, &ir.Temp{ID: 3, Type: types.Int}
+	then, end := &ir.Block{Name: "then"}, &ir.Block{Name: "end"}
+	entry := &ir.Block{
+		Name: "entry",
+		Instrs: []ir.Instr{
+			&ir.OpenFrame{Size: 1},
+			&ir.Const{Dst: two, Value: 2},
+			&ir.Load{Dst: x, Slot: ir.Slot{Index: 0, Level: 0}},
+			&ir.Binary{Dst: ne, Op: ir.OpNe, X: x, Y: two},
+		},
+		Term: &ir.Branch{Cond: ne, Then: then, Else: end},
+	}
+	then.Instrs = []ir.Instr{&ir.Load{Dst: px, Slot: ir.Slot{}}, &ir.Builtin{Name: "print", Args: []*ir.Temp{px}}}
+	then.Term = &ir.Jump{Target: end}
+	end.Instrs = []ir.Instr{&ir.CloseFrame{}}
+	end.Term = &ir.Halt{}
+	prog := &ir.Program{Funcs: []*ir.Func{{Name: "main", Blocks: []*ir.Block{entry, then, end}}}}
+
+	want := parse(t, ".main; push #PC+3; jmp; halt; "+
+		"push 1; oframe; push 2; push [0:0]; eq; not; push #PC+4; cjmp; push #PC+4; jmp; "+
+		"push [0:0]; print; "+
+		"cframe; halt")
+	if got := Generate(prog); !reflect.DeepEqual(got, want) {
+		t.Fatalf("Expected:\n%v\ngot:\n%v", want, got)
+	}
+}
+
+func TestGenerateStackOrder(t *testing.T) {
+	a, b, sum := &ir.Temp{ID: 0, Type: types.Int}, &ir.Temp{ID: 1, Type: types.Int}, &ir.Temp{ID: 2, Type: types.Int}
+	entry := &ir.Block{
+		Name: "entry",
+		Instrs: []ir.Instr{
+			&ir.Const{Dst: a, Value: 1},
+			&ir.Const{Dst: b, Value: 2},
+			// a is defined first, so it is not on top
+			&ir.Binary{Dst: sum, Op: ir.OpAdd, X: a, Y: b},
+		},
+		Term: &ir.Halt{},
+	}
+	defer func() {
+		if recover() == nil {
+			t.Fatal("Expected a panic for operands out of stack order")
+		}
+	}()
+	Generate(&ir.Program{Funcs: []*ir.Func{{Name: "main", Blocks: []*ir.Block{entry}}}})
+}
+
+func TestGenerateFunctionReadsGlobal(t *testing.T) {
+	// f runs at a different frame depth on every call, so base cannot be
+	// reached at a fixed level from where f is declared
+	src := `let base:int = 100;
+	fun f(n:int) -> int {
+		if (n <= 0) { return base; }
+		return f(n - 1) + 1;
+	}
+	__print f(2);
+	{ let i:int = 0; while (i < 1) { __print f(0); i = i + 1; } }`
+	if got, want := run(t, src), "102\n100\n"; got != want {
+		t.Errorf("Expected output %q, got %q", want, got)
+	}
+}
+
+func TestGenerateFunctionUpdatesGlobal(t *testing.T) {
+	// the assignments reach main through the calls of count, each of
+	// which passes total and xs back to its caller
+	src := `let total:int = 0;
+	let xs:int[2] = [0, 0];
+	fun add(n:int) -> int { total = total + n; return total; }
+	fun mark(i:int) -> int { xs[i] = i + 1; return xs[0]; }
+	fun count(n:int) -> int {
+		if (n <= 0) { return 0; }
+		total = total + 1;
+		let r:int = count(n - 1);
+		{ let m:int = mark(0); return r + m; }
+	}
+	__print add(5);
+	__print count(3);
+	__print total;
+	__print xs;`
+	if got, want := run(t, src), "5\n3\n8\n[1, 0]\n"; got != want {
+		t.Errorf("Expected output %q, got %q", want, got)
+	}
+}
+
+// run compiles src and returns what it prints on the VM.
+func run(t *testing.T, src string) string {
+	t.Helper()
+	p := parser.NewParser(src)
+	root, err := p.Parse(parser.NewGrammar())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diags := sema.NewSemanticVisitor().Analyze(root); diag.HasErrors(diags) {
+		t.Fatal(diags)
+	}
+	machine, err := vm.NewVM(Generate(ir.Build(root)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out strings.Builder
+	machine.Out = &out
+	if err := machine.Run(); err != nil {
+		t.Fatal(err)
+	}
+	return out.String()
+}
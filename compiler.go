@@ -0,0 +1,135 @@
+// Package compiler compiles PArL source programs to PArIR.
+//
+// The phases are importable on their own (lexer, parser, sema, ir,
+// codegen, vm); Compile runs them in order and is what most callers want.
+package compiler
+
+import (
+	"strings"
+
+	"github.com/giuszeppe/compiler-theory/ast"
+	"github.com/giuszeppe/compiler-theory/codegen"
+	"github.com/giuszeppe/compiler-theory/diag"
+	"github.com/giuszeppe/compiler-theory/ir"
+	"github.com/giuszeppe/compiler-theory/optimize"
+	"github.com/giuszeppe/compiler-theory/parir"
+	"github.com/giuszeppe/compiler-theory/parser"
+	"github.com/giuszeppe/compiler-theory/sema"
+)
+
+// Phase identifies a compiler phase.
+type Phase int
+
+const (
+	PhaseLex Phase = iota + 1
+	PhaseParse
+	PhaseCheck
+	PhaseCodegen
+)
+
+// CodeInvalidPArIR reports generated code that fails parir.Verify, which
+// is a bug in the compiler rather than in the program. It is only checked
+// in debug builds.
+const CodeInvalidPArIR = "V001"
+
+func (p Phase) String() string {
+	switch p {
+	case PhaseLex:
+		return "lexical"
+	case PhaseParse:
+		return "syntax"
+	case PhaseCheck:
+		return "semantic"
+	case PhaseCodegen:
+		return "codegen"
+	default:
+		return "unknown"
+	}
+}
+
+// PhaseOf returns the phase that reported d, judging by its code.
+func PhaseOf(d diag.Diagnostic) Phase {
+	switch {
+	case strings.HasPrefix(d.Code, "L"):
+		return PhaseLex
+	case strings.HasPrefix(d.Code, "S"):
+		return PhaseParse
+	case strings.HasPrefix(d.Code, "E"), strings.HasPrefix(d.Code, "W"):
+		return PhaseCheck
+	default:
+		return PhaseCodegen
+	}
+}
+
+// Options controls a call to Compile.
+type Options struct {
+	// StopAfter ends compilation after the given phase. The zero value
+	// runs every phase.
+	StopAfter Phase
+	// OptLevel is the level passed to optimize.Peephole for the generated
+	// code. The zero value leaves it as generated.
+	OptLevel int
+	// Warnings sets which warnings are reported, and which as errors.
+	// "// prl:ignore" comments in the source are honoured whatever it
+	// says.
+	Warnings diag.WarningConfig
+}
+
+// Result is the output of Compile. Fields for phases that did not run are
+// left empty.
+type Result struct {
+	// AST is the parsed program. After syntax errors it is the partial
+	// tree produced by error recovery, or nil.
+	AST ast.ASTNode
+	// IR is the program lowered to the mid-level IR.
+	IR *ir.Program
+	// Instructions is the generated PArIR.
+	Instructions []parir.Instruction
+}
+
+// Compile compiles src, stopping at the first phase that reports an error.
+// The returned diagnostics hold every error and warning reported up to
+// that point.
+func Compile(src string, opts Options) (Result, []diag.Diagnostic) {
+	var res Result
+	stopAfter := func(phase Phase) bool {
+		return opts.StopAfter != 0 && opts.StopAfter <= phase
+	}
+
+	p := parser.NewParser(src)
+	node, err := p.Parse(parser.NewGrammar())
+	res.AST = node
+	if err != nil {
+		return res, p.Errors
+	}
+	if stopAfter(PhaseParse) {
+		return res, nil
+	}
+
+	diags := sema.NewSemanticVisitor().Analyze(node)
+	diags = opts.Warnings.Apply(diags, diag.ParseIgnores(p.Tokens))
+	if diag.HasErrors(diags) || stopAfter(PhaseCheck) {
+		return res, diags
+	}
+
+	res.IR = ir.Build(node)
+	instrs := codegen.Generate(res.IR)
+	if debug {
+		diags = append(diags, verify(instrs, "code generator")...)
+	}
+	res.Instructions = optimize.Peephole(instrs, opts.OptLevel)
+	if debug && opts.OptLevel > 0 && !diag.HasErrors(diags) {
+		diags = append(diags, verify(res.Instructions, "optimiser")...)
+	}
+	return res, diags
+}
+
+// verify checks prog, the output of producer, with parir.Verify.
+func verify(prog []parir.Instruction, producer string) []diag.Diagnostic {
+	var diags []diag.Diagnostic
+	for _, err := range parir.Verify(prog) {
+		diags = append(diags, diag.NewError(CodeInvalidPArIR, diag.Span{},
+			"internal error: the %s produced invalid PArIR at %s: %v", producer, prog[err.At], err))
+	}
+	return diags
+}
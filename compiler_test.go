@@ -0,0 +1,93 @@
+package compiler
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/giuszeppe/compiler-theory/parir"
+	"github.com/giuszeppe/compiler-theory/parser"
+	"github.com/giuszeppe/compiler-theory/sema"
+)
+
+func TestCompileValidProgram(t *testing.T) {
+	res, diags := Compile("let x:int = 1; __print x;", Options{})
+	if len(diags) != 0 {
+		t.Fatalf("Expected no diagnostics, got %v", diags)
+	}
+	if res.AST == nil || len(res.Instructions) == 0 {
+		t.Fatalf("Expected an AST and instructions, got %+v", res)
+	}
+}
+
+func TestCompileStopsAtFirstFailingPhase(t *testing.T) {
+	tests := []struct {
+		src   string
+		code  string
+		phase Phase
+	}{
+		{"let x:int = 1; @", parser.CodeInvalidToken, PhaseLex},
+		{"let x:int = ;", parser.CodeNoRule, PhaseParse},
+		{"let x:int = true;", sema.CodeTypeMismatch, PhaseCheck},
+	}
+	for _, tt := range tests {
+		res, diags := Compile(tt.src, Options{})
+		if len(diags) != 1 || diags[0].Code != tt.code {
+			t.Fatalf("%q: expected %s, got %v", tt.src, tt.code, diags)
+		}
+		if PhaseOf(diags[0]) != tt.phase {
+			t.Fatalf("%q: expected phase %v, got %v", tt.src, tt.phase, PhaseOf(diags[0]))
+		}
+		if res.Instructions != nil {
+			t.Fatalf("%q: expected no instructions after an error", tt.src)
+		}
+	}
+}
+
+func TestCompileStopAfter(t *testing.T) {
+	res, diags := Compile("let x:int = true;", Options{StopAfter: PhaseParse})
+	if len(diags) != 0 {
+		t.Fatalf("Expected semantic analysis to be skipped, got %v", diags)
+	}
+	if res.AST == nil {
+		t.Fatalf("Expected an AST")
+	}
+
+	res, _ = Compile("let x:int = 1;", Options{StopAfter: PhaseCheck})
+	if res.Instructions != nil {
+		t.Fatalf("Expected code generation to be skipped")
+	}
+}
+
+// TestGeneratedCodeVerifies checks the code generated for the example
+// programs at every optimisation level, as debug builds do on every
+// compile.
+func TestGeneratedCodeVerifies(t *testing.T) {
+	paths, err := filepath.Glob("examples/*.prl")
+	if err != nil || len(paths) == 0 {
+		t.Fatalf("No examples found: %v", err)
+	}
+	for _, path := range paths {
+		src, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for level := 0; level <= 2; level++ {
+			res, _ := Compile(string(src), Options{OptLevel: level})
+			if res.Instructions == nil {
+				continue
+			}
+			if errs := parir.Verify(res.Instructions); len(errs) != 0 {
+				t.Errorf("%s at -O%d: %v", path, level, errs)
+			}
+		}
+	}
+}
+
+func TestVerifyReportsCompilerBugs(t *testing.T) {
+	prog := []parir.Instruction{{Op: parir.OpCFrame}}
+	diags := verify(prog, "code generator")
+	if len(diags) != 1 || diags[0].Code != CodeInvalidPArIR || PhaseOf(diags[0]) != PhaseCodegen {
+		t.Fatalf("Expected one %s diagnostic, got %v", CodeInvalidPArIR, diags)
+	}
+}
@@ -0,0 +1,7 @@
+//go:build debug
+
+package compiler
+
+// debug turns on checks of the compiler's own output. Build with -tags
+// debug to enable it.
+const debug = true
@@ -0,0 +1,146 @@
+// Package diag defines the diagnostics reported by every compiler phase.
+package diag
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/giuszeppe/compiler-theory/lexer"
+)
+
+type Severity int
+
+const (
+	SeverityError Severity = iota
+	SeverityWarning
+	SeverityNote
+)
+
+func (s Severity) String() string {
+	switch s {
+	case SeverityError:
+		return "error"
+	case SeverityWarning:
+		return "warning"
+	case SeverityNote:
+		return "note"
+	default:
+		return "unknown"
+	}
+}
+
+// Pos is a position in the source program. Offset is a 0-based byte offset;
+// Line and Column are 1-based. The zero Pos means the position is unknown.
+type Pos struct {
+	Offset int
+	Line   int
+	Column int
+}
+
+func (p Pos) IsValid() bool {
+	return p.Line > 0
+}
+
+// Span is the source range [Start, End) a diagnostic refers to.
+type Span struct {
+	Start Pos
+	End   Pos
+}
+
+// SpanOf returns the span covered by tok.
+func SpanOf(tok lexer.Token) Span {
+	start := Pos{Offset: tok.Offset, Line: tok.Line, Column: tok.Column}
+	end := start
+	if tok.Type == lexer.End {
+		return Span{Start: start, End: end}
+	}
+	for i := 0; i < len(tok.Lexeme); i++ {
+		if tok.Lexeme[i] == '\n' {
+			end.Line++
+			end.Column = 1
+		} else {
+			end.Column++
+		}
+	}
+	end.Offset += len(tok.Lexeme)
+	return Span{Start: start, End: end}
+}
+
+// Diagnostic is a single finding reported by one of the compiler phases.
+type Diagnostic struct {
+	Severity Severity
+	Code     string
+	Message  string
+	Span     Span
+	// Notes add context to the message, such as where a clashing name
+	// was declared, or suggest a fix.
+	Notes []Note
+}
+
+// Note is a remark attached to a diagnostic. Help notes suggest a fix. A
+// note with a valid Span refers to that part of the source.
+type Note struct {
+	Message string
+	Span    Span
+	Help    bool
+}
+
+// WithNote returns d with a note about span, which may be the zero Span.
+func (d Diagnostic) WithNote(span Span, format string, args ...any) Diagnostic {
+	d.Notes = append(d.Notes[:len(d.Notes):len(d.Notes)], Note{Message: fmt.Sprintf(format, args...), Span: span})
+	return d
+}
+
+// WithHelp returns d with a help note suggesting a fix.
+func (d Diagnostic) WithHelp(format string, args ...any) Diagnostic {
+	d.Notes = append(d.Notes[:len(d.Notes):len(d.Notes)], Note{Message: fmt.Sprintf(format, args...), Help: true})
+	return d
+}
+
+func (d Diagnostic) Error() string {
+	if !d.Span.Start.IsValid() {
+		return d.Message
+	}
+	return fmt.Sprintf("%s (at line %d, column %d)", d.Message, d.Span.Start.Line, d.Span.Start.Column)
+}
+
+// HasErrors reports whether any of diags has error severity.
+func HasErrors(diags []Diagnostic) bool {
+	for _, d := range diags {
+		if d.Severity == SeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+// Diagnostics is a list of diagnostics that can be returned as an error.
+type Diagnostics []Diagnostic
+
+func (ds Diagnostics) Error() string {
+	msgs := make([]string, len(ds))
+	for i, d := range ds {
+		msgs[i] = d.Error()
+	}
+	return strings.Join(msgs, "\n")
+}
+
+// NewError returns an error diagnostic with a formatted message.
+func NewError(code string, span Span, format string, args ...any) Diagnostic {
+	return Diagnostic{
+		Severity: SeverityError,
+		Code:     code,
+		Message:  fmt.Sprintf(format, args...),
+		Span:     span,
+	}
+}
+
+// NewWarning returns a warning diagnostic with a formatted message.
+func NewWarning(code string, span Span, format string, args ...any) Diagnostic {
+	return Diagnostic{
+		Severity: SeverityWarning,
+		Code:     code,
+		Message:  fmt.Sprintf(format, args...),
+		Span:     span,
+	}
+}
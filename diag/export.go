@@ -0,0 +1,194 @@
+package diag
+
+import (
+	"encoding/json"
+	"io"
+	"path/filepath"
+	"strings"
+)
+
+// jsonDiagnostic is the form WriteJSON gives a diagnostic.
+type jsonDiagnostic struct {
+	File     string     `json:"file"`
+	Code     string     `json:"code"`
+	Severity string     `json:"severity"`
+	Message  string     `json:"message"`
+	Span     *jsonSpan  `json:"span,omitempty"`
+	Notes    []jsonNote `json:"notes,omitempty"`
+}
+
+type jsonNote struct {
+	Kind    string    `json:"kind"` // "note" or "help"
+	Message string    `json:"message"`
+	Span    *jsonSpan `json:"span,omitempty"`
+}
+
+type jsonSpan struct {
+	Start jsonPos `json:"start"`
+	End   jsonPos `json:"end"`
+}
+
+type jsonPos struct {
+	Line   int `json:"line"`
+	Column int `json:"column"`
+	Offset int `json:"offset"`
+}
+
+func toJSONSpan(s Span) *jsonSpan {
+	if !s.Start.IsValid() {
+		return nil
+	}
+	return &jsonSpan{
+		Start: jsonPos{Line: s.Start.Line, Column: s.Start.Column, Offset: s.Start.Offset},
+		End:   jsonPos{Line: s.End.Line, Column: s.End.Column, Offset: s.End.Offset},
+	}
+}
+
+// WriteJSON writes diags, found in the file at path, to w as a JSON array.
+// Spans are left out where they are unknown, and notes with a span are the
+// related locations of a diagnostic.
+func WriteJSON(w io.Writer, path string, diags []Diagnostic) error {
+	out := make([]jsonDiagnostic, len(diags))
+	for i, d := range diags {
+		out[i] = jsonDiagnostic{
+			File:     path,
+			Code:     d.Code,
+			Severity: d.Severity.String(),
+			Message:  d.Message,
+			Span:     toJSONSpan(d.Span),
+		}
+		for _, n := range d.Notes {
+			kind := "note"
+			if n.Help {
+				kind = "help"
+			}
+			out[i].Notes = append(out[i].Notes, jsonNote{Kind: kind, Message: n.Message, Span: toJSONSpan(n.Span)})
+		}
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(out)
+}
+
+// The subset of SARIF 2.1.0 that WriteSARIF produces.
+type (
+	sarifLog struct {
+		Schema  string     `json:"$schema"`
+		Version string     `json:"version"`
+		Runs    []sarifRun `json:"runs"`
+	}
+	sarifRun struct {
+		Tool    sarifTool     `json:"tool"`
+		Results []sarifResult `json:"results"`
+	}
+	sarifTool struct {
+		Driver sarifDriver `json:"driver"`
+	}
+	sarifDriver struct {
+		Name  string      `json:"name"`
+		Rules []sarifRule `json:"rules"`
+	}
+	sarifRule struct {
+		ID string `json:"id"`
+	}
+	sarifResult struct {
+		RuleID           string          `json:"ruleId"`
+		Level            string          `json:"level"`
+		Message          sarifMessage    `json:"message"`
+		Locations        []sarifLocation `json:"locations"`
+		RelatedLocations []sarifLocation `json:"relatedLocations,omitempty"`
+	}
+	sarifMessage struct {
+		Text string `json:"text"`
+	}
+	sarifLocation struct {
+		ID               *int                  `json:"id,omitempty"`
+		PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+		Message          *sarifMessage         `json:"message,omitempty"`
+	}
+	sarifPhysicalLocation struct {
+		ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+		Region           *sarifRegion          `json:"region,omitempty"`
+	}
+	sarifArtifactLocation struct {
+		URI string `json:"uri"`
+	}
+	sarifRegion struct {
+		StartLine   int `json:"startLine"`
+		StartColumn int `json:"startColumn"`
+		EndLine     int `json:"endLine"`
+		EndColumn   int `json:"endColumn"`
+	}
+)
+
+func sarifLocationOf(uri string, s Span) sarifLocation {
+	loc := sarifLocation{PhysicalLocation: sarifPhysicalLocation{ArtifactLocation: sarifArtifactLocation{URI: uri}}}
+	if s.Start.IsValid() {
+		end := s.End
+		if !end.IsValid() {
+			end = s.Start
+		}
+		loc.PhysicalLocation.Region = &sarifRegion{
+			StartLine:   s.Start.Line,
+			StartColumn: s.Start.Column,
+			EndLine:     end.Line,
+			EndColumn:   end.Column,
+		}
+	}
+	return loc
+}
+
+// WriteSARIF writes diags, found in the file at path, to w as a SARIF 2.1.0
+// log with a single run of the tool named tool. Notes with a span become
+// related locations; the others are appended to the message.
+func WriteSARIF(w io.Writer, tool, path string, diags []Diagnostic) error {
+	uri := filepath.ToSlash(path)
+	run := sarifRun{Tool: sarifTool{Driver: sarifDriver{Name: tool, Rules: []sarifRule{}}}, Results: []sarifResult{}}
+	seen := make(map[string]bool)
+	for _, d := range diags {
+		if !seen[d.Code] {
+			seen[d.Code] = true
+			run.Tool.Driver.Rules = append(run.Tool.Driver.Rules, sarifRule{ID: d.Code})
+		}
+		level := "note"
+		switch d.Severity {
+		case SeverityError:
+			level = "error"
+		case SeverityWarning:
+			level = "warning"
+		}
+
+		text := []string{d.Message}
+		var related []sarifLocation
+		for _, n := range d.Notes {
+			kind := "note"
+			if n.Help {
+				kind = "help"
+			}
+			if !n.Span.Start.IsValid() {
+				text = append(text, kind+": "+n.Message)
+				continue
+			}
+			loc := sarifLocationOf(uri, n.Span)
+			id := len(related) + 1
+			loc.ID = &id
+			loc.Message = &sarifMessage{Text: n.Message}
+			related = append(related, loc)
+		}
+		run.Results = append(run.Results, sarifResult{
+			RuleID:           d.Code,
+			Level:            level,
+			Message:          sarifMessage{Text: strings.Join(text, "\n")},
+			Locations:        []sarifLocation{sarifLocationOf(uri, d.Span)},
+			RelatedLocations: related,
+		})
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(sarifLog{
+		Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
+		Version: "2.1.0",
+		Runs:    []sarifRun{run},
+	})
+}
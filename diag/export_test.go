@@ -0,0 +1,116 @@
+package diag
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func exportDiagnostics() []Diagnostic {
+	first := Span{Start: Pos{Offset: 4, Line: 1, Column: 5}, End: Pos{Offset: 5, Line: 1, Column: 6}}
+	second := Span{Start: Pos{Offset: 19, Line: 2, Column: 5}, End: Pos{Offset: 20, Line: 2, Column: 6}}
+	return []Diagnostic{
+		NewError("E002", second, "Variable already declared: x").WithNote(first, "x first declared here"),
+		NewWarning("W003", first, "Variable is never read: x").WithHelp("remove it"),
+		NewError("V001", Span{}, "internal error"),
+	}
+}
+
+func TestWriteJSON(t *testing.T) {
+	var b strings.Builder
+	if err := WriteJSON(&b, "prog.prl", exportDiagnostics()); err != nil {
+		t.Fatal(err)
+	}
+	var got []struct {
+		File, Code, Severity, Message string
+		Span                          *struct {
+			Start, End struct{ Line, Column, Offset int }
+		}
+		Notes []struct {
+			Kind, Message string
+			Span          *struct{ Start struct{ Line int } }
+		}
+	}
+	if err := json.Unmarshal([]byte(b.String()), &got); err != nil {
+		t.Fatalf("Invalid JSON: %v\n%s", err, b.String())
+	}
+	if len(got) != 3 {
+		t.Fatalf("Expected 3 diagnostics, got %d", len(got))
+	}
+	d := got[0]
+	if d.File != "prog.prl" || d.Code != "E002" || d.Severity != "error" || d.Span == nil || d.Span.Start.Line != 2 || d.Span.End.Column != 6 {
+		t.Fatalf("Unexpected diagnostic %+v", d)
+	}
+	if len(d.Notes) != 1 || d.Notes[0].Kind != "note" || d.Notes[0].Span == nil || d.Notes[0].Span.Start.Line != 1 {
+		t.Fatalf("Expected the declaration as a related location, got %+v", d.Notes)
+	}
+	if n := got[1].Notes; len(n) != 1 || n[0].Kind != "help" || n[0].Span != nil {
+		t.Fatalf("Expected a help note without a span, got %+v", n)
+	}
+	if got[2].Span != nil {
+		t.Fatalf("Expected no span for an unknown position, got %+v", got[2].Span)
+	}
+
+	b.Reset()
+	WriteJSON(&b, "prog.prl", nil)
+	if strings.TrimSpace(b.String()) != "[]" {
+		t.Fatalf("Expected an empty array, got %q", b.String())
+	}
+}
+
+func TestWriteSARIF(t *testing.T) {
+	var b strings.Builder
+	if err := WriteSARIF(&b, "prlc", "dir/prog.prl", exportDiagnostics()); err != nil {
+		t.Fatal(err)
+	}
+	var log struct {
+		Version string
+		Runs    []struct {
+			Tool struct {
+				Driver struct {
+					Name  string
+					Rules []struct{ ID string }
+				}
+			}
+			Results []struct {
+				RuleID    string
+				Level     string
+				Message   struct{ Text string }
+				Locations []struct {
+					PhysicalLocation struct {
+						ArtifactLocation struct{ URI string }
+						Region           *struct{ StartLine, StartColumn, EndLine, EndColumn int }
+					}
+				}
+				RelatedLocations []struct {
+					ID      int
+					Message struct{ Text string }
+				}
+			}
+		}
+	}
+	if err := json.Unmarshal([]byte(b.String()), &log); err != nil {
+		t.Fatalf("Invalid JSON: %v\n%s", err, b.String())
+	}
+	if log.Version != "2.1.0" || len(log.Runs) != 1 {
+		t.Fatalf("Unexpected log %+v", log)
+	}
+	run := log.Runs[0]
+	if run.Tool.Driver.Name != "prlc" || len(run.Tool.Driver.Rules) != 3 || len(run.Results) != 3 {
+		t.Fatalf("Unexpected run %+v", run)
+	}
+	r := run.Results[0]
+	loc := r.Locations[0].PhysicalLocation
+	if r.RuleID != "E002" || r.Level != "error" || loc.ArtifactLocation.URI != "dir/prog.prl" || loc.Region == nil || loc.Region.StartLine != 2 || loc.Region.StartColumn != 5 {
+		t.Fatalf("Unexpected result %+v", r)
+	}
+	if len(r.RelatedLocations) != 1 || r.RelatedLocations[0].ID != 1 || r.RelatedLocations[0].Message.Text != "x first declared here" {
+		t.Fatalf("Expected the declaration as a related location, got %+v", r.RelatedLocations)
+	}
+	if r := run.Results[1]; r.Level != "warning" || r.Message.Text != "Variable is never read: x\nhelp: remove it" {
+		t.Fatalf("Unexpected result %+v", r)
+	}
+	if r := run.Results[2]; r.Locations[0].PhysicalLocation.Region != nil {
+		t.Fatalf("Expected no region for an unknown position, got %+v", r.Locations[0])
+	}
+}
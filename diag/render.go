@@ -0,0 +1,143 @@
+package diag
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// ANSI escape sequences used by a Renderer with Colour set.
+const (
+	ansiReset  = "\x1b[0m"
+	ansiBold   = "\x1b[1m"
+	ansiRed    = "\x1b[1;31m"
+	ansiYellow = "\x1b[1;33m"
+	ansiGreen  = "\x1b[1;32m"
+	ansiCyan   = "\x1b[1;36m"
+	ansiBlue   = "\x1b[1;34m"
+)
+
+// Renderer writes diagnostics in the style of rustc: a header with the
+// severity, code and message, the source line the span starts on with the
+// span underlined, and then the notes.
+//
+//	error[E003]: Type mismatch: expected int, got float
+//	 --> prog.prl:1:5
+//	  |
+//	1 | let x:int = 1.5;
+//	  |     ^
+//	  = help: cast the value with `as int`
+type Renderer struct {
+	// Path names the source file in the output.
+	Path string
+	// Src is the source the diagnostics refer to.
+	Src string
+	// Colour adds ANSI colours, for terminals.
+	Colour bool
+
+	lines []string
+}
+
+// Render writes d to w, followed by a blank line.
+func (r *Renderer) Render(w io.Writer, d Diagnostic) error {
+	if r.lines == nil {
+		r.lines = strings.Split(r.Src, "\n")
+	}
+	gutter := r.gutterWidth(d)
+
+	var b strings.Builder
+	b.WriteString(r.paint(severityColour(d.Severity), fmt.Sprintf("%s[%s]", d.Severity, d.Code)))
+	b.WriteString(r.paint(ansiBold, ": "+d.Message))
+	b.WriteByte('\n')
+	r.snippet(&b, d.Span, gutter, '^', severityColour(d.Severity))
+
+	// notes about other parts of the source get snippets of their own,
+	// after the notes that only add a remark
+	for _, n := range d.Notes {
+		if n.Span.Start.IsValid() {
+			continue
+		}
+		kind, colour := "note", ansiGreen
+		if n.Help {
+			kind, colour = "help", ansiCyan
+		}
+		fmt.Fprintf(&b, "%s %s %s\n", strings.Repeat(" ", gutter), r.paint(ansiBlue, "="), r.paint(colour, kind+":")+" "+n.Message)
+	}
+	for _, n := range d.Notes {
+		if !n.Span.Start.IsValid() {
+			continue
+		}
+		kind, colour := "note", ansiGreen
+		if n.Help {
+			kind, colour = "help", ansiCyan
+		}
+		b.WriteString(r.paint(colour, kind) + r.paint(ansiBold, ": "+n.Message) + "\n")
+		r.snippet(&b, n.Span, gutter, '-', ansiBlue)
+	}
+	b.WriteByte('\n')
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// snippet writes the location of span and the source line it starts on,
+// underlining the span with mark. It writes nothing for an invalid span.
+func (r *Renderer) snippet(b *strings.Builder, span Span, gutter int, mark byte, colour string) {
+	if !span.Start.IsValid() {
+		return
+	}
+	pad := strings.Repeat(" ", gutter)
+	fmt.Fprintf(b, "%s%s %s:%d:%d\n", pad, r.paint(ansiBlue, "-->"), r.Path, span.Start.Line, span.Start.Column)
+	if span.Start.Line > len(r.lines) {
+		return
+	}
+	line := strings.TrimRight(r.lines[span.Start.Line-1], "\r")
+	bar := r.paint(ansiBlue, "|")
+	fmt.Fprintf(b, "%s %s\n", pad, bar)
+	fmt.Fprintf(b, "%s %s %s\n", r.paint(ansiBlue, fmt.Sprintf("%*d", gutter, span.Start.Line)), bar, line)
+
+	// Columns count bytes. Keep the tabs before the span so that the
+	// underline lines up however the terminal shows them.
+	start := min(span.Start.Column-1, len(line))
+	var indent strings.Builder
+	for i := 0; i < start; i++ {
+		if line[i] == '\t' {
+			indent.WriteByte('\t')
+		} else {
+			indent.WriteByte(' ')
+		}
+	}
+	width := len(line) - start
+	if span.End.Line == span.Start.Line {
+		width = span.End.Column - span.Start.Column
+	}
+	width = max(width, 1)
+	fmt.Fprintf(b, "%s %s %s%s\n", pad, bar, indent.String(), r.paint(colour, strings.Repeat(string(mark), width)))
+}
+
+// gutterWidth returns the width of the widest line number d shows.
+func (r *Renderer) gutterWidth(d Diagnostic) int {
+	line := d.Span.Start.Line
+	for _, n := range d.Notes {
+		line = max(line, n.Span.Start.Line)
+	}
+	return len(strconv.Itoa(line))
+}
+
+func (r *Renderer) paint(colour, s string) string {
+	if !r.Colour {
+		return s
+	}
+	return colour + s + ansiReset
+}
+
+func severityColour(s Severity) string {
+	switch s {
+	case SeverityError:
+		return ansiRed
+	case SeverityWarning:
+		return ansiYellow
+	default:
+		return ansiGreen
+	}
+}
@@ -0,0 +1,44 @@
+package diag
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRender(t *testing.T) {
+	src := "let x:int = 5;\n\tx = 2.5;\n"
+	d := NewError("E003", Span{Start: Pos{Offset: 16, Line: 2, Column: 2}, End: Pos{Offset: 17, Line: 2, Column: 3}}, "Type mismatch: expected int, got float").
+		WithHelp("cast the value with `as int`").
+		WithNote(Span{Start: Pos{Offset: 4, Line: 1, Column: 5}, End: Pos{Offset: 5, Line: 1, Column: 6}}, "x declared here as int")
+
+	var b strings.Builder
+	r := &Renderer{Path: "prog.prl", Src: src}
+	if err := r.Render(&b, d); err != nil {
+		t.Fatal(err)
+	}
+	want := "error[E003]: Type mismatch: expected int, got float\n" +
+		" --> prog.prl:2:2\n" +
+		"  |\n" +
+		"2 | \tx = 2.5;\n" +
+		"  | \t^\n" +
+		"  = help: cast the value with `as int`\n" +
+		"note: x declared here as int\n" +
+		" --> prog.prl:1:5\n" +
+		"  |\n" +
+		"1 | let x:int = 5;\n" +
+		"  |     -\n" +
+		"\n"
+	if b.String() != want {
+		t.Fatalf("Unexpected rendering:\n%s\nwant:\n%s", b.String(), want)
+	}
+}
+
+func TestRenderWithoutSpan(t *testing.T) {
+	var b strings.Builder
+	r := &Renderer{Path: "prog.prl", Src: "", Colour: true}
+	r.Render(&b, NewError("V001", Span{}, "internal error"))
+	want := ansiRed + "error[V001]" + ansiReset + ansiBold + ": internal error" + ansiReset + "\n\n"
+	if b.String() != want {
+		t.Fatalf("Unexpected rendering %q, want %q", b.String(), want)
+	}
+}
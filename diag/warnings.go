@@ -0,0 +1,150 @@
+package diag
+
+import (
+	"strings"
+
+	"github.com/giuszeppe/compiler-theory/lexer"
+)
+
+// Level is how a warning is reported.
+type Level int
+
+const (
+	LevelWarn  Level = iota // reported as a warning, the default
+	LevelAllow              // not reported
+	LevelDeny               // reported as an error
+)
+
+// WarningConfig sets the level of each warning. The zero value reports
+// every warning as a warning.
+type WarningConfig struct {
+	// Levels sets the level of the warnings with the given codes.
+	Levels map[string]Level
+	// Error reports the warnings not in Levels as errors (-Werror).
+	Error bool
+}
+
+// Level returns the level of the warning with the given code.
+func (c WarningConfig) Level(code string) Level {
+	if level, ok := c.Levels[code]; ok {
+		return level
+	}
+	if c.Error {
+		return LevelDeny
+	}
+	return LevelWarn
+}
+
+// SetLevel sets the level of the warning with the given code.
+func (c *WarningConfig) SetLevel(code string, level Level) {
+	if c.Levels == nil {
+		c.Levels = make(map[string]Level)
+	}
+	c.Levels[code] = level
+}
+
+// Apply returns diags with the warnings that are allowed or ignored left
+// out and those that are denied turned into errors. Other diagnostics are
+// kept as they are.
+func (c WarningConfig) Apply(diags []Diagnostic, ignores Ignores) []Diagnostic {
+	var out []Diagnostic
+	for _, d := range diags {
+		if d.Severity == SeverityWarning {
+			if ignores.Ignored(d) {
+				continue
+			}
+			switch c.Level(d.Code) {
+			case LevelAllow:
+				continue
+			case LevelDeny:
+				d.Severity = SeverityError
+			}
+		}
+		out = append(out, d)
+	}
+	return out
+}
+
+// IgnoreDirective starts a comment that suppresses warnings, such as
+// "// prl:ignore W003 W004". Without codes it suppresses every warning.
+const IgnoreDirective = "prl:ignore"
+
+// Ignores maps source lines to the codes of the warnings suppressed on
+// them. A line mapped to an empty list has every warning suppressed.
+type Ignores map[int][]string
+
+// ParseIgnores finds the ignore directives among tokens. A directive at
+// the end of a line applies to that line; one on a line of its own
+// applies to the next line holding code.
+func ParseIgnores(tokens []lexer.Token) Ignores {
+	ignores := make(Ignores)
+	var pending [][]string
+	codeLine := 0 // the last line a code token was seen on
+	for _, tok := range tokens {
+		switch tok.Type {
+		case lexer.WhitespaceToken, lexer.NewLineToken, lexer.CommentMultiLine, lexer.End:
+		case lexer.CommentSingleLine:
+			codes, ok := parseIgnore(tok.Lexeme)
+			if !ok {
+				break
+			}
+			if tok.Line == codeLine {
+				ignores.add(tok.Line, codes)
+			} else {
+				pending = append(pending, codes)
+			}
+		default:
+			codeLine = tok.Line
+			for _, codes := range pending {
+				ignores.add(tok.Line, codes)
+			}
+			pending = nil
+		}
+	}
+	return ignores
+}
+
+// parseIgnore returns the codes listed by the ignore directive in comment,
+// which may separate them with spaces or commas, and whether comment is
+// one.
+func parseIgnore(comment string) ([]string, bool) {
+	text := strings.TrimSpace(strings.TrimPrefix(comment, "//"))
+	rest, ok := strings.CutPrefix(text, IgnoreDirective)
+	if !ok || (rest != "" && rest[0] != ' ' && rest[0] != '\t') {
+		return nil, false
+	}
+	codes := strings.FieldsFunc(rest, func(r rune) bool {
+		return r == ',' || r == ' ' || r == '\t'
+	})
+	return codes, true
+}
+
+func (ig Ignores) add(line int, codes []string) {
+	old, seen := ig[line]
+	switch {
+	case seen && len(old) == 0:
+		// already ignoring everything
+	case len(codes) == 0:
+		ig[line] = []string{}
+	default:
+		ig[line] = append(old, codes...)
+	}
+}
+
+// Ignored reports whether d is suppressed by a directive on the line it
+// starts on.
+func (ig Ignores) Ignored(d Diagnostic) bool {
+	codes, ok := ig[d.Span.Start.Line]
+	if !ok {
+		return false
+	}
+	if len(codes) == 0 {
+		return true
+	}
+	for _, code := range codes {
+		if code == d.Code {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,72 @@
+package diag
+
+import (
+	"testing"
+
+	"github.com/giuszeppe/compiler-theory/lexer"
+)
+
+func warningAt(code string, line int) Diagnostic {
+	return NewWarning(code, Span{Start: Pos{Line: line, Column: 1}}, "warning %s", code)
+}
+
+func TestParseIgnores(t *testing.T) {
+	src := "let x:int = 1; // prl:ignore W003\n" +
+		"// prl:ignore W004, W005\n" +
+		"\n" +
+		"x = 2;\n" +
+		"x = 3; // prl:ignore\n" +
+		"x = 4; // prl:ignored W003\n"
+	lex := lexer.NewLexer()
+	ignores := ParseIgnores(lex.GenerateTokens(src))
+
+	tests := []struct {
+		code string
+		line int
+		want bool
+	}{
+		{"W003", 1, true},
+		{"W004", 1, false},
+		{"W004", 2, false},
+		{"W004", 4, true},
+		{"W005", 4, true},
+		{"W003", 4, false},
+		{"W001", 5, true},
+		{"W003", 6, false},
+	}
+	for _, tt := range tests {
+		if got := ignores.Ignored(warningAt(tt.code, tt.line)); got != tt.want {
+			t.Errorf("Ignored(%s at line %d) = %v, want %v", tt.code, tt.line, got, tt.want)
+		}
+	}
+}
+
+func TestWarningConfigApply(t *testing.T) {
+	diags := []Diagnostic{
+		warningAt("W001", 1),
+		warningAt("W002", 2),
+		warningAt("W003", 3),
+		NewError("E001", Span{Start: Pos{Line: 4, Column: 1}}, "error"),
+	}
+	ignores := Ignores{2: {"W002"}}
+
+	var c WarningConfig
+	c.SetLevel("W003", LevelAllow)
+	got := c.Apply(diags, ignores)
+	if len(got) != 2 || got[0].Code != "W001" || got[0].Severity != SeverityWarning || got[1].Code != "E001" {
+		t.Fatalf("Unexpected diagnostics %v", got)
+	}
+
+	c = WarningConfig{Error: true}
+	c.SetLevel("W001", LevelWarn)
+	got = c.Apply(diags, nil)
+	want := []Severity{SeverityWarning, SeverityError, SeverityError, SeverityError}
+	if len(got) != len(want) {
+		t.Fatalf("Unexpected diagnostics %v", got)
+	}
+	for i, d := range got {
+		if d.Severity != want[i] {
+			t.Errorf("%s: severity %s, want %s", d.Code, d.Severity, want[i])
+		}
+	}
+}
@@ -0,0 +1,177 @@
+// Package flow builds control flow graphs over the statements of a PArL
+// function body or program, for the checks in sema that need to know
+// which code runs after which.
+//
+// Unlike the graphs in package ir, these work on an AST that may still
+// hold errors and keep the statements themselves, names and all. The
+// algorithms on them are shared with package ir through package graph.
+package flow
+
+import (
+	"github.com/giuszeppe/compiler-theory/ast"
+	"github.com/giuszeppe/compiler-theory/graph"
+)
+
+// Block is a straight line of statements.
+type Block struct {
+	Index int
+	// Nodes are the statements the block runs, in order. An if, while or
+	// for contributes its Condition node to the block that tests it, and
+	// a for its VarDecl and Increment statements to the blocks around it.
+	Nodes []ast.ASTNode
+	Succs []*Block
+}
+
+// Graph is the control flow graph of a function body or program.
+type Graph struct {
+	// Entry is where control starts, and Exit where it leaves, by a
+	// return or by reaching the end. Exit runs no statements.
+	Entry, Exit *Block
+	Blocks      []*Block
+	Preds       map[*Block][]*Block
+	// Start maps every statement in the body, nested ones included, to
+	// the block control is in when the statement starts.
+	Start map[ast.ASTNode]*Block
+}
+
+// NewGraph builds the control flow graph of body.
+//
+// Loop conditions are assumed to be able to fail, except the literal
+// true, so a loop that returns on every iteration does not count as
+// returning.
+func NewGraph(body *ast.ASTBlockNode) *Graph {
+	g := &Graph{Start: make(map[ast.ASTNode]*Block)}
+	b := &builder{g: g}
+	g.Entry = b.newBlock()
+	g.Exit = &Block{Index: -1}
+	b.cur = g.Entry
+	b.stmts(body.Stmts)
+	edge(b.cur, g.Exit)
+	g.Exit.Index = len(g.Blocks)
+	g.Blocks = append(g.Blocks, g.Exit)
+	g.Preds = graph.Preds(g.Blocks, succs)
+	return g
+}
+
+func succs(blk *Block) []*Block { return blk.Succs }
+
+// Reachable returns the blocks control can reach from the entry.
+func (g *Graph) Reachable() map[*Block]bool {
+	return graph.Reachable(g.Entry, succs)
+}
+
+// Returns reports whether blk ends with a return statement.
+func (blk *Block) Returns() bool {
+	if len(blk.Nodes) == 0 {
+		return false
+	}
+	_, ok := blk.Nodes[len(blk.Nodes)-1].(*ast.ASTReturnNode)
+	return ok
+}
+
+type builder struct {
+	g   *Graph
+	cur *Block
+}
+
+func (b *builder) newBlock() *Block {
+	blk := &Block{Index: len(b.g.Blocks)}
+	b.g.Blocks = append(b.g.Blocks, blk)
+	return blk
+}
+
+func edge(from, to *Block) {
+	from.Succs = append(from.Succs, to)
+}
+
+func (b *builder) add(node ast.ASTNode) {
+	b.cur.Nodes = append(b.cur.Nodes, node)
+}
+
+func (b *builder) stmts(stmts []ast.ASTNode) {
+	for _, stmt := range stmts {
+		b.stmt(stmt)
+	}
+}
+
+// body adds a block statement, or a single statement standing in for one.
+func (b *builder) body(node ast.ASTNode) {
+	if blk, ok := node.(*ast.ASTBlockNode); ok {
+		b.g.Start[blk] = b.cur
+		b.stmts(blk.Stmts)
+		return
+	}
+	b.stmt(node)
+}
+
+func (b *builder) stmt(node ast.ASTNode) {
+	if node == nil {
+		return
+	}
+	if _, ok := node.(*ast.ASTEpsilon); ok {
+		return
+	}
+	b.g.Start[node] = b.cur
+	switch node := node.(type) {
+	case *ast.ASTBlockNode:
+		b.stmts(node.Stmts)
+	case *ast.ASTIfNode:
+		b.add(node.Condition)
+		test := b.cur
+		join := &Block{}
+
+		b.cur = b.newBlock()
+		edge(test, b.cur)
+		b.body(node.ThenBlock)
+		edge(b.cur, join)
+
+		if node.ElseBlock != nil {
+			b.cur = b.newBlock()
+			edge(test, b.cur)
+			b.body(node.ElseBlock)
+			edge(b.cur, join)
+		} else {
+			edge(test, join)
+		}
+		b.place(join)
+	case *ast.ASTWhileNode:
+		b.loop(node.Condition, node.Block, nil)
+	case *ast.ASTForNode:
+		b.stmt(node.VarDecl)
+		b.loop(node.Condition, node.Block, node.Increment)
+	case *ast.ASTReturnNode:
+		b.add(node)
+		edge(b.cur, b.g.Exit)
+		// anything after the return starts a block nothing leads to
+		b.cur = b.newBlock()
+	default:
+		b.add(node)
+	}
+}
+
+// loop adds a loop that tests cond before each run of block, followed by
+// increment if it is not nil.
+func (b *builder) loop(cond, block, increment ast.ASTNode) {
+	test := b.newBlock()
+	edge(b.cur, test)
+	test.Nodes = append(test.Nodes, cond)
+
+	b.cur = b.newBlock()
+	edge(test, b.cur)
+	b.body(block)
+	b.stmt(increment)
+	edge(b.cur, test)
+
+	b.cur = b.newBlock()
+	if lit, ok := cond.(*ast.ASTBooleanNode); !ok || !lit.Value {
+		edge(test, b.cur)
+	}
+}
+
+// place numbers blk, which was created ahead of the blocks leading to it,
+// and continues there.
+func (b *builder) place(blk *Block) {
+	blk.Index = len(b.g.Blocks)
+	b.g.Blocks = append(b.g.Blocks, blk)
+	b.cur = blk
+}
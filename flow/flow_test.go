@@ -0,0 +1,60 @@
+package flow
+
+import (
+	"testing"
+
+	"github.com/giuszeppe/compiler-theory/ast"
+	"github.com/giuszeppe/compiler-theory/parser"
+)
+
+func body(t *testing.T, src string) *ast.ASTBlockNode {
+	t.Helper()
+	p := parser.NewParser(src)
+	node, err := p.Parse(parser.NewGrammar())
+	if err != nil {
+		t.Fatalf("Failed to parse program: %v", err)
+	}
+	return &node.(*ast.ASTProgramNode).Block
+}
+
+func TestNewGraph(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		// exits is whether each statement can reach the end of the body
+		// without returning, and reachable whether each top-level
+		// statement can run.
+		exits     bool
+		reachable []bool
+	}{
+		{"straight line", `let x:int = 1; __print x;`, true, []bool{true, true}},
+		{"return", `return 1; __print 2;`, false, []bool{true, false}},
+		{"if without else", `if (true) { return 1; } __print 2;`, true, []bool{true, true}},
+		{"if and else", `if (true) { return 1; } else { return 2; } __print 3;`, false, []bool{true, false}},
+		{"while", `while (false) { return 1; } __print 2;`, true, []bool{true, true}},
+		{"while true", `while (true) { __print 1; } __print 2;`, false, []bool{true, false}},
+		{"for", `for (let i:int = 0; i < 2; i = i + 1) { return i; }`, true, []bool{true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := body(t, tt.src)
+			g := NewGraph(b)
+			reachable := g.Reachable()
+
+			exits := false
+			for _, pred := range g.Preds[g.Exit] {
+				if reachable[pred] && !pred.Returns() {
+					exits = true
+				}
+			}
+			if exits != tt.exits {
+				t.Errorf("Expected falls off the end to be %v", tt.exits)
+			}
+			for i, stmt := range b.Stmts {
+				if got := reachable[g.Start[stmt]]; got != tt.reachable[i] {
+					t.Errorf("Expected statement %d reachable to be %v", i, tt.reachable[i])
+				}
+			}
+		})
+	}
+}
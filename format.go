@@ -0,0 +1,34 @@
+package compiler
+
+import (
+	"strings"
+
+	"github.com/giuszeppe/compiler-theory/ast"
+	"github.com/giuszeppe/compiler-theory/diag"
+	"github.com/giuszeppe/compiler-theory/lexer"
+	"github.com/giuszeppe/compiler-theory/parser"
+)
+
+// Format returns src in canonical form, keeping its comments. Formatting is
+// idempotent: formatting the result again gives the same text. A source
+// with lexical or syntax errors is not formatted; its errors are returned
+// instead.
+func Format(src string) (string, []diag.Diagnostic) {
+	p := parser.NewParser(src)
+	node, err := p.Parse(parser.NewGrammar())
+	if err != nil {
+		return "", p.Errors
+	}
+
+	var comments []lexer.Token
+	for _, tok := range p.Tokens {
+		if tok.Type == lexer.CommentSingleLine || tok.Type == lexer.CommentMultiLine {
+			comments = append(comments, tok)
+		}
+	}
+	var out strings.Builder
+	formatter := ast.NewFormatVisitor(comments)
+	formatter.Out = &out
+	node.Accept(formatter)
+	return out.String(), nil
+}
@@ -0,0 +1,116 @@
+package compiler
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/giuszeppe/compiler-theory/parser"
+)
+
+func TestFormat(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want string
+	}{
+		{
+			"indentation and spacing",
+			"fun a()->int{\nif(1<2){\nreturn 0;}\n  return 1;}\nlet b:int=a();",
+			"fun a() -> int {\n    if (1 < 2) {\n        return 0;\n    }\n    return 1;\n}\nlet b:int = a();\n",
+		},
+		{
+			"redundant parentheses",
+			"let x:int = ((1) + (2 * 3));",
+			"let x:int = 1 + 2 * 3;\n",
+		},
+		{
+			"needed parentheses",
+			"let x:int = (1 + 2) * (3 - (4 - 5));\nlet b:bool = not (x < 2) and (x > 1);",
+			"let x:int = (1 + 2) * (3 - (4 - 5));\nlet b:bool = not (x < 2) and (x > 1);\n",
+		},
+		{
+			"casts",
+			"let f:float = (x + y) / 2 as float;\nlet g:float = (x as float) + 1.5;",
+			"let f:float = (x + y) / 2 as float;\nlet g:float = (x as float) + 1.5;\n",
+		},
+		{
+			"builtins and arrays",
+			"let a:int[] = [1,2,3];\n__write_box 1,2,3,4,#ff0000;\nlet c:colour = __read(__width-1, a[0]);\n__print __random_int(10);",
+			"let a:int[] = [1, 2, 3];\n__write_box 1, 2, 3, 4, #ff0000;\nlet c:colour = __read(__width - 1, a[0]);\n__print __random_int(10);\n",
+		},
+		{
+			"for and else",
+			"for(let i:int=0;i<3;i=i+1){__print i;}\nif (true) {} else { { __print 1; } }",
+			"for (let i:int = 0; i < 3; i = i + 1) {\n    __print i;\n}\nif (true) {} else {\n    {\n        __print 1;\n    }\n}\n",
+		},
+		{
+			"blank lines collapse",
+			"__print 1;\n\n\n\n__print 2;\n__print 3;",
+			"__print 1;\n\n__print 2;\n__print 3;\n",
+		},
+		{
+			"comments",
+			"// leading\n__print 1; // trailing\n\n/* block\n   comment */\nfun f() -> int {\n  // inside\n  return 1 /* inline */ ;\n  // last\n}\n// end\n",
+			"// leading\n__print 1; // trailing\n\n/* block\n   comment */\nfun f() -> int {\n    // inside\n    return 1; /* inline */\n    // last\n}\n// end\n",
+		},
+		{
+			"comments inside a statement",
+			"let xs:int[] = [23, // a\n 54, /*b*/ 21 // c\n];\nlet ys:int[4] = [1, /* d */\n2];\n__print 1;",
+			"let xs:int[] = [23, 54, 21]; // a\n/*b*/\n// c\nlet ys:int[4] = [1, 2]; /* d */\n__print 1;\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, diags := Format(tt.src)
+			if diags != nil {
+				t.Fatalf("Unexpected diagnostics %v", diags)
+			}
+			if got != tt.want {
+				t.Fatalf("Expected:\n%s\ngot:\n%s", tt.want, got)
+			}
+			if again, _ := Format(got); again != got {
+				t.Fatalf("Formatting is not idempotent:\n%s\nthen:\n%s", got, again)
+			}
+		})
+	}
+}
+
+func TestFormatSyntaxError(t *testing.T) {
+	out, diags := Format("let x:int = ;")
+	if out != "" || len(diags) != 1 || diags[0].Code != parser.CodeNoRule {
+		t.Fatalf("Expected a syntax error and no output, got %q, %v", out, diags)
+	}
+}
+
+// TestFormatExamples checks that formatting the example programs is
+// idempotent and does not change what they compile to.
+func TestFormatExamples(t *testing.T) {
+	paths, err := filepath.Glob("examples/*.prl")
+	if err != nil || len(paths) == 0 {
+		t.Fatalf("No examples found: %v", err)
+	}
+	for _, path := range paths {
+		t.Run(filepath.Base(path), func(t *testing.T) {
+			src, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			once, diags := Format(string(src))
+			if diags != nil {
+				t.Skipf("Example does not parse: %v", diags)
+			}
+			twice, _ := Format(once)
+			if once != twice {
+				t.Fatalf("Formatting is not idempotent:\n%s\nthen:\n%s", once, twice)
+			}
+
+			before, beforeDiags := Compile(string(src), Options{})
+			after, afterDiags := Compile(once, Options{})
+			if !reflect.DeepEqual(before.Instructions, after.Instructions) || len(beforeDiags) != len(afterDiags) {
+				t.Fatalf("Formatting changed the program:\n%s", once)
+			}
+		})
+	}
+}
@@ -0,0 +1,3 @@
+module github.com/giuszeppe/compiler-theory
+
+go 1.22
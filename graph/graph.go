@@ -0,0 +1,35 @@
+// Package graph holds the algorithms shared by the control flow graphs of
+// packages flow and ir. A graph is given by its nodes or its entry and a
+// function returning the successors of a node, so that each package keeps
+// the blocks that suit it: statements of a checked AST in flow, and
+// instructions in ir.
+package graph
+
+// Reachable returns the nodes control can reach from entry, entry included.
+func Reachable[N comparable](entry N, succs func(N) []N) map[N]bool {
+	seen := map[N]bool{entry: true}
+	work := []N{entry}
+	for len(work) > 0 {
+		n := work[len(work)-1]
+		work = work[:len(work)-1]
+		for _, succ := range succs(n) {
+			if !seen[succ] {
+				seen[succ] = true
+				work = append(work, succ)
+			}
+		}
+	}
+	return seen
+}
+
+// Preds returns the predecessors of each of nodes that has any, in the
+// order of nodes.
+func Preds[N comparable](nodes []N, succs func(N) []N) map[N][]N {
+	preds := make(map[N][]N)
+	for _, n := range nodes {
+		for _, succ := range succs(n) {
+			preds[succ] = append(preds[succ], n)
+		}
+	}
+	return preds
+}
@@ -0,0 +1,19 @@
+package graph
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestReachableAndPreds(t *testing.T) {
+	// 0 -> 1 -> 2 -> 1, with 3 -> 2 unreachable
+	edges := map[int][]int{0: {1}, 1: {2}, 2: {1}, 3: {2}}
+	succs := func(n int) []int { return edges[n] }
+
+	if got, want := Reachable(0, succs), map[int]bool{0: true, 1: true, 2: true}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %v reachable, got %v", want, got)
+	}
+	if got, want := Preds([]int{0, 1, 2, 3}, succs), map[int][]int{1: {0, 2}, 2: {1, 3}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected predecessors %v, got %v", want, got)
+	}
+}
@@ -0,0 +1,496 @@
+// Package interp runs checked PArL programs by walking their AST, without
+// compiling them.
+//
+// It is the reference for what a program means: its scopes, calls and
+// control flow are the source's own, not the frames and jumps codegen
+// turns them into. Values follow the PArIR numeric model, as in the VM and
+// the web simulator: every int, float, bool and colour is a number, so the
+// output of the two can be compared. Unlike PArIR, which has no integer
+// division and no conversions, the interpreter truncates the quotient of
+// two ints and converts the value of a cast as the type checker types it,
+// so programs that divide ints or cast floats print different results on
+// the VM. It also evaluates operands from left to right, where compiled
+// code evaluates them from right to left, which shows when a call assigns
+// a variable that the rest of the expression reads.
+package interp
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/giuszeppe/compiler-theory/ast"
+	"github.com/giuszeppe/compiler-theory/parir"
+	"github.com/giuszeppe/compiler-theory/types"
+	"github.com/giuszeppe/compiler-theory/vm"
+)
+
+// value is a PArL value: a number or, if elems is not nil, an array.
+type value struct {
+	num   vm.Value
+	elems []vm.Value
+}
+
+func number(n vm.Value) value { return value{num: n} }
+
+func boolean(b bool) value {
+	if b {
+		return number(1)
+	}
+	return number(0)
+}
+
+func (val value) truthy() bool { return val.num != 0 }
+
+// copy returns val with its own copy of the elements of an array, since
+// arrays are assigned and passed by value.
+func (val value) copy() value {
+	if val.elems != nil {
+		val.elems = append([]vm.Value(nil), val.elems...)
+	}
+	return val
+}
+
+func (val value) String() string {
+	if val.elems == nil {
+		return val.num.String()
+	}
+	items := make([]string, len(val.elems))
+	for i, e := range val.elems {
+		items[i] = e.String()
+	}
+	return "[" + strings.Join(items, ", ") + "]"
+}
+
+// env is a scope: the variables and functions declared in a block.
+type env struct {
+	vars   map[string]*value
+	funcs  map[string]*function
+	parent *env
+}
+
+func newEnv(parent *env) *env {
+	return &env{vars: make(map[string]*value), funcs: make(map[string]*function), parent: parent}
+}
+
+func (e *env) lookup(name string) *value {
+	for ; e != nil; e = e.parent {
+		if val, ok := e.vars[name]; ok {
+			return val
+		}
+	}
+	panic(runtimeError{fmt.Sprintf("undeclared variable %s", name)})
+}
+
+func (e *env) function(name string) *function {
+	for ; e != nil; e = e.parent {
+		if fn, ok := e.funcs[name]; ok {
+			return fn
+		}
+	}
+	panic(runtimeError{fmt.Sprintf("undeclared function %s", name)})
+}
+
+// function is a declared function and the scope it was declared in, which
+// its body sees.
+type function struct {
+	decl *ast.ASTFuncDeclNode
+	env  *env
+}
+
+// runtimeError is raised with panic when the program cannot go on, and
+// turned back into an error by Run.
+type runtimeError struct {
+	msg string
+}
+
+// InterpreterVisitor runs a program that has passed semantic analysis.
+// Expressions leave their value in the visitor for the node that visits
+// them.
+type InterpreterVisitor struct {
+	Pad   *vm.Pad
+	Out   io.Writer
+	Rand  *rand.Rand
+	Sleep func(time.Duration) // nil turns __delay into a no-op
+	// MaxSteps bounds the number of statements run; 0 means no limit.
+	MaxSteps int
+	Steps    int
+
+	env   *env
+	value value
+	// returning is set by a return statement until the call it returns
+	// from has its value.
+	returning bool
+}
+
+func NewInterpreterVisitor() *InterpreterVisitor {
+	return &InterpreterVisitor{
+		Pad:  vm.NewPad(vm.DefaultPadWidth, vm.DefaultPadHeight),
+		Out:  os.Stdout,
+		Rand: rand.New(rand.NewSource(time.Now().UnixNano())),
+		env:  newEnv(nil),
+	}
+}
+
+// Run runs the program rooted at node and returns the first runtime error,
+// such as a division by zero.
+func (v *InterpreterVisitor) Run(node ast.ASTNode) (err error) {
+	defer v.catch(&err)
+	node.Accept(v)
+	return nil
+}
+
+// Exec runs stmts in the global scope, where what they declare stays for
+// later calls, as a REPL needs. A return among them ends the statements.
+func (v *InterpreterVisitor) Exec(stmts []ast.ASTNode) (err error) {
+	defer v.catch(&err)
+	for _, stmt := range stmts {
+		if v.returning {
+			break
+		}
+		v.step()
+		stmt.Accept(v)
+	}
+	v.returning = false
+	return nil
+}
+
+// Eval returns the value of the expression node in the global scope, as
+// __print would write it.
+func (v *InterpreterVisitor) Eval(node ast.ASTNode) (val string, err error) {
+	defer v.catch(&err)
+	return v.eval(node).String(), nil
+}
+
+// catch turns a runtime error raised while running into *err. It must be
+// deferred.
+func (v *InterpreterVisitor) catch(err *error) {
+	if r := recover(); r != nil {
+		rerr, ok := r.(runtimeError)
+		if !ok {
+			panic(r)
+		}
+		v.returning = false
+		*err = fmt.Errorf("runtime error: %s", rerr.msg)
+	}
+}
+
+// eval returns the value of the expression node.
+func (v *InterpreterVisitor) eval(node ast.ASTNode) value {
+	node.Accept(v)
+	return v.value
+}
+
+// exec runs the statements of a block in a scope of their own, stopping at
+// a return.
+func (v *InterpreterVisitor) exec(stmts []ast.ASTNode) {
+	outer := v.env
+	v.env = newEnv(outer)
+	defer func() { v.env = outer }()
+	for _, stmt := range stmts {
+		if v.returning {
+			return
+		}
+		v.step()
+		stmt.Accept(v)
+	}
+}
+
+func (v *InterpreterVisitor) step() {
+	v.Steps++
+	if v.MaxSteps > 0 && v.Steps > v.MaxSteps {
+		panic(runtimeError{fmt.Sprintf("step limit of %d exceeded", v.MaxSteps)})
+	}
+}
+
+// body runs a block statement, or a single statement standing in for one.
+func (v *InterpreterVisitor) body(node ast.ASTNode) {
+	if blk, ok := node.(*ast.ASTBlockNode); ok {
+		v.exec(blk.Stmts)
+		return
+	}
+	v.exec([]ast.ASTNode{node})
+}
+
+// ===== Statements =====
+
+func (v *InterpreterVisitor) VisitProgramNode(node *ast.ASTProgramNode) {
+	v.exec(node.Block.Stmts)
+}
+
+func (v *InterpreterVisitor) VisitBlockNode(node *ast.ASTBlockNode) {
+	v.exec(node.Stmts)
+}
+
+func (v *InterpreterVisitor) VisitVarDeclNode(node *ast.ASTVarDeclNode) {
+	val := v.eval(node.Expression).copy()
+	if arr, ok := node.Type.(types.Array); ok && arr.Len > len(val.elems) {
+		// a literal shorter than the declared size is padded with zeros
+		val.elems = append(val.elems, make([]vm.Value, arr.Len-len(val.elems))...)
+	}
+	v.env.vars[node.Token.Lexeme] = &val
+}
+
+func (v *InterpreterVisitor) VisitAssignmentNode(node *ast.ASTAssignmentNode) {
+	val := v.eval(node.Expr).copy()
+	target := v.env.lookup(node.Id.Token.Lexeme)
+	if hasOffset(&node.Id) {
+		*v.element(target, node.Id.Offset) = val.num
+		return
+	}
+	*target = val
+}
+
+// element returns the element of arr at the index offset evaluates to.
+func (v *InterpreterVisitor) element(arr *value, offset ast.ASTNode) *vm.Value {
+	i := int(v.eval(offset).num)
+	if i < 0 || i >= len(arr.elems) {
+		panic(runtimeError{fmt.Sprintf("index %d out of range for array of %d", i, len(arr.elems))})
+	}
+	return &arr.elems[i]
+}
+
+func hasOffset(node *ast.ASTVariableNode) bool {
+	_, isEpsilon := node.Offset.(*ast.ASTEpsilon)
+	return node.Offset != nil && !isEpsilon
+}
+
+func (v *InterpreterVisitor) VisitPrintNode(node *ast.ASTPrintNode) {
+	fmt.Fprintln(v.Out, v.eval(&node.Expr))
+}
+
+func (v *InterpreterVisitor) VisitIfNode(node *ast.ASTIfNode) {
+	if v.eval(node.Condition).truthy() {
+		v.body(node.ThenBlock)
+	} else if node.ElseBlock != nil {
+		v.body(node.ElseBlock)
+	}
+}
+
+func (v *InterpreterVisitor) VisitWhileNode(node *ast.ASTWhileNode) {
+	for !v.returning && v.eval(node.Condition).truthy() {
+		v.step()
+		v.body(node.Block)
+	}
+}
+
+func (v *InterpreterVisitor) VisitForNode(node *ast.ASTForNode) {
+	outer := v.env
+	v.env = newEnv(outer)
+	defer func() { v.env = outer }()
+	if node.VarDecl != nil {
+		node.VarDecl.Accept(v)
+	}
+	for !v.returning && v.eval(node.Condition).truthy() {
+		v.step()
+		v.body(node.Block)
+		if !v.returning && node.Increment != nil {
+			node.Increment.Accept(v)
+		}
+	}
+}
+
+func (v *InterpreterVisitor) VisitFuncDeclNode(node *ast.ASTFuncDeclNode) {
+	v.env.funcs[node.Token.Lexeme] = &function{decl: node, env: v.env}
+}
+
+func (v *InterpreterVisitor) VisitReturnNode(node *ast.ASTReturnNode) {
+	v.value = v.eval(node.Expr)
+	v.returning = true
+}
+
+// ===== Expressions =====
+
+func (v *InterpreterVisitor) VisitExpressionNode(node *ast.ASTExpressionNode) {
+	node.Expr.Accept(v)
+}
+
+func (v *InterpreterVisitor) VisitIntegerNode(node *ast.ASTIntegerNode) {
+	v.value = number(vm.Value(node.Value))
+}
+
+func (v *InterpreterVisitor) VisitFloatNode(node *ast.ASTFloatNode) {
+	v.value = number(vm.Value(node.Value))
+}
+
+func (v *InterpreterVisitor) VisitBooleanNode(node *ast.ASTBooleanNode) {
+	v.value = boolean(node.Value)
+}
+
+func (v *InterpreterVisitor) VisitColorNode(node *ast.ASTColorNode) {
+	// the lexer only accepts well-formed colour literals
+	rgb, _ := parir.ParseOperand(node.Value)
+	v.value = number(vm.Value(rgb.Int))
+}
+
+func (v *InterpreterVisitor) VisitVariableNode(node *ast.ASTVariableNode) {
+	val := v.env.lookup(node.Token.Lexeme)
+	if hasOffset(node) {
+		v.value = number(*v.element(val, node.Offset))
+		return
+	}
+	v.value = *val
+}
+
+func (v *InterpreterVisitor) VisitArrayNode(node *ast.ASTArrayNode) {
+	elems := make([]vm.Value, len(node.Items))
+	for i, item := range node.Items {
+		elems[i] = v.eval(item).num
+	}
+	v.value = value{elems: elems}
+}
+
+func (v *InterpreterVisitor) VisitBinaryOpNode(node *ast.ASTBinaryOpNode) {
+	x := v.eval(node.Left).num
+	y := v.eval(node.Right).num
+	switch node.Operator {
+	case "+":
+		v.value = number(x + y)
+	case "-":
+		v.value = number(x - y)
+	case "*":
+		v.value = number(x * y)
+	case "/":
+		if y == 0 {
+			panic(runtimeError{"division by zero"})
+		}
+		if integral(node.ExprType()) {
+			v.value = number(vm.Value(math.Trunc(float64(x / y))))
+		} else {
+			v.value = number(x / y)
+		}
+	case "%":
+		if y == 0 {
+			panic(runtimeError{"division by zero"})
+		}
+		v.value = number(vm.Value(math.Mod(float64(x), float64(y))))
+	case "and":
+		v.value = boolean(x != 0 && y != 0)
+	case "or":
+		v.value = boolean(x != 0 || y != 0)
+	case "==":
+		v.value = boolean(x == y)
+	case "!=":
+		v.value = boolean(x != y)
+	case "<":
+		v.value = boolean(x < y)
+	case "<=":
+		v.value = boolean(x <= y)
+	case ">":
+		v.value = boolean(x > y)
+	case ">=":
+		v.value = boolean(x >= y)
+	default:
+		panic(runtimeError{fmt.Sprintf("unknown operator %s", node.Operator)})
+	}
+}
+
+func (v *InterpreterVisitor) VisitUnaryOpNode(node *ast.ASTUnaryOpNode) {
+	x := v.eval(node.Operand)
+	if node.Operator == "-" {
+		v.value = number(-x.num)
+		return
+	}
+	v.value = boolean(!x.truthy())
+}
+
+// VisitTypeCastNode converts the value to the type cast to.
+func (v *InterpreterVisitor) VisitTypeCastNode(node *ast.ASTTypeCastNode) {
+	v.value = number(convert(v.eval(node.Expr).num, node.Type))
+}
+
+// integral reports whether the values of typ are whole numbers.
+func integral(typ types.Type) bool {
+	return typ == types.Int || typ == types.Colour
+}
+
+// convert converts x to typ: a whole number is truncated towards zero, and
+// a bool is 1 for anything but zero.
+func convert(x vm.Value, typ types.Type) vm.Value {
+	switch {
+	case integral(typ):
+		return vm.Value(math.Trunc(float64(x)))
+	case typ == types.Bool && x != 0:
+		return 1
+	}
+	return x
+}
+
+func (v *InterpreterVisitor) VisitFuncCallNode(node *ast.ASTFuncCallNode) {
+	fn := v.env.function(node.Name.Lexeme)
+	var args []value
+	for _, arg := range node.Params.(*ast.ASTActualParamsNode).Params {
+		args = append(args, v.eval(arg).copy())
+	}
+
+	outer := v.env
+	v.env = newEnv(fn.env)
+	defer func() { v.env = outer }()
+	for i, param := range fn.decl.Params.(*ast.ASTFormalParamsNode).Params {
+		v.env.vars[param.(*ast.ASTVarDeclNode).Token.Lexeme] = &args[i]
+	}
+	v.exec(fn.decl.Block.(*ast.ASTBlockNode).Stmts)
+	if !v.returning {
+		panic(runtimeError{fmt.Sprintf("function %s ended without returning", node.Name.Lexeme)})
+	}
+	v.returning = false
+}
+
+func (v *InterpreterVisitor) VisitActualParamsNode(node *ast.ASTActualParamsNode) {}
+
+func (v *InterpreterVisitor) VisitActualParamNode(node *ast.ASTActualParamNode) {
+	node.Value.Accept(v)
+}
+
+func (v *InterpreterVisitor) VisitBuiltinFuncNode(node *ast.ASTBuiltinFuncNode) {
+	args := make([]value, len(node.Args))
+	for i, arg := range node.Args {
+		args[i] = v.eval(arg)
+	}
+	num := func(i int) int { return int(args[i].num) }
+	v.value = value{}
+	switch node.Token.Lexeme {
+	case "__print":
+		fmt.Fprintln(v.Out, args[0])
+	case "__delay":
+		if v.Sleep != nil && num(0) > 0 {
+			v.Sleep(time.Duration(num(0)) * time.Millisecond)
+		}
+	case "__width":
+		v.value = number(vm.Value(v.Pad.Width))
+	case "__height":
+		v.value = number(vm.Value(v.Pad.Height))
+	case "__clear":
+		v.Pad.Clear(num(0))
+	case "__write":
+		v.Pad.Write(num(0), num(1), num(2))
+	case "__write_box":
+		v.Pad.WriteBox(num(0), num(1), num(2), num(3), num(4))
+	case "__read":
+		v.value = number(vm.Value(v.Pad.Read(num(0), num(1))))
+	case "__random_int":
+		if max := num(0); max > 0 {
+			v.value = number(vm.Value(v.Rand.Intn(max)))
+		} else {
+			v.value = number(0)
+		}
+	default:
+		panic(runtimeError{fmt.Sprintf("unknown builtin %s", node.Token.Lexeme)})
+	}
+}
+
+// ===== Nodes with nothing to run =====
+
+func (v *InterpreterVisitor) VisitSimpleExpressionNode(node *ast.ASTSimpleExpression) {}
+func (v *InterpreterVisitor) VisitEpsilon(node *ast.ASTEpsilon)                       {}
+func (v *InterpreterVisitor) VisitTypeNode(node *ast.ASTTypeNode)                     {}
+func (v *InterpreterVisitor) VisitFormalParamsNode(node *ast.ASTFormalParamsNode)     {}
+func (v *InterpreterVisitor) VisitFormalParamNode(node *ast.ASTFormalParamNode)       {}
+
+func (v *InterpreterVisitor) VisitErrorNode(node *ast.ASTErrorNode) {
+	panic(runtimeError{"the program has syntax errors"})
+}
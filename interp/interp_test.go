@@ -0,0 +1,272 @@
+package interp
+
+import (
+	"bytes"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/giuszeppe/compiler-theory/ast"
+	"github.com/giuszeppe/compiler-theory/codegen"
+	"github.com/giuszeppe/compiler-theory/diag"
+	"github.com/giuszeppe/compiler-theory/ir"
+	"github.com/giuszeppe/compiler-theory/lexer"
+	"github.com/giuszeppe/compiler-theory/parser"
+	"github.com/giuszeppe/compiler-theory/sema"
+	"github.com/giuszeppe/compiler-theory/vm"
+)
+
+func check(t *testing.T, program string) ast.ASTNode {
+	t.Helper()
+	p := parser.NewParser(program)
+	rootAST, err := p.Parse(parser.NewGrammar())
+	if err != nil {
+		t.Fatalf("Failed to parse program: %v", err)
+	}
+	if diags := sema.NewSemanticVisitor().Analyze(rootAST); diag.HasErrors(diags) {
+		t.Fatalf("Semantic errors: %v", diag.Diagnostics(diags))
+	}
+	return rootAST
+}
+
+func interpret(t *testing.T, rootAST ast.ASTNode) (*InterpreterVisitor, string, error) {
+	t.Helper()
+	var out bytes.Buffer
+	in := NewInterpreterVisitor()
+	in.Out = &out
+	in.Rand = rand.New(rand.NewSource(1))
+	in.MaxSteps = 100000
+	err := in.Run(rootAST)
+	return in, out.String(), err
+}
+
+func expectOutput(t *testing.T, program string, expected ...string) {
+	t.Helper()
+	_, out, err := interpret(t, check(t, program))
+	if err != nil {
+		t.Fatalf("Failed to run program: %v", err)
+	}
+	if want := strings.Join(expected, "\n") + "\n"; out != want {
+		t.Errorf("Expected output %q, got %q", want, out)
+	}
+}
+
+func TestInterpreterArithmetic(t *testing.T) {
+	expectOutput(t, `let x:int = 7; let y:int = x * 2 - 3; __print y; __print -x; __print x < y; __print x / 2;`,
+		"11", "-7", "1", "3")
+}
+
+func TestInterpreterCasts(t *testing.T) {
+	expectOutput(t, `
+let f:float = 3.9;
+__print f as int;
+__print -f as int;
+__print (7 as float) / 2.0;
+__print (-7) / 2;
+__print 2.5 as bool;
+__print 0.0 as bool;`,
+		"3", "-3", "3.5", "-3", "1", "0")
+}
+
+func TestInterpreterScopes(t *testing.T) {
+	expectOutput(t, `let x:int = 1; { let y:int = 2; x = y; } { let y:int = 3; __print y; } __print x;`, "3", "2")
+}
+
+func TestInterpreterControlFlow(t *testing.T) {
+	expectOutput(t, `
+let n:int = 0;
+while (n < 3) { n = n + 1; }
+for (let i:int = 0; i < 2; i = i + 1) { __print i; }
+if (n == 3) { __print true; } else { __print false; }`,
+		"0", "1", "1")
+}
+
+func TestInterpreterRecursion(t *testing.T) {
+	expectOutput(t, `
+fun fact(n:int) -> int {
+	if (n <= 1) { return 1; }
+	return n * fact(n - 1);
+}
+fun first(n:int) -> int {
+	while (true) {
+		if (n > 2) { return n; }
+		n = n + 1;
+	}
+	return 0;
+}
+__print fact(5);
+__print first(0);`,
+		"120", "3")
+}
+
+func TestInterpreterArraysAreCopied(t *testing.T) {
+	expectOutput(t, `
+fun zero(a:int[3]) -> int { a[0] = 0; return a[0]; }
+let xs:int[3] = [1, 2, 3];
+__print zero(xs);
+xs[1] = 5;
+__print xs;`,
+		"0", "[1, 5, 3]")
+}
+
+func TestInterpreterPad(t *testing.T) {
+	in, _, err := interpret(t, check(t, `__write_box 1, 1, 2, 2, #ff0000; __write 0, 0, #00ff00; __print __read(1, 2);`))
+	if err != nil {
+		t.Fatalf("Failed to run program: %v", err)
+	}
+	if got := in.Pad.Read(0, 0); got != 0x00ff00 {
+		t.Errorf("Expected #00ff00 at (0, 0), got %#06x", got)
+	}
+	if got := in.Pad.Read(2, 2); got != 0xff0000 {
+		t.Errorf("Expected #ff0000 at (2, 2), got %#06x", got)
+	}
+}
+
+func TestInterpreterRuntimeErrors(t *testing.T) {
+	tests := []struct {
+		program string
+		want    string
+	}{
+		{`let x:int = 0; __print 1 / x;`, "division by zero"},
+		{`let xs:int[2] = [1, 2]; let i:int = 2; __print xs[i];`, "index 2 out of range"},
+		{`while (true) { }`, "step limit"},
+	}
+	for _, tc := range tests {
+		_, _, err := interpret(t, check(t, tc.program))
+		if err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("%s: expected an error containing %q, got %v", tc.program, tc.want, err)
+		}
+	}
+}
+
+// TestInterpreterMatchesVM runs programs both with the interpreter and as
+// generated PArIR on the VM, which must agree on the output and the pad.
+func TestInterpreterMatchesVM(t *testing.T) {
+	programs := map[string]string{
+		"loops": `
+let total:int = 0;
+for (let i:int = 0; i < 10; i = i + 1) {
+	if (i < 5) { total = total + i; } else { total = total - 1; }
+}
+__print total;
+__print (total as float) / 4.0;`,
+		"random": `
+for (let i:int = 0; i < 5; i = i + 1) {
+	let x:int = __random_int(__width);
+	let y:int = __random_int(__height);
+	__write x, y, #123456;
+	__print x;
+}`,
+		"recursion": `
+let base:int = 100;
+fun f(n:int) -> int {
+	if (n <= 0) { return base; }
+	return f(n - 1) + 1;
+}
+__print f(2);`,
+		"nested calls": `
+let scale:int = 3;
+let xs:int[3] = [1, 2, 3];
+fun g(n:int) -> int { return n * scale + xs[1]; }
+fun f(n:int) -> int { let m:int = n + 1; return g(m); }
+if (scale > 0) { __print f(1); } else { __print 0; }
+let i:int = 0;
+while (i < 2) { __print g(i); i = i + 1; }
+{ let y:int = f(i); __print y; }`,
+		"outer assignment": `
+let calls:int = 0;
+let seen:int[3] = [0, 0, 0];
+fun visit(n:int) -> int {
+	calls = calls + 1;
+	if (n < 0) { return 0; }
+	seen[n] = seen[n] + 1;
+	return visit(n - 1);
+}
+fun twice() -> int { let a:int = visit(1); return visit(2); }
+for (let i:int = 0; i < 2; i = i + 1) { let r:int = twice(); __print calls; }
+__print seen;`,
+	}
+	for _, name := range []string{"array_example", "color", "full_example", "nested"} {
+		src, err := os.ReadFile(filepath.Join("..", "examples", name+".prl"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		programs[name] = string(src)
+	}
+
+	// PArIR has no integer division or conversions, and compiled code
+	// evaluates operands from right to left, so these programs print what
+	// is listed for each
+	differences := map[string]struct{ program, interp, vm string }{
+		"int division": {`let x:int = 7; __print x / 2;`, "3\n", "3.5\n"},
+		"casts":        {`let f:float = 3.9; __print f as int;`, "3\n", "3.9\n"},
+		"evaluation order": {`
+let x:int = 1;
+fun bump() -> int { x = x + 10; return 0; }
+__print x + bump();`, "1\n", "11\n"},
+	}
+	for name, d := range differences {
+		programs[name] = d.program
+	}
+
+	for name, program := range programs {
+		t.Run(name, func(t *testing.T) {
+			rootAST := check(t, program)
+			in, got, err := interpret(t, rootAST)
+			if err != nil {
+				t.Fatalf("Interpreter failed: %v", err)
+			}
+
+			machine, err := vm.NewVM(codegen.Generate(ir.Build(rootAST)))
+			if err != nil {
+				t.Fatalf("Failed to load program: %v", err)
+			}
+			var want bytes.Buffer
+			machine.Out = &want
+			machine.Rand = rand.New(rand.NewSource(1))
+			machine.MaxSteps = 1000000
+			if err := machine.Run(); err != nil {
+				t.Fatalf("VM failed: %v", err)
+			}
+
+			if d, ok := differences[name]; ok {
+				if got != d.interp || want.String() != d.vm {
+					t.Errorf("Expected the known difference %q and %q, got\ninterpreter: %q\nvm:          %q", d.interp, d.vm, got, want.String())
+				}
+			} else if got != want.String() {
+				t.Errorf("Output differs from the VM:\ninterpreter: %q\nvm:          %q", got, want.String())
+			}
+			var gotPad, wantPad strings.Builder
+			in.Pad.WritePPM(&gotPad)
+			machine.Pad.WritePPM(&wantPad)
+			if gotPad.String() != wantPad.String() {
+				t.Errorf("Pad differs from the VM")
+			}
+		})
+	}
+}
+
+func TestInterpreterExecKeepsGlobals(t *testing.T) {
+	var out bytes.Buffer
+	in := NewInterpreterVisitor()
+	in.Out = &out
+	for _, program := range []string{`let x:int = 2;`, `fun double(n:int) -> int { return n * x; }`, `x = 3; __print double(2);`} {
+		p := parser.NewParser(program)
+		rootAST, err := p.Parse(parser.NewGrammar())
+		if err != nil {
+			t.Fatalf("Failed to parse program: %v", err)
+		}
+		if err := in.Exec(rootAST.(*ast.ASTProgramNode).Block.Stmts); err != nil {
+			t.Fatalf("%s: %v", program, err)
+		}
+	}
+	if out.String() != "6\n" {
+		t.Errorf("Expected output %q, got %q", "6\n", out.String())
+	}
+	val, err := in.Eval(&ast.ASTVariableNode{Token: lexer.NewToken(lexer.Identifier, "x")})
+	if err != nil || val != "3" {
+		t.Errorf("Expected x to be 3, got %q (%v)", val, err)
+	}
+}
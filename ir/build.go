@@ -0,0 +1,483 @@
+package ir
+
+import (
+	"fmt"
+
+	"github.com/giuszeppe/compiler-theory/ast"
+	"github.com/giuszeppe/compiler-theory/parir"
+	"github.com/giuszeppe/compiler-theory/types"
+)
+
+// Build lowers the program rooted at node, which must have passed semantic
+// analysis: the type of every temporary comes from the types it recorded
+// on the expressions.
+func Build(node ast.ASTNode) *Program {
+	b := &builder{prog: &Program{}}
+	program := node.(*ast.ASTProgramNode)
+
+	b.startFunc("main", nil)
+	b.openFrame()
+	b.stmts(program.Block.Stmts)
+	b.closeFrame()
+	b.endFrame()
+	b.terminate(&Halt{})
+	b.endFunc()
+	return b.prog
+}
+
+// builder holds the state of Build.
+type builder struct {
+	prog   *Program
+	blocks int // blocks named so far, to keep names unique
+
+	// The function being built, its current block and the number of
+	// temporaries it has defined. block is nil after a terminator until
+	// the next block starts.
+	fn    *Func
+	block *Block
+	temps int
+
+	// frames are the frames enclosing the current code in the source,
+	// innermost last: those of the enclosing functions and blocks, the
+	// current function's frame at base, and the frames opened inside it.
+	// A frame's index is the depth sema gave the variables declared in
+	// it. Only the frames from base up are open when the code runs; decl
+	// is the current function, whose captures stand in for the rest.
+	frames []*frame
+	base   int
+	decl   *ast.ASTFuncDeclNode
+}
+
+type frame struct {
+	size int
+	open *OpenFrame // nil for a function's frame, which the call opens
+}
+
+// ===== Functions, blocks and frames =====
+
+// startFunc starts building a function with a fresh frame.
+func (b *builder) startFunc(name string, ret types.Type) *Func {
+	fn := &Func{Name: name, Return: ret}
+	b.prog.Funcs = append(b.prog.Funcs, fn)
+	b.fn, b.temps = fn, 0
+	b.startBlock(b.newBlock("entry"))
+	return fn
+}
+
+// endFunc finishes the current function, ending its last block if control
+// can reach it.
+func (b *builder) endFunc() {
+	if b.block != nil {
+		b.terminate(&Unreachable{})
+	}
+}
+
+func (b *builder) newBlock(hint string) *Block {
+	b.blocks++
+	return &Block{Name: fmt.Sprintf("%s%d", hint, b.blocks)}
+}
+
+// startBlock makes blk the current block, placing it after the blocks
+// already in the function.
+func (b *builder) startBlock(blk *Block) {
+	b.fn.Blocks = append(b.fn.Blocks, blk)
+	b.block = blk
+}
+
+// terminate ends the current block with t.
+func (b *builder) terminate(t Terminator) {
+	b.current().Term = t
+	b.block = nil
+}
+
+// jump ends the current block with a jump to target, unless control cannot
+// reach the end of it.
+func (b *builder) jump(target *Block) {
+	if b.block != nil {
+		b.terminate(&Jump{Target: target})
+	}
+}
+
+// current returns the block to add instructions to. Code after a return
+// goes in a block of its own that nothing jumps to.
+func (b *builder) current() *Block {
+	if b.block == nil {
+		b.startBlock(b.newBlock("unreachable"))
+	}
+	return b.block
+}
+
+func (b *builder) emit(in Instr) {
+	blk := b.current()
+	blk.Instrs = append(blk.Instrs, in)
+}
+
+func (b *builder) newTemp(typ types.Type) *Temp {
+	t := &Temp{ID: b.temps, Type: typ}
+	b.temps++
+	return t
+}
+
+// openFrame emits code to open a frame. Its size is filled in by endFrame.
+func (b *builder) openFrame() {
+	open := &OpenFrame{}
+	b.emit(open)
+	b.frames = append(b.frames, &frame{open: open})
+}
+
+// closeFrame emits code to close the innermost frame, if control reaches
+// this point. An if closes its frame once on each branch.
+func (b *builder) closeFrame() {
+	if b.block != nil {
+		b.emit(&CloseFrame{})
+	}
+}
+
+// endFrame ends the innermost frame.
+func (b *builder) endFrame() {
+	f := b.frames[len(b.frames)-1]
+	if f.open != nil {
+		f.open.Size = f.size
+	}
+	b.frames = b.frames[:len(b.frames)-1]
+}
+
+// define makes room in the innermost frame for the slots sema gave decl.
+func (b *builder) define(decl *ast.ASTVarDeclNode) Slot {
+	depth := len(b.frames) - 1
+	if decl.Slot.Depth != depth {
+		panic(fmt.Sprintf("ir: %s was given a slot in frame %d, declared in frame %d", decl.Token.Lexeme, decl.Slot.Depth, depth))
+	}
+	f := b.frames[depth]
+	f.size = max(f.size, decl.Slot.Index+types.Size(decl.Type))
+	return Slot{Index: decl.Slot.Index}
+}
+
+// slot returns the slot of the variable node refers to, as seen from the
+// innermost frame.
+func (b *builder) slot(node *ast.ASTVariableNode) Slot {
+	if node.Decl == nil {
+		panic(fmt.Sprintf("ir: %s at %v is not resolved", node.Token.Lexeme, node.Span().Start))
+	}
+	return b.slotOf(node.Decl)
+}
+
+// slotOf returns the slot of decl as seen from the innermost frame: its
+// own slot if it is declared in the current function, and the slot of the
+// function's copy of it otherwise.
+func (b *builder) slotOf(decl *ast.ASTVarDeclNode) Slot {
+	level := len(b.frames) - 1
+	if decl.Slot.Depth >= b.base {
+		return Slot{Index: decl.Slot.Index, Level: level - decl.Slot.Depth}
+	}
+	for _, c := range b.decl.Captures {
+		if c.Decl == decl {
+			return Slot{Index: c.Index, Level: level - b.base}
+		}
+	}
+	panic(fmt.Sprintf("ir: %s uses %s, which it does not capture", b.decl.Token.Lexeme, decl.Token.Lexeme))
+}
+
+// ===== Statements =====
+
+func (b *builder) stmts(stmts []ast.ASTNode) {
+	for _, stmt := range stmts {
+		b.stmt(stmt)
+	}
+}
+
+// body lowers the branch or body of a statement in the current frame.
+func (b *builder) body(node ast.ASTNode) {
+	if blk, ok := node.(*ast.ASTBlockNode); ok {
+		b.stmts(blk.Stmts)
+	} else {
+		b.stmt(node)
+	}
+}
+
+func (b *builder) stmt(node ast.ASTNode) {
+	switch node := node.(type) {
+	case *ast.ASTBlockNode:
+		b.openFrame()
+		b.stmts(node.Stmts)
+		b.closeFrame()
+		b.endFrame()
+	case *ast.ASTVarDeclNode:
+		value := b.expr(node.Expression)
+		slot := b.define(node)
+		b.emit(&Store{Slot: slot, Value: value})
+	case *ast.ASTAssignmentNode:
+		value := b.expr(node.Expr)
+		store := &Store{Slot: b.slot(&node.Id), Value: value}
+		if hasOffset(&node.Id) {
+			store.Index = b.expr(node.Id.Offset)
+		}
+		b.emit(store)
+	case *ast.ASTBuiltinFuncNode:
+		b.builtin(node)
+	case *ast.ASTPrintNode:
+		b.emit(&Builtin{Name: "print", Args: []*Temp{b.expr(node.Expr.Expr)}})
+	case *ast.ASTIfNode:
+		b.ifStmt(node)
+	case *ast.ASTWhileNode:
+		b.whileStmt(node)
+	case *ast.ASTForNode:
+		b.forStmt(node)
+	case *ast.ASTFuncDeclNode:
+		b.funcDecl(node)
+	case *ast.ASTReturnNode:
+		value := b.expr(node.Expr)
+		var outs []*Temp
+		for i := len(b.decl.Captures) - 1; i >= 0; i-- {
+			if c := b.decl.Captures[i]; c.Written {
+				out := b.newTemp(c.Decl.Type)
+				b.emit(&Load{Dst: out, Slot: b.slotOf(c.Decl)})
+				outs = append([]*Temp{out}, outs...)
+			}
+		}
+		for range b.frames[b.base+1:] {
+			b.emit(&CloseFrame{})
+		}
+		b.terminate(&Return{Value: value, Outs: outs})
+	case *ast.ASTEpsilon, nil:
+	default:
+		panic(fmt.Sprintf("ir: unexpected statement %T", node))
+	}
+}
+
+// ifStmt opens one frame for both branches, each of which closes it.
+func (b *builder) ifStmt(node *ast.ASTIfNode) {
+	then, els, end := b.newBlock("if_then"), b.newBlock("if_else"), b.newBlock("if_end")
+	b.openFrame()
+	b.terminate(&Branch{Cond: b.expr(node.Condition), Then: then, Else: els})
+
+	b.startBlock(then)
+	b.body(node.ThenBlock)
+	b.closeFrame()
+	b.jump(end)
+
+	b.startBlock(els)
+	if node.ElseBlock != nil {
+		b.body(node.ElseBlock)
+	}
+	b.closeFrame()
+	b.jump(end)
+
+	b.endFrame()
+	b.startBlock(end)
+}
+
+func (b *builder) whileStmt(node *ast.ASTWhileNode) {
+	cond, body, end := b.newBlock("while_cond"), b.newBlock("while_body"), b.newBlock("while_end")
+	b.openFrame()
+	b.jump(cond)
+
+	b.startBlock(cond)
+	b.terminate(&Branch{Cond: b.expr(node.Condition), Then: body, Else: end})
+
+	b.startBlock(body)
+	b.body(node.Block)
+	b.jump(cond)
+
+	b.startBlock(end)
+	b.closeFrame()
+	b.endFrame()
+}
+
+func (b *builder) forStmt(node *ast.ASTForNode) {
+	cond, body, end := b.newBlock("for_cond"), b.newBlock("for_body"), b.newBlock("for_end")
+	b.openFrame()
+	b.stmt(node.VarDecl)
+	b.jump(cond)
+
+	b.startBlock(cond)
+	b.terminate(&Branch{Cond: b.expr(node.Condition), Then: body, Else: end})
+
+	b.startBlock(body)
+	b.body(node.Block)
+	b.stmt(node.Increment)
+	b.jump(cond)
+
+	b.startBlock(end)
+	b.closeFrame()
+	b.endFrame()
+}
+
+// funcDecl builds a function. At run time its frame sits on top of its
+// caller's, so the variables declared outside it that it uses are passed
+// in as extra parameters after its own, one per capture, and those it
+// assigns are passed back by its returns.
+func (b *builder) funcDecl(node *ast.ASTFuncDeclNode) {
+	fn, block, temps, base, outer := b.fn, b.block, b.temps, b.base, b.decl
+
+	decl := b.startFunc(node.Token.Lexeme, node.ReturnType)
+	b.base, b.decl = len(b.frames), node
+	b.frames = append(b.frames, &frame{})
+	params := 0
+	for _, param := range node.Params.(*ast.ASTFormalParamsNode).Params {
+		param := param.(*ast.ASTVarDeclNode)
+		decl.Params = append(decl.Params, Param{Name: param.Token.Lexeme, Type: param.Type})
+		b.define(param)
+		params += types.Size(param.Type)
+	}
+	for _, c := range node.Captures {
+		decl.Params = append(decl.Params, Param{Name: c.Decl.Token.Lexeme, Type: c.Decl.Type})
+		params += types.Size(c.Decl.Type)
+	}
+	f := b.frames[b.base]
+	f.size = max(f.size, params)
+	b.stmts(node.Block.(*ast.ASTBlockNode).Stmts)
+	decl.Locals = f.size - params
+	b.endFrame()
+	b.endFunc()
+
+	b.fn, b.block, b.temps, b.base, b.decl = fn, block, temps, base, outer
+}
+
+// ===== Expressions =====
+
+// expr lowers an expression and returns the temporary holding its value.
+func (b *builder) expr(node ast.ASTNode) *Temp {
+	switch node := node.(type) {
+	case *ast.ASTExpressionNode:
+		return b.expr(node.Expr)
+	case *ast.ASTIntegerNode:
+		return b.constant(types.Int, float64(node.Value))
+	case *ast.ASTFloatNode:
+		return b.constant(types.Float, node.Value)
+	case *ast.ASTBooleanNode:
+		if node.Value {
+			return b.constant(types.Bool, 1)
+		}
+		return b.constant(types.Bool, 0)
+	case *ast.ASTColorNode:
+		// the lexer only accepts well-formed colour literals
+		rgb, _ := parir.ParseOperand(node.Value)
+		return b.constant(types.Colour, float64(rgb.Int))
+	case *ast.ASTVariableNode:
+		slot := b.slot(node)
+		if !hasOffset(node) {
+			dst := b.newTemp(typeOf(node))
+			b.emit(&Load{Dst: dst, Slot: slot})
+			return dst
+		}
+		index := b.expr(node.Offset)
+		dst := b.newTemp(typeOf(node))
+		b.emit(&Load{Dst: dst, Slot: slot, Index: index})
+		return dst
+	case *ast.ASTBinaryOpNode:
+		y := b.expr(node.Right)
+		x := b.expr(node.Left)
+		dst := b.newTemp(typeOf(node))
+		op := binaryOps[node.Operator]
+		b.emit(&Binary{Dst: dst, Op: op, X: x, Y: y})
+		return dst
+	case *ast.ASTUnaryOpNode:
+		x := b.expr(node.Operand)
+		op := OpNot
+		if node.Operator == "-" {
+			op = OpNeg
+		}
+		dst := b.newTemp(typeOf(node))
+		b.emit(&Unary{Dst: dst, Op: op, X: x})
+		return dst
+	case *ast.ASTTypeCastNode:
+		x := b.expr(node.Expr)
+		dst := b.newTemp(typeOf(node))
+		b.emit(&Cast{Dst: dst, X: x})
+		return dst
+	case *ast.ASTArrayNode:
+		elems := b.args(node.Items)
+		// a literal shorter than its declared type fills the start of
+		// the array
+		dst := b.newTemp(types.Array{Elem: types.Elem(typeOf(node)), Len: len(elems)})
+		b.emit(&MakeArray{Dst: dst, Elems: elems})
+		return dst
+	case *ast.ASTFuncCallNode:
+		// the captures follow the arguments, so they are lowered first
+		captures := make([]*Temp, len(node.Decl.Captures))
+		for i := len(captures) - 1; i >= 0; i-- {
+			decl := node.Decl.Captures[i].Decl
+			captures[i] = b.newTemp(decl.Type)
+			b.emit(&Load{Dst: captures[i], Slot: b.slotOf(decl)})
+		}
+		args := append(b.args(node.Params.(*ast.ASTActualParamsNode).Params), captures...)
+		call := &Call{Dst: b.newTemp(typeOf(node)), Func: node.Name.Lexeme, Args: args}
+		// the captures the callee assigns come back on top of its
+		// result and are stored before the result is used
+		var stores []Instr
+		for _, c := range node.Decl.Captures {
+			if c.Written {
+				out := b.newTemp(c.Decl.Type)
+				call.Outs = append(call.Outs, out)
+				stores = append(stores, &Store{Slot: b.slotOf(c.Decl), Value: out})
+			}
+		}
+		b.emit(call)
+		for _, store := range stores {
+			b.emit(store)
+		}
+		return call.Dst
+	case *ast.ASTBuiltinFuncNode:
+		return b.builtin(node)
+	}
+	panic(fmt.Sprintf("ir: unexpected expression %T", node))
+}
+
+// hasOffset reports whether node indexes into an array.
+func hasOffset(node *ast.ASTVariableNode) bool {
+	_, isEpsilon := node.Offset.(*ast.ASTEpsilon)
+	return node.Offset != nil && !isEpsilon
+}
+
+var binaryOps = map[string]Op{
+	"+":   OpAdd,
+	"-":   OpSub,
+	"*":   OpMul,
+	"/":   OpDiv,
+	"%":   OpMod,
+	"and": OpAnd,
+	"or":  OpOr,
+	"==":  OpEq,
+	"!=":  OpNe,
+	"<":   OpLt,
+	"<=":  OpLe,
+	">":   OpGt,
+	">=":  OpGe,
+}
+
+// args lowers nodes from last to first, so that the first ends up on top
+// of the stack, and returns their temporaries in source order.
+func (b *builder) args(nodes []ast.ASTNode) []*Temp {
+	temps := make([]*Temp, len(nodes))
+	for i := len(nodes) - 1; i >= 0; i-- {
+		temps[i] = b.expr(nodes[i])
+	}
+	return temps
+}
+
+// builtin lowers a builtin call and returns its result, or nil for a
+// builtin without one.
+func (b *builder) builtin(node *ast.ASTBuiltinFuncNode) *Temp {
+	in := &Builtin{Name: node.Token.Lexeme[2:], Args: b.args(node.Args)}
+	if typ := node.ExprType(); typ != nil {
+		in.Dst = b.newTemp(typ)
+	}
+	b.emit(in)
+	return in.Dst
+}
+
+// typeOf returns the type semantic analysis recorded for node.
+func typeOf(node ast.Expr) types.Type {
+	typ := node.ExprType()
+	if typ == nil || typ == types.Invalid {
+		panic(fmt.Sprintf("ir: %T at %v has no checked type", node, node.Span().Start))
+	}
+	return typ
+}
+
+func (b *builder) constant(typ types.Type, value float64) *Temp {
+	dst := b.newTemp(typ)
+	b.emit(&Const{Dst: dst, Value: value})
+	return dst
+}
@@ -0,0 +1,93 @@
+package ir
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/giuszeppe/compiler-theory/graph"
+)
+
+// Succs returns the blocks control can go to from the end of b: none after
+// a return, halt or unreachable, and the true branch first after a branch.
+func (b *Block) Succs() []*Block {
+	switch t := b.Term.(type) {
+	case *Jump:
+		return []*Block{t.Target}
+	case *Branch:
+		return []*Block{t.Then, t.Else}
+	}
+	return nil
+}
+
+// CFG is the control flow graph of a function, whose nodes are its blocks.
+type CFG struct {
+	Func  *Func
+	Entry *Block
+	Preds map[*Block][]*Block
+}
+
+// NewCFG returns the control flow graph of fn.
+func NewCFG(fn *Func) *CFG {
+	return &CFG{Func: fn, Entry: fn.Blocks[0], Preds: graph.Preds(fn.Blocks, (*Block).Succs)}
+}
+
+// Reachable returns the blocks control can reach from the entry.
+func (g *CFG) Reachable() map[*Block]bool {
+	return graph.Reachable(g.Entry, (*Block).Succs)
+}
+
+// WriteDOT writes the control flow graphs of the functions in p to w as a
+// Graphviz digraph, one cluster per function. Each function has an exit
+// node that its returns and halts lead to, and blocks control cannot reach
+// are dashed.
+func WriteDOT(w io.Writer, p *Program) error {
+	var b strings.Builder
+	b.WriteString("digraph cfg {\n")
+	b.WriteString("\tnode [shape=box, fontname=\"monospace\"];\n")
+	for _, fn := range p.Funcs {
+		g := NewCFG(fn)
+		reachable := g.Reachable()
+		exit := dotID(fn.Name + ".exit")
+
+		fmt.Fprintf(&b, "\tsubgraph %s {\n", dotID("cluster_"+fn.Name))
+		fmt.Fprintf(&b, "\t\tlabel=%s;\n", dotID(fn.Name))
+		for _, blk := range fn.Blocks {
+			var label strings.Builder
+			label.WriteString(blk.Name + ":\\l")
+			for _, in := range blk.Instrs {
+				label.WriteString(dotEscape(in.String()) + "\\l")
+			}
+			label.WriteString(dotEscape(blk.Term.String()) + "\\l")
+			style := ""
+			if !reachable[blk] {
+				style = ", style=dashed"
+			}
+			fmt.Fprintf(&b, "\t\t%s [label=\"%s\"%s];\n", dotID(blk.Name), label.String(), style)
+		}
+		fmt.Fprintf(&b, "\t\t%s [label=\"exit\", shape=oval];\n", exit)
+
+		for _, blk := range fn.Blocks {
+			from := dotID(blk.Name)
+			switch t := blk.Term.(type) {
+			case *Jump:
+				fmt.Fprintf(&b, "\t\t%s -> %s;\n", from, dotID(t.Target.Name))
+			case *Branch:
+				fmt.Fprintf(&b, "\t\t%s -> %s [label=\"true\"];\n", from, dotID(t.Then.Name))
+				fmt.Fprintf(&b, "\t\t%s -> %s [label=\"false\"];\n", from, dotID(t.Else.Name))
+			case *Return, *Halt:
+				fmt.Fprintf(&b, "\t\t%s -> %s;\n", from, exit)
+			}
+		}
+		b.WriteString("\t}\n")
+	}
+	b.WriteString("}\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func dotID(s string) string { return "\"" + dotEscape(s) + "\"" }
+
+func dotEscape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s)
+}
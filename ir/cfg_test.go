@@ -0,0 +1,59 @@
+package ir
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestCFG(t *testing.T) {
+	prog := build(t, `fun f(x:int) -> int {
+		while (x > 0) { if (x == 3) { return x; } x = x - 1; }
+		return 0;
+		__print 1;
+	}`)
+	g := NewCFG(prog.Funcs[1])
+	blocks := make(map[string]*Block)
+	for _, blk := range g.Func.Blocks {
+		blocks[strings.TrimRight(blk.Name, "0123456789")] = blk
+	}
+
+	cond := blocks["while_cond"]
+	var preds []string
+	for _, p := range g.Preds[cond] {
+		preds = append(preds, p.Name)
+	}
+	if got := strings.Join(preds, " "); got != "entry2 if_end8" {
+		t.Errorf("Expected the loop condition to follow the entry and the loop body, got %s", got)
+	}
+	if succs := blocks["if_then"].Succs(); len(succs) != 0 {
+		t.Errorf("Expected a return to have no successors, got %d", len(succs))
+	}
+
+	reachable := g.Reachable()
+	for name, blk := range blocks {
+		if want := name != "unreachable"; reachable[blk] != want {
+			t.Errorf("Expected %s reachable to be %v", blk.Name, want)
+		}
+	}
+}
+
+// TestNestedDOT keeps examples/nested.dot, the graph reviewers look at, in
+// step with the compiler. Regenerate it with
+//
+//	prlc ir -dump-cfg examples/nested.prl > examples/nested.dot
+func TestNestedDOT(t *testing.T) {
+	src, err := os.ReadFile("../examples/nested.prl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := os.ReadFile("../examples/nested.dot")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got strings.Builder
+	WriteDOT(&got, build(t, string(src)))
+	if got.String() != string(want) {
+		t.Fatalf("examples/nested.dot is out of date, got:\n%s", got.String())
+	}
+}
@@ -0,0 +1,387 @@
+// Package ir is the compiler's mid-level intermediate representation.
+//
+// Build lowers a checked AST into a Program: one Func per PArL function,
+// plus main for the top-level statements. A Func is a list of basic
+// Blocks, each a straight line of Instrs ending in a Terminator. Values
+// live in typed temporaries, variables in frame slots, and frames are
+// opened and closed explicitly, so a backend only has to pick
+// instructions. codegen is the PArIR backend.
+//
+// Build defines the operands of every instruction in reverse order and
+// uses each temporary exactly once, so a stack machine finds operand 0 on
+// top of the stack when it reaches the instruction that uses it. Passes
+// that rewrite the IR must keep this order for codegen to accept it. It
+// also means operands are evaluated from right to left, which shows when
+// a call assigns a variable that the rest of the expression reads.
+package ir
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/giuszeppe/compiler-theory/types"
+)
+
+// Temp is a typed temporary, defined by one instruction and used by one
+// other.
+type Temp struct {
+	ID   int
+	Type types.Type
+}
+
+func (t *Temp) String() string { return fmt.Sprintf("%%%d", t.ID) }
+
+// def prints t where it is defined, with its type.
+func (t *Temp) def() string { return fmt.Sprintf("%%%d:%s", t.ID, t.Type) }
+
+// Slot locates a variable: slot Index of the frame Level frames below the
+// innermost one.
+type Slot struct {
+	Index, Level int
+}
+
+func (s Slot) String() string { return fmt.Sprintf("[%d:%d]", s.Index, s.Level) }
+
+// Op is a unary or binary operator.
+type Op uint8
+
+const (
+	OpAdd Op = iota
+	OpSub
+	OpMul
+	OpDiv
+	OpMod
+	OpAnd
+	OpOr
+	OpEq
+	OpNe
+	OpLt
+	OpLe
+	OpGt
+	OpGe
+	OpNeg
+	OpNot
+)
+
+var opNames = [...]string{
+	OpAdd: "add",
+	OpSub: "sub",
+	OpMul: "mul",
+	OpDiv: "div",
+	OpMod: "mod",
+	OpAnd: "and",
+	OpOr:  "or",
+	OpEq:  "eq",
+	OpNe:  "ne",
+	OpLt:  "lt",
+	OpLe:  "le",
+	OpGt:  "gt",
+	OpGe:  "ge",
+	OpNeg: "neg",
+	OpNot: "not",
+}
+
+func (op Op) String() string {
+	if int(op) < len(opNames) {
+		return opNames[op]
+	}
+	return fmt.Sprintf("Op(%d)", op)
+}
+
+// Instr is an instruction in the body of a block.
+type Instr interface {
+	String() string
+	instr()
+}
+
+// Const defines Dst as a constant. Booleans are 0 or 1 and colours are
+// their RGB value.
+type Const struct {
+	Dst   *Temp
+	Value float64
+}
+
+// Load defines Dst as the variable in Slot. With an Index it is element
+// Index of the array starting at Slot; without one, an array variable is
+// loaded whole.
+type Load struct {
+	Dst   *Temp
+	Slot  Slot
+	Index *Temp
+}
+
+// Store assigns Value to the variable in Slot, or with an Index to element
+// Index of the array starting at Slot.
+type Store struct {
+	Slot  Slot
+	Index *Temp
+	Value *Temp
+}
+
+// Unary defines Dst as Op applied to X.
+type Unary struct {
+	Dst *Temp
+	Op  Op
+	X   *Temp
+}
+
+// Binary defines Dst as X Op Y.
+type Binary struct {
+	Dst  *Temp
+	Op   Op
+	X, Y *Temp
+}
+
+// Cast defines Dst as X converted to the type of Dst.
+type Cast struct {
+	Dst *Temp
+	X   *Temp
+}
+
+// MakeArray defines Dst as the array of Elems.
+type MakeArray struct {
+	Dst   *Temp
+	Elems []*Temp
+}
+
+// Call defines Dst as the result of calling the function Func with Args.
+// The callee's frame holds the arguments in its first slots. Outs are the
+// values the callee passes back along with its result, defined on top of
+// Dst with Outs[0] on top.
+type Call struct {
+	Dst  *Temp
+	Func string
+	Args []*Temp
+	Outs []*Temp
+}
+
+// Builtin calls a PArL builtin, named without its leading underscores,
+// such as "print" or "write_box". Dst is nil for builtins without a
+// result.
+type Builtin struct {
+	Dst  *Temp
+	Name string
+	Args []*Temp
+}
+
+// OpenFrame opens a frame of Size slots.
+type OpenFrame struct {
+	Size int
+}
+
+// CloseFrame closes the innermost frame.
+type CloseFrame struct{}
+
+func (*Const) instr()      {}
+func (*Load) instr()       {}
+func (*Store) instr()      {}
+func (*Unary) instr()      {}
+func (*Binary) instr()     {}
+func (*Cast) instr()       {}
+func (*MakeArray) instr()  {}
+func (*Call) instr()       {}
+func (*Builtin) instr()    {}
+func (*OpenFrame) instr()  {}
+func (*CloseFrame) instr() {}
+
+func (in *Const) String() string {
+	var v string
+	switch in.Dst.Type {
+	case types.Float:
+		v = strconv.FormatFloat(in.Value, 'f', -1, 64)
+		if !strings.ContainsAny(v, ".IN") {
+			v += ".0"
+		}
+	case types.Colour:
+		v = fmt.Sprintf("#%06x", int(in.Value))
+	default:
+		v = strconv.Itoa(int(in.Value))
+	}
+	return fmt.Sprintf("%s = const %s", in.Dst.def(), v)
+}
+
+func (in *Load) String() string {
+	if in.Index != nil {
+		return fmt.Sprintf("%s = load %s + %s", in.Dst.def(), in.Slot, in.Index)
+	}
+	return fmt.Sprintf("%s = load %s", in.Dst.def(), in.Slot)
+}
+
+func (in *Store) String() string {
+	if in.Index != nil {
+		return fmt.Sprintf("store %s + %s, %s", in.Slot, in.Index, in.Value)
+	}
+	return fmt.Sprintf("store %s, %s", in.Slot, in.Value)
+}
+
+func (in *Unary) String() string {
+	return fmt.Sprintf("%s = %s %s", in.Dst.def(), in.Op, in.X)
+}
+
+func (in *Binary) String() string {
+	return fmt.Sprintf("%s = %s %s, %s", in.Dst.def(), in.Op, in.X, in.Y)
+}
+
+func (in *Cast) String() string {
+	return fmt.Sprintf("%s = cast %s", in.Dst.def(), in.X)
+}
+
+func (in *MakeArray) String() string {
+	return fmt.Sprintf("%s = array %s", in.Dst.def(), temps(in.Elems))
+}
+
+func (in *Call) String() string {
+	s := fmt.Sprintf("%s = call %s(%s)", in.Dst.def(), in.Func, temps(in.Args))
+	if len(in.Outs) > 0 {
+		outs := make([]string, len(in.Outs))
+		for i, t := range in.Outs {
+			outs[i] = t.def()
+		}
+		s += " out " + strings.Join(outs, ", ")
+	}
+	return s
+}
+
+func (in *Builtin) String() string {
+	s := in.Name
+	if len(in.Args) > 0 {
+		s += " " + temps(in.Args)
+	}
+	if in.Dst != nil {
+		s = in.Dst.def() + " = " + s
+	}
+	return s
+}
+
+func (in *OpenFrame) String() string  { return fmt.Sprintf("frame.open %d", in.Size) }
+func (in *CloseFrame) String() string { return "frame.close" }
+
+func temps(ts []*Temp) string {
+	s := make([]string, len(ts))
+	for i, t := range ts {
+		s[i] = t.String()
+	}
+	return strings.Join(s, ", ")
+}
+
+// Terminator ends a block and says where control goes next.
+type Terminator interface {
+	String() string
+	terminator()
+}
+
+// Jump continues at Target.
+type Jump struct {
+	Target *Block
+}
+
+// Branch continues at Then if Cond holds and at Else otherwise.
+type Branch struct {
+	Cond       *Temp
+	Then, Else *Block
+}
+
+// Return returns Value from a function, closing its frame, and passes
+// Outs back with it, Outs[0] on top. Frames opened inside the function
+// must be closed first.
+type Return struct {
+	Value *Temp
+	Outs  []*Temp
+}
+
+// Halt ends the program.
+type Halt struct{}
+
+// Unreachable ends a block that control never falls out of, such as the
+// end of a function whose every path returns.
+type Unreachable struct{}
+
+func (*Jump) terminator()        {}
+func (*Branch) terminator()      {}
+func (*Return) terminator()      {}
+func (*Halt) terminator()        {}
+func (*Unreachable) terminator() {}
+
+func (t *Jump) String() string { return "jmp " + t.Target.Name }
+func (t *Branch) String() string {
+	return fmt.Sprintf("br %s, %s, %s", t.Cond, t.Then.Name, t.Else.Name)
+}
+func (t *Return) String() string {
+	if len(t.Outs) > 0 {
+		return fmt.Sprintf("ret %s out %s", t.Value, temps(t.Outs))
+	}
+	return "ret " + t.Value.String()
+}
+func (t *Halt) String() string        { return "halt" }
+func (t *Unreachable) String() string { return "unreachable" }
+
+// Block is a basic block. Name is unique within the program.
+type Block struct {
+	Name   string
+	Instrs []Instr
+	Term   Terminator
+}
+
+// Param is a formal parameter of a function.
+type Param struct {
+	Name string
+	Type types.Type
+}
+
+// Func is a function. Blocks[0] is its entry and the blocks are listed in
+// the order a backend should lay them out.
+type Func struct {
+	Name   string
+	Params []Param
+	Return types.Type // nil for main
+	// Locals is the number of slots the function needs beyond those of
+	// its parameters, which fill the start of its frame.
+	Locals int
+	Blocks []*Block
+}
+
+// Program is a lowered PArL program. Funcs[0] is main, which runs the
+// top-level statements; the rest are in declaration order.
+type Program struct {
+	Funcs []*Func
+}
+
+// Print writes p to w in the IR's text form.
+func Print(w io.Writer, p *Program) error {
+	var b strings.Builder
+	for i, fn := range p.Funcs {
+		if i > 0 {
+			b.WriteByte('\n')
+		}
+		params := make([]string, len(fn.Params))
+		for i, p := range fn.Params {
+			params[i] = p.Name + ":" + p.Type.String()
+		}
+		fmt.Fprintf(&b, "func %s(%s)", fn.Name, strings.Join(params, ", "))
+		if fn.Return != nil {
+			fmt.Fprintf(&b, " -> %s", fn.Return)
+		}
+		if fn.Locals > 0 {
+			fmt.Fprintf(&b, " locals %d", fn.Locals)
+		}
+		b.WriteString(" {\n")
+		for _, blk := range fn.Blocks {
+			fmt.Fprintf(&b, "%s:\n", blk.Name)
+			for _, in := range blk.Instrs {
+				fmt.Fprintf(&b, "    %s\n", in)
+			}
+			fmt.Fprintf(&b, "    %s\n", blk.Term)
+		}
+		b.WriteString("}\n")
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func (p *Program) String() string {
+	var b strings.Builder
+	Print(&b, p)
+	return b.String()
+}
@@ -0,0 +1,135 @@
+package ir
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/giuszeppe/compiler-theory/diag"
+	"github.com/giuszeppe/compiler-theory/parser"
+	"github.com/giuszeppe/compiler-theory/sema"
+)
+
+func build(t *testing.T, src string) *Program {
+	t.Helper()
+	p := parser.NewParser(src)
+	node, err := p.Parse(parser.NewGrammar())
+	if err != nil {
+		t.Fatalf("Failed to parse program: %v", err)
+	}
+	if diags := sema.NewSemanticVisitor().Analyze(node); diag.HasErrors(diags) {
+		t.Fatalf("Program does not check: %v", diags)
+	}
+	return Build(node)
+}
+
+func TestBuild(t *testing.T) {
+	prog := build(t, `
+		fun sign(x:float) -> int {
+			let one:int = 1;
+			if (x < 0.0) { return -one; } else { let y:int = one; x = 0.0; }
+			return one;
+			__print 0;
+		}
+		let s:int = sign(2.5);
+		{ let c:colour = #f00; __write s, s, c; }`)
+	want := `func main() {
+entry1:
+    frame.open 1
+    %0:float = const 2.5
+    %1:int = call sign(%0)
+    store [0:0], %1
+    frame.open 1
+    %2:colour = const #ff0000
+    store [0:0], %2
+    %3:colour = load [0:0]
+    %4:int = load [0:1]
+    %5:int = load [0:1]
+    write %5, %4, %3
+    frame.close
+    frame.close
+    halt
+}
+
+func sign(x:float) -> int locals 1 {
+entry2:
+    %0:int = const 1
+    store [1:0], %0
+    frame.open 1
+    %1:float = const 0.0
+    %2:float = load [0:1]
+    %3:bool = lt %2, %1
+    br %3, if_then3, if_else4
+if_then3:
+    %4:int = load [1:1]
+    %5:int = neg %4
+    frame.close
+    ret %5
+if_else4:
+    %6:int = load [1:1]
+    store [0:0], %6
+    %7:float = const 0.0
+    store [0:1], %7
+    frame.close
+    jmp if_end5
+if_end5:
+    %8:int = load [1:0]
+    ret %8
+unreachable6:
+    %9:int = const 0
+    print %9
+    unreachable
+}
+`
+	if got := prog.String(); got != want {
+		t.Fatalf("Expected:\n%s\ngot:\n%s", want, got)
+	}
+}
+
+func TestBuildFrames(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want []string
+	}{
+		{
+			"arrays take one slot per element",
+			`let xs:int[3] = [1, 2, 3]; let y:int = xs[1];`,
+			[]string{"frame.open 4", "%3:int[3] = array %2, %1, %0", "store [3:0], %5"},
+		},
+		{
+			"if branches share a frame",
+			`if (true) { let a:int = 1; } else { let b:int = 2; let c:int = 3; }`,
+			[]string{"frame.open 3", "store [0:0], %1", "store [2:0], %3"},
+		},
+		{
+			"loops get a frame",
+			`let n:int = 0; while (n < 3) { let m:int = n + 1; n = m; }`,
+			[]string{"frame.open 1\n    jmp while_cond", "store [0:0], %6", "store [0:1], %7"},
+		},
+		{
+			"sibling scopes reuse names",
+			`for (let i:int = 0; i < 2; i = i + 1) { } for (let i:int = 5; i < 7; i = i + 1) { __print i; }`,
+			[]string{"%11:int = load [0:0]\n    print %11"},
+		},
+		{
+			"indexed store",
+			`let xs:int[2] = [1, 2]; let i:int = 1; xs[i] = 7;`,
+			[]string{"%4:int = const 7\n    %5:int = load [2:0]\n    store [0:0] + %5, %4"},
+		},
+		{
+			"return closes the frames opened in the function",
+			`fun f() -> int { while (true) { if (false) { return 1; } } return 0; }`,
+			[]string{"frame.close\n    frame.close\n    ret %2"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := build(t, tt.src).String()
+			for _, want := range tt.want {
+				if !strings.Contains(got, want) {
+					t.Fatalf("Expected %q in:\n%s", want, got)
+				}
+			}
+		})
+	}
+}
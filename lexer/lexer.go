@@ -1,7 +1,11 @@
-package main
+// Package lexer turns PArL source text into tokens using a table-driven DFA.
+package lexer
 
 import (
 	"slices"
+	"unicode/utf8"
+
+	"github.com/giuszeppe/compiler-theory/types"
 )
 
 type Token struct {
@@ -9,6 +13,7 @@ type Token struct {
 	Lexeme string
 	Line   int `default:"1"`
 	Column int `default:"1"`
+	Offset int // byte offset of the first character in the source
 }
 
 type TokenType int
@@ -111,6 +116,16 @@ func (t TokenType) String() string {
 		return "RightBracket"
 	case LeftBracketToken:
 		return "LeftBracket"
+	case OperatorToken:
+		return "Operator"
+	case HexNumber:
+		return "HexNumber"
+	case Float:
+		return "Float"
+	case True:
+		return "True"
+	case False:
+		return "False"
 	default:
 		return "Unknown"
 	}
@@ -307,7 +322,7 @@ var charCategoryMap = map[byte]string{
 }
 
 func NewToken(t TokenType, lexeme string) Token {
-	return Token{t, lexeme, 1, 1}
+	return Token{t, lexeme, 1, 1, 0}
 }
 
 type Lexer struct {
@@ -527,19 +542,20 @@ func getKeywordTokenByLexeme(lexeme string) (Token, bool) {
 	}
 }
 
+// typeTokens maps each primitive type to the token its name lexes to.
+var typeTokens = map[types.Primitive]TokenType{
+	types.Int:    IntType,
+	types.Float:  FloatType,
+	types.Bool:   BoolType,
+	types.Colour: ColourType,
+}
+
 func getTypeTokenByLexeme(lexeme string) (Token, bool) {
-	switch lexeme {
-	case "colour":
-		return Token{Type: ColourType, Lexeme: lexeme}, true
-	case "int":
-		return Token{Type: IntType, Lexeme: lexeme}, true
-	case "bool":
-		return Token{Type: BoolType, Lexeme: lexeme}, true
-	case "float":
-		return Token{Type: FloatType, Lexeme: lexeme}, true
-	default:
+	p, ok := types.Lookup(lexeme)
+	if !ok {
 		return Token{}, false
 	}
+	return Token{Type: typeTokens[p], Lexeme: lexeme}, true
 }
 
 func (l *Lexer) getTokenTypeByFinalState(state int, lexeme string) Token {
@@ -598,9 +614,10 @@ func (l *Lexer) NextToken(src string, idx int) (Token, string) {
 	lexeme := ""
 	startLine := l.Line
 	startColumn := l.Column
+	startOffset := idx
 
 	if l.isEndOfInput(src, idx) {
-		return Token{Type: End, Lexeme: "end", Line: l.Line, Column: l.Column}, "end"
+		return Token{Type: End, Lexeme: "end", Line: l.Line, Column: l.Column, Offset: idx}, "end"
 	}
 
 	for state != -1 {
@@ -644,41 +661,46 @@ func (l *Lexer) NextToken(src string, idx int) (Token, string) {
 	}
 
 	if syntaxError {
-		return Token{Type: Error, Lexeme: lexeme, Line: startLine, Column: startColumn}, lexeme
+		return Token{Type: Error, Lexeme: lexeme, Line: startLine, Column: startColumn, Offset: startOffset}, lexeme
 	}
 	if l.isAcceptingState(state) {
 		token := l.getTokenTypeByFinalState(state, lexeme)
 		token.Line = startLine
 		token.Column = startColumn
+		token.Offset = startOffset
 		return token, lexeme
 	}
-	return Token{Type: Error, Lexeme: lexeme, Line: startLine, Column: startColumn}, lexeme
+	return Token{Type: Error, Lexeme: lexeme, Line: startLine, Column: startColumn, Offset: startOffset}, lexeme
 }
 
+// GenerateTokens splits src into tokens, ending with an End token. A
+// character no token can start with becomes an Error token of its own, and
+// lexing carries on after it.
 func (l *Lexer) GenerateTokens(src string) []Token {
-	//fmt.Println("INPUT::", src)
 	tokens := []Token{}
 	idx := 0
-	token, lexeme := l.NextToken(src, idx)
-	tokens = append(tokens, token)
-
-	for token.Type != End {
-		// Track line/column
-		if token.Type == NewLineToken {
-			l.Line++
-			l.Column = 1
-		} else {
-			l.Column++
+	for {
+		token, lexeme := l.NextToken(src, idx)
+		if token.Type == Error && lexeme == "" {
+			_, size := utf8.DecodeRuneInString(src[idx:])
+			lexeme = src[idx : idx+size]
+			token.Lexeme = lexeme
 		}
-		idx += len(lexeme)
-		token, lexeme = l.NextToken(src, idx)
 		tokens = append(tokens, token)
-		if token.Type == Error {
-			break
+		if token.Type == End {
+			return tokens
 		}
+		// Track line/column
+		for i := 0; i < len(lexeme); i++ {
+			if lexeme[i] == '\n' {
+				l.Line++
+				l.Column = 1
+			} else {
+				l.Column++
+			}
+		}
+		idx += len(lexeme)
 	}
-
-	return tokens
 }
 
 func isDigit(ch byte) bool {
@@ -1,4 +1,4 @@
-package main
+package lexer
 
 import (
 	"testing"
@@ -79,6 +79,8 @@ func TestAllTokenTypes(t *testing.T) {
                        `}},
 		{"[", Token{Type: LeftBracketToken, Lexeme: "["}},
 		{"]", Token{Type: RightBracketToken, Lexeme: "]"}},
+		{"$ x", Token{Type: Error, Lexeme: "$"}},
+		{"é", Token{Type: Error, Lexeme: "é"}},
 	}
 	for _, test := range tests {
 		tokens := lexer.GenerateTokens(test.input)
@@ -92,3 +94,38 @@ func TestAllTokenTypes(t *testing.T) {
 		}
 	}
 }
+
+func TestTokenPositions(t *testing.T) {
+	lexer := NewLexer()
+	src := "let x = 1;\n/* a\nb */ y"
+	tokens := lexer.GenerateTokens(src)
+
+	expected := []struct {
+		lexeme               string
+		line, column, offset int
+	}{
+		{"let", 1, 1, 0},
+		{"x", 1, 5, 4},
+		{"=", 1, 7, 6},
+		{"1", 1, 9, 8},
+		{";", 1, 10, 9},
+		{"/* a\nb */", 2, 1, 11},
+		{"y", 3, 6, 21},
+		{"end", 3, 7, 22},
+	}
+	i := 0
+	for _, tok := range tokens {
+		if tok.Type == WhitespaceToken || tok.Type == NewLineToken {
+			continue
+		}
+		if i >= len(expected) {
+			t.Fatalf("Unexpected extra token %v", tok)
+		}
+		e := expected[i]
+		if tok.Lexeme != e.lexeme || tok.Line != e.line || tok.Column != e.column || tok.Offset != e.offset {
+			t.Errorf("Expected %q at %d:%d (offset %d), got %q at %d:%d (offset %d)",
+				e.lexeme, e.line, e.column, e.offset, tok.Lexeme, tok.Line, tok.Column, tok.Offset)
+		}
+		i++
+	}
+}
@@ -0,0 +1,252 @@
+package lsp
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/giuszeppe/compiler-theory/ast"
+	"github.com/giuszeppe/compiler-theory/diag"
+	"github.com/giuszeppe/compiler-theory/lexer"
+	"github.com/giuszeppe/compiler-theory/parser"
+	"github.com/giuszeppe/compiler-theory/sema"
+)
+
+// document is an open text document and what the compiler found in it.
+type document struct {
+	URI     string
+	Version int
+	Text    string
+
+	Diagnostics []diag.Diagnostic
+	// Root, Uses and Decls are set whenever the parser returns a tree,
+	// which after syntax errors is the partial one its recovery built.
+	Root ast.ASTNode
+	// Uses maps identifier uses to their declarations, see
+	// sema.SemanticVisitor.Uses.
+	Uses map[ast.ASTNode]ast.ASTNode
+	// Decls holds every variable, parameter and function declaration in
+	// source order.
+	Decls []ast.ASTNode
+}
+
+// analyze parses and checks text. A document with syntax errors is still
+// checked, as far as the parser recovered, but only errors are kept from
+// the check: warnings such as an unread variable may be wrong about
+// statements the parser had to drop.
+func analyze(g *parser.Grammar, uri string, version int, text string) *document {
+	doc := &document{URI: uri, Version: version, Text: text}
+	p := parser.NewParser(text)
+	root, err := p.Parse(g)
+	if root == nil {
+		doc.Diagnostics = p.Errors
+		return doc
+	}
+	v := sema.NewSemanticVisitor()
+	diags := diag.WarningConfig{}.Apply(v.Analyze(root), diag.ParseIgnores(p.Tokens))
+	if err != nil {
+		diags = slices.DeleteFunc(diags, func(d diag.Diagnostic) bool {
+			return d.Severity != diag.SeverityError
+		})
+		diags = append(slices.Clone(p.Errors), diags...)
+		sort.SliceStable(diags, func(i, j int) bool {
+			return diags[i].Span.Start.Offset < diags[j].Span.Start.Offset
+		})
+	}
+	doc.Diagnostics = diags
+	doc.Root = root
+	doc.Uses = v.Uses
+	collectDecls(root, &doc.Decls)
+	return doc
+}
+
+// collectDecls appends the declarations under node to decls.
+func collectDecls(node ast.ASTNode, decls *[]ast.ASTNode) {
+	switch n := node.(type) {
+	case *ast.ASTProgramNode:
+		collectDecls(&n.Block, decls)
+	case *ast.ASTBlockNode:
+		for _, stmt := range n.Stmts {
+			collectDecls(stmt, decls)
+		}
+	case *ast.ASTVarDeclNode:
+		*decls = append(*decls, n)
+	case *ast.ASTFuncDeclNode:
+		*decls = append(*decls, n)
+		collectDecls(n.Params, decls)
+		collectDecls(n.Block, decls)
+	case *ast.ASTFormalParamsNode:
+		for _, param := range n.Params {
+			collectDecls(param, decls)
+		}
+	case *ast.ASTIfNode:
+		collectDecls(n.ThenBlock, decls)
+		collectDecls(n.ElseBlock, decls)
+	case *ast.ASTWhileNode:
+		collectDecls(n.Block, decls)
+	case *ast.ASTForNode:
+		collectDecls(n.VarDecl, decls)
+		collectDecls(n.Block, decls)
+	}
+}
+
+// nameToken returns the identifier token of a declaration or use.
+func nameToken(node ast.ASTNode) (lexer.Token, bool) {
+	switch n := node.(type) {
+	case *ast.ASTVarDeclNode:
+		return n.Token, true
+	case *ast.ASTFuncDeclNode:
+		return n.Token, true
+	case *ast.ASTVariableNode:
+		return n.Token, true
+	case *ast.ASTFuncCallNode:
+		return n.Name, true
+	}
+	return lexer.Token{}, false
+}
+
+// declarationAt returns the declaration named by the identifier at offset,
+// whether the identifier is a use or the declaration itself, along with the
+// identifier's span.
+func (d *document) declarationAt(offset int) (ast.ASTNode, diag.Span, bool) {
+	contains := func(node ast.ASTNode) (diag.Span, bool) {
+		tok, ok := nameToken(node)
+		if !ok {
+			return diag.Span{}, false
+		}
+		span := diag.SpanOf(tok)
+		return span, span.Start.Offset <= offset && offset <= span.End.Offset
+	}
+	for use, decl := range d.Uses {
+		if span, ok := contains(use); ok {
+			return decl, span, true
+		}
+	}
+	for _, decl := range d.Decls {
+		if span, ok := contains(decl); ok {
+			return decl, span, true
+		}
+	}
+	return nil, diag.Span{}, false
+}
+
+// signature describes a declaration the way it is written in source.
+func signature(decl ast.ASTNode) string {
+	switch n := decl.(type) {
+	case *ast.ASTVarDeclNode:
+		return fmt.Sprintf("let %s: %s", n.Token.Lexeme, n.Type)
+	case *ast.ASTFuncDeclNode:
+		var params []string
+		if fp, ok := n.Params.(*ast.ASTFormalParamsNode); ok {
+			for _, p := range fp.Params {
+				if vd, ok := p.(*ast.ASTVarDeclNode); ok {
+					params = append(params, vd.Token.Lexeme+": "+vd.Type.String())
+				}
+			}
+		}
+		return fmt.Sprintf("fun %s(%s) -> %s", n.Token.Lexeme, strings.Join(params, ", "), n.ReturnType)
+	}
+	return ""
+}
+
+// symbols returns the document outline: functions with their parameters and
+// locals nested inside, and top-level variables.
+func (d *document) symbols() []DocumentSymbol {
+	syms := []DocumentSymbol{}
+	if d.Root != nil {
+		syms = append(syms, outline(d.Root)...)
+	}
+	return syms
+}
+
+func outline(node ast.ASTNode) []DocumentSymbol {
+	var syms []DocumentSymbol
+	switch n := node.(type) {
+	case *ast.ASTProgramNode:
+		syms = outline(&n.Block)
+	case *ast.ASTBlockNode:
+		for _, stmt := range n.Stmts {
+			syms = append(syms, outline(stmt)...)
+		}
+	case *ast.ASTFormalParamsNode:
+		for _, param := range n.Params {
+			syms = append(syms, outline(param)...)
+		}
+	case *ast.ASTVarDeclNode:
+		syms = append(syms, symbolFor(n, SymbolKindVariable))
+	case *ast.ASTFuncDeclNode:
+		sym := symbolFor(n, SymbolKindFunction)
+		sym.Children = append(outline(n.Params), outline(n.Block)...)
+		syms = append(syms, sym)
+	case *ast.ASTIfNode:
+		syms = append(outline(n.ThenBlock), outline(n.ElseBlock)...)
+	case *ast.ASTWhileNode:
+		syms = outline(n.Block)
+	case *ast.ASTForNode:
+		syms = append(outline(n.VarDecl), outline(n.Block)...)
+	}
+	return syms
+}
+
+func symbolFor(decl ast.ASTNode, kind SymbolKind) DocumentSymbol {
+	tok, _ := nameToken(decl)
+	return DocumentSymbol{
+		Name:           tok.Lexeme,
+		Detail:         signature(decl),
+		Kind:           kind,
+		Range:          toRange(decl.Span()),
+		SelectionRange: toRange(diag.SpanOf(tok)),
+	}
+}
+
+// offsetOf converts an LSP position to a byte offset in text. Characters are
+// counted in bytes, which matches UTF-16 code units for ASCII sources.
+func offsetOf(text string, pos Position) int {
+	offset := 0
+	for line := 0; line < pos.Line; line++ {
+		i := strings.IndexByte(text[offset:], '\n')
+		if i < 0 {
+			return len(text)
+		}
+		offset += i + 1
+	}
+	// a character past the end of the line means the end of the line
+	end := strings.IndexByte(text[offset:], '\n')
+	if end < 0 {
+		end = len(text) - offset
+	}
+	return offset + min(pos.Character, end)
+}
+
+func toPosition(p diag.Pos) Position {
+	if !p.IsValid() {
+		return Position{}
+	}
+	return Position{Line: p.Line - 1, Character: p.Column - 1}
+}
+
+func toRange(span diag.Span) Range {
+	end := span.End
+	if !end.IsValid() {
+		end = span.Start
+	}
+	return Range{Start: toPosition(span.Start), End: toPosition(end)}
+}
+
+func toDiagnostic(d diag.Diagnostic) Diagnostic {
+	severity := SeverityError
+	switch d.Severity {
+	case diag.SeverityWarning:
+		severity = SeverityWarning
+	case diag.SeverityNote:
+		severity = SeverityInformation
+	}
+	return Diagnostic{
+		Range:    toRange(d.Span),
+		Severity: severity,
+		Code:     d.Code,
+		Source:   "prl",
+		Message:  d.Message,
+	}
+}
@@ -0,0 +1,287 @@
+// Package lsp implements a Language Server Protocol server for PArL.
+//
+// The server speaks JSON-RPC 2.0 with Content-Length framing, normally over
+// stdio. Every change re-parses and re-checks the whole document, and the
+// results back diagnostics, go-to-definition, hover, document symbols and
+// completion.
+package lsp
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io"
+	"sort"
+
+	"github.com/giuszeppe/compiler-theory/ast"
+	"github.com/giuszeppe/compiler-theory/diag"
+	"github.com/giuszeppe/compiler-theory/parser"
+)
+
+// ErrExitWithoutShutdown is returned by Serve when the client sends "exit"
+// without a preceding "shutdown" request.
+var ErrExitWithoutShutdown = errors.New("exit received before shutdown")
+
+type Server struct {
+	grammar  *parser.Grammar
+	docs     map[string]*document
+	out      io.Writer
+	shutdown bool
+}
+
+func NewServer() *Server {
+	return &Server{
+		grammar: parser.NewGrammar(),
+		docs:    make(map[string]*document),
+	}
+}
+
+type handler func(s *Server, params json.RawMessage) (any, error)
+
+var requestHandlers = map[string]handler{
+	"initialize":                  (*Server).initialize,
+	"shutdown":                    (*Server).handleShutdown,
+	"textDocument/definition":     (*Server).definition,
+	"textDocument/hover":          (*Server).hover,
+	"textDocument/documentSymbol": (*Server).documentSymbol,
+	"textDocument/completion":     (*Server).completion,
+}
+
+var notificationHandlers = map[string]handler{
+	"textDocument/didOpen":   (*Server).didOpen,
+	"textDocument/didChange": (*Server).didChange,
+	"textDocument/didClose":  (*Server).didClose,
+}
+
+// Serve reads requests from r and writes responses and notifications to w
+// until the client sends "exit" or r is exhausted.
+func (s *Server) Serve(r io.Reader, w io.Writer) error {
+	s.out = w
+	br := bufio.NewReader(r)
+	for {
+		body, err := readMessage(br)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		var req request
+		if err := json.Unmarshal(body, &req); err != nil {
+			if err := s.replyError(json.RawMessage("null"), &rpcError{Code: codeParseError, Message: err.Error()}); err != nil {
+				return err
+			}
+			continue
+		}
+		if req.Method == "exit" {
+			if !s.shutdown {
+				return ErrExitWithoutShutdown
+			}
+			return nil
+		}
+
+		if req.ID == nil {
+			// notifications get no reply, not even for unknown methods or
+			// bad params; only failing to write is fatal
+			if h, ok := notificationHandlers[req.Method]; ok {
+				var rerr *rpcError
+				if _, err := h(s, req.Params); err != nil && !errors.As(err, &rerr) {
+					return err
+				}
+			}
+			continue
+		}
+
+		h, ok := requestHandlers[req.Method]
+		if !ok {
+			err = s.replyError(*req.ID, &rpcError{Code: codeMethodNotFound, Message: "method not found: " + req.Method})
+		} else if result, herr := h(s, req.Params); herr != nil {
+			var rerr *rpcError
+			if !errors.As(herr, &rerr) {
+				return herr
+			}
+			err = s.replyError(*req.ID, rerr)
+		} else {
+			err = writeMessage(s.out, response{JSONRPC: "2.0", ID: *req.ID, Result: result})
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func decode(params json.RawMessage, v any) error {
+	if err := json.Unmarshal(params, v); err != nil {
+		return &rpcError{Code: codeInvalidParams, Message: err.Error()}
+	}
+	return nil
+}
+
+func (s *Server) replyError(id json.RawMessage, rerr *rpcError) error {
+	return writeMessage(s.out, errorResponse{JSONRPC: "2.0", ID: id, Error: rerr})
+}
+
+func (s *Server) notify(method string, params any) error {
+	return writeMessage(s.out, notification{JSONRPC: "2.0", Method: method, Params: params})
+}
+
+func (s *Server) initialize(params json.RawMessage) (any, error) {
+	return InitializeResult{
+		Capabilities: ServerCapabilities{
+			TextDocumentSync:       textDocumentSyncFull,
+			DefinitionProvider:     true,
+			HoverProvider:          true,
+			DocumentSymbolProvider: true,
+			CompletionProvider:     &CompletionOptions{TriggerCharacters: []string{"_"}},
+		},
+		ServerInfo: ServerInfo{Name: "prls"},
+	}, nil
+}
+
+func (s *Server) handleShutdown(params json.RawMessage) (any, error) {
+	s.shutdown = true
+	return nil, nil
+}
+
+// update re-analyses a document and publishes its diagnostics.
+func (s *Server) update(uri string, version int, text string) error {
+	doc := analyze(s.grammar, uri, version, text)
+	s.docs[uri] = doc
+	diags := make([]Diagnostic, 0, len(doc.Diagnostics))
+	for _, d := range doc.Diagnostics {
+		diags = append(diags, toDiagnostic(d))
+	}
+	return s.notify("textDocument/publishDiagnostics", PublishDiagnosticsParams{URI: uri, Version: version, Diagnostics: diags})
+}
+
+func (s *Server) didOpen(params json.RawMessage) (any, error) {
+	var p DidOpenTextDocumentParams
+	if err := decode(params, &p); err != nil {
+		return nil, err
+	}
+	return nil, s.update(p.TextDocument.URI, p.TextDocument.Version, p.TextDocument.Text)
+}
+
+func (s *Server) didChange(params json.RawMessage) (any, error) {
+	var p DidChangeTextDocumentParams
+	if err := decode(params, &p); err != nil {
+		return nil, err
+	}
+	if len(p.ContentChanges) == 0 {
+		return nil, nil
+	}
+	// full sync: the last change holds the whole document
+	text := p.ContentChanges[len(p.ContentChanges)-1].Text
+	return nil, s.update(p.TextDocument.URI, p.TextDocument.Version, text)
+}
+
+func (s *Server) didClose(params json.RawMessage) (any, error) {
+	var p DidCloseTextDocumentParams
+	if err := decode(params, &p); err != nil {
+		return nil, err
+	}
+	delete(s.docs, p.TextDocument.URI)
+	return nil, s.notify("textDocument/publishDiagnostics", PublishDiagnosticsParams{URI: p.TextDocument.URI, Diagnostics: []Diagnostic{}})
+}
+
+// lookup finds the declaration named at the position in params.
+func (s *Server) lookup(params json.RawMessage) (*document, ast.ASTNode, Range, error) {
+	var p TextDocumentPositionParams
+	if err := decode(params, &p); err != nil {
+		return nil, nil, Range{}, err
+	}
+	doc, ok := s.docs[p.TextDocument.URI]
+	if !ok {
+		return nil, nil, Range{}, &rpcError{Code: codeInvalidParams, Message: "unknown document " + p.TextDocument.URI}
+	}
+	decl, span, ok := doc.declarationAt(offsetOf(doc.Text, p.Position))
+	if !ok {
+		return doc, nil, Range{}, nil
+	}
+	return doc, decl, toRange(span), nil
+}
+
+func (s *Server) definition(params json.RawMessage) (any, error) {
+	doc, decl, _, err := s.lookup(params)
+	if err != nil || decl == nil {
+		return nil, err
+	}
+	tok, _ := nameToken(decl)
+	return Location{URI: doc.URI, Range: toRange(diag.SpanOf(tok))}, nil
+}
+
+func (s *Server) hover(params json.RawMessage) (any, error) {
+	_, decl, rng, err := s.lookup(params)
+	if err != nil || decl == nil {
+		return nil, err
+	}
+	return Hover{
+		Contents: MarkupContent{Kind: "markdown", Value: "```prl\n" + signature(decl) + "\n```"},
+		Range:    &rng,
+	}, nil
+}
+
+func (s *Server) documentSymbol(params json.RawMessage) (any, error) {
+	var p DocumentSymbolParams
+	if err := decode(params, &p); err != nil {
+		return nil, err
+	}
+	doc, ok := s.docs[p.TextDocument.URI]
+	if !ok {
+		return nil, &rpcError{Code: codeInvalidParams, Message: "unknown document " + p.TextDocument.URI}
+	}
+	return doc.symbols(), nil
+}
+
+var keywords = []string{
+	"let", "fun", "return", "if", "else", "while", "for", "as",
+	"and", "or", "not", "true", "false", "int", "float", "bool", "colour",
+}
+
+var builtins = []struct{ name, detail string }{
+	{"__print", "__print <expr>;"},
+	{"__delay", "__delay <ms>;"},
+	{"__write", "__write <x>, <y>, <colour>;"},
+	{"__write_box", "__write_box <x>, <y>, <w>, <h>, <colour>;"},
+	{"__clear", "__clear <colour>;"},
+	{"__random_int", "__random_int(<max>) -> int"},
+	{"__read", "__read(<x>, <y>) -> colour"},
+	{"__width", "__width -> int"},
+	{"__height", "__height -> int"},
+}
+
+// completion offers keywords, builtins and every name declared in the
+// document. Filtering by prefix is left to the client.
+func (s *Server) completion(params json.RawMessage) (any, error) {
+	var p TextDocumentPositionParams
+	if err := decode(params, &p); err != nil {
+		return nil, err
+	}
+	items := []CompletionItem{}
+	for _, kw := range keywords {
+		items = append(items, CompletionItem{Label: kw, Kind: CompletionKindKeyword})
+	}
+	for _, b := range builtins {
+		items = append(items, CompletionItem{Label: b.name, Kind: CompletionKindFunction, Detail: b.detail})
+	}
+	if doc, ok := s.docs[p.TextDocument.URI]; ok {
+		seen := map[string]bool{}
+		var names []CompletionItem
+		for _, decl := range doc.Decls {
+			tok, _ := nameToken(decl)
+			if seen[tok.Lexeme] {
+				continue
+			}
+			seen[tok.Lexeme] = true
+			kind := CompletionKindVariable
+			if _, ok := decl.(*ast.ASTFuncDeclNode); ok {
+				kind = CompletionKindFunction
+			}
+			names = append(names, CompletionItem{Label: tok.Lexeme, Kind: kind, Detail: signature(decl)})
+		}
+		sort.Slice(names, func(i, j int) bool { return names[i].Label < names[j].Label })
+		items = append(items, names...)
+	}
+	return items, nil
+}
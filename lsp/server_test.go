@@ -0,0 +1,372 @@
+package lsp
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+)
+
+const testURI = "file:///test.prl"
+
+// script is a scripted JSON-RPC client: it queues framed messages, runs
+// them through a server in one go and collects everything written back.
+type script struct {
+	in     bytes.Buffer
+	nextID int
+}
+
+func (c *script) request(method string, params any) int {
+	c.nextID++
+	c.send(map[string]any{"jsonrpc": "2.0", "id": c.nextID, "method": method, "params": params})
+	return c.nextID
+}
+
+func (c *script) notify(method string, params any) {
+	c.send(map[string]any{"jsonrpc": "2.0", "method": method, "params": params})
+}
+
+func (c *script) send(msg any) {
+	if err := writeMessage(&c.in, msg); err != nil {
+		panic(err)
+	}
+}
+
+type received struct {
+	ID     *int            `json:"id"`
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params"`
+	Result json.RawMessage `json:"result"`
+	Error  *rpcError       `json:"error"`
+}
+
+type transcript struct {
+	responses     map[int]received
+	notifications []received
+}
+
+func (c *script) run(t *testing.T) (transcript, error) {
+	t.Helper()
+	var out bytes.Buffer
+	err := NewServer().Serve(&c.in, &out)
+
+	tr := transcript{responses: map[int]received{}}
+	r := bufio.NewReader(&out)
+	for {
+		body, rerr := readMessage(r)
+		if rerr == io.EOF {
+			break
+		}
+		if rerr != nil {
+			t.Fatalf("Malformed server output: %v", rerr)
+		}
+		var msg received
+		if err := json.Unmarshal(body, &msg); err != nil {
+			t.Fatalf("Malformed server message %s: %v", body, err)
+		}
+		if msg.ID != nil {
+			tr.responses[*msg.ID] = msg
+		} else {
+			tr.notifications = append(tr.notifications, msg)
+		}
+	}
+	return tr, err
+}
+
+func (tr transcript) result(t *testing.T, id int, v any) {
+	t.Helper()
+	msg, ok := tr.responses[id]
+	if !ok {
+		t.Fatalf("No response to request %d", id)
+	}
+	if msg.Error != nil {
+		t.Fatalf("Request %d failed: %v", id, msg.Error)
+	}
+	if err := json.Unmarshal(msg.Result, v); err != nil {
+		t.Fatalf("Bad result for request %d: %v", id, err)
+	}
+}
+
+func (tr transcript) diagnostics(t *testing.T) []PublishDiagnosticsParams {
+	t.Helper()
+	var all []PublishDiagnosticsParams
+	for _, n := range tr.notifications {
+		if n.Method != "textDocument/publishDiagnostics" {
+			continue
+		}
+		var p PublishDiagnosticsParams
+		if err := json.Unmarshal(n.Params, &p); err != nil {
+			t.Fatal(err)
+		}
+		all = append(all, p)
+	}
+	return all
+}
+
+func openDocument(c *script, text string) {
+	c.request("initialize", map[string]any{"capabilities": map[string]any{}})
+	c.notify("initialized", map[string]any{})
+	c.notify("textDocument/didOpen", DidOpenTextDocumentParams{
+		TextDocument: TextDocumentItem{URI: testURI, LanguageID: "prl", Version: 1, Text: text},
+	})
+}
+
+func closeSession(c *script) {
+	c.request("shutdown", nil)
+	c.notify("exit", nil)
+}
+
+func at(line, character int) TextDocumentPositionParams {
+	return TextDocumentPositionParams{
+		TextDocument: TextDocumentIdentifier{URI: testURI},
+		Position:     Position{Line: line, Character: character},
+	}
+}
+
+const program = `fun add(a:int, b:int) -> int {
+    return a + b;
+}
+let total:int = add(1, 2);
+total = total + 1;
+__print total;
+`
+
+func TestServerLifecycle(t *testing.T) {
+	c := &script{}
+	id := c.request("initialize", map[string]any{"capabilities": map[string]any{}})
+	closeSession(c)
+	tr, err := c.run(t)
+	if err != nil {
+		t.Fatalf("Serve failed: %v", err)
+	}
+	var res InitializeResult
+	tr.result(t, id, &res)
+	caps := res.Capabilities
+	if caps.TextDocumentSync != textDocumentSyncFull || !caps.DefinitionProvider || !caps.HoverProvider || !caps.DocumentSymbolProvider || caps.CompletionProvider == nil {
+		t.Fatalf("Unexpected capabilities %+v", caps)
+	}
+}
+
+func TestServerExitWithoutShutdown(t *testing.T) {
+	c := &script{}
+	c.notify("exit", nil)
+	if _, err := c.run(t); err != ErrExitWithoutShutdown {
+		t.Fatalf("Expected ErrExitWithoutShutdown, got %v", err)
+	}
+}
+
+func TestServerUnknownMethod(t *testing.T) {
+	c := &script{}
+	id := c.request("textDocument/frobnicate", nil)
+	closeSession(c)
+	tr, _ := c.run(t)
+	if msg := tr.responses[id]; msg.Error == nil || msg.Error.Code != codeMethodNotFound {
+		t.Fatalf("Expected method not found, got %+v", msg)
+	}
+}
+
+func TestServerPublishesDiagnosticsOnChange(t *testing.T) {
+	c := &script{}
+	openDocument(c, program)
+	c.notify("textDocument/didChange", DidChangeTextDocumentParams{
+		TextDocument:   VersionedTextDocumentIdentifier{URI: testURI, Version: 2},
+		ContentChanges: []TextDocumentContentChangeEvent{{Text: "let x:int = true;\ny = 1;\n"}},
+	})
+	c.notify("textDocument/didChange", DidChangeTextDocumentParams{
+		TextDocument:   VersionedTextDocumentIdentifier{URI: testURI, Version: 3},
+		ContentChanges: []TextDocumentContentChangeEvent{{Text: "let x:int = ;\n"}},
+	})
+	closeSession(c)
+	tr, err := c.run(t)
+	if err != nil {
+		t.Fatalf("Serve failed: %v", err)
+	}
+
+	published := tr.diagnostics(t)
+	if len(published) != 3 {
+		t.Fatalf("Expected 3 diagnostic notifications, got %d", len(published))
+	}
+	if len(published[0].Diagnostics) != 0 {
+		t.Fatalf("Expected a clean program, got %+v", published[0].Diagnostics)
+	}
+
+	semantic := published[1].Diagnostics
+	if len(semantic) != 2 || semantic[0].Code != "E003" || semantic[1].Code != "E001" {
+		t.Fatalf("Expected E003 and E001, got %+v", semantic)
+	}
+	if r := semantic[1].Range; r.Start != (Position{Line: 1, Character: 0}) || r.End != (Position{Line: 1, Character: 1}) {
+		t.Fatalf("Expected the undeclared variable to be underlined, got %+v", r)
+	}
+	if semantic[0].Severity != SeverityError || semantic[0].Source != "prl" {
+		t.Fatalf("Unexpected diagnostic %+v", semantic[0])
+	}
+
+	syntax := published[2].Diagnostics
+	if len(syntax) != 1 || !strings.HasPrefix(syntax[0].Code, "S") || published[2].Version != 3 {
+		t.Fatalf("Expected one syntax error for version 3, got %+v", published[2])
+	}
+}
+
+func TestServerDefinition(t *testing.T) {
+	c := &script{}
+	openDocument(c, program)
+	callID := c.request("textDocument/definition", at(3, 17))  // add(1, 2)
+	varID := c.request("textDocument/definition", at(4, 9))    // total + 1
+	paramID := c.request("textDocument/definition", at(1, 15)) // b in a + b
+	noneID := c.request("textDocument/definition", at(3, 25))  // the literal 2
+	pastID := c.request("textDocument/definition", at(3, 30))  // past the end of the line
+	closeSession(c)
+	tr, err := c.run(t)
+	if err != nil {
+		t.Fatalf("Serve failed: %v", err)
+	}
+
+	tests := []struct {
+		id   int
+		want Range
+	}{
+		{callID, Range{Start: Position{0, 4}, End: Position{0, 7}}},
+		{varID, Range{Start: Position{3, 4}, End: Position{3, 9}}},
+		{paramID, Range{Start: Position{0, 15}, End: Position{0, 16}}},
+	}
+	for _, tt := range tests {
+		var loc Location
+		tr.result(t, tt.id, &loc)
+		if loc.URI != testURI || loc.Range != tt.want {
+			t.Errorf("Request %d: expected %+v, got %+v", tt.id, tt.want, loc)
+		}
+	}
+	if res := string(tr.responses[noneID].Result); res != "null" {
+		t.Fatalf("Expected no definition for a literal, got %s", res)
+	}
+	if res := string(tr.responses[pastID].Result); res != "null" {
+		t.Fatalf("Expected no definition past the end of a line, got %s", res)
+	}
+}
+
+func TestServerChecksPartialTree(t *testing.T) {
+	c := &script{}
+	openDocument(c, "let x:int = true;\nlet y:int = ;\n__print z;\nlet w:int = x;\n")
+	defID := c.request("textDocument/definition", at(3, 12)) // x in w's initialiser
+	closeSession(c)
+	tr, err := c.run(t)
+	if err != nil {
+		t.Fatalf("Serve failed: %v", err)
+	}
+
+	published := tr.diagnostics(t)
+	if len(published) != 1 {
+		t.Fatalf("Expected 1 diagnostic notification, got %d", len(published))
+	}
+	// w is never read, but warnings are not reported after syntax errors
+	var codes []string
+	for _, d := range published[0].Diagnostics {
+		codes = append(codes, d.Code)
+	}
+	if len(codes) != 3 || codes[0] != "E003" || !strings.HasPrefix(codes[1], "S") || codes[2] != "E001" {
+		t.Fatalf("Expected E003, a syntax error and E001, got %v", codes)
+	}
+	var loc Location
+	tr.result(t, defID, &loc)
+	if loc.Range != (Range{Start: Position{0, 4}, End: Position{0, 5}}) {
+		t.Fatalf("Expected x's declaration, got %+v", loc)
+	}
+}
+
+func TestServerHover(t *testing.T) {
+	c := &script{}
+	openDocument(c, program)
+	funcID := c.request("textDocument/hover", at(3, 18))
+	varID := c.request("textDocument/hover", at(4, 0))
+	closeSession(c)
+	tr, err := c.run(t)
+	if err != nil {
+		t.Fatalf("Serve failed: %v", err)
+	}
+
+	var hover Hover
+	tr.result(t, funcID, &hover)
+	if !strings.Contains(hover.Contents.Value, "fun add(a: int, b: int) -> int") {
+		t.Fatalf("Unexpected hover %q", hover.Contents.Value)
+	}
+	tr.result(t, varID, &hover)
+	if !strings.Contains(hover.Contents.Value, "let total: int") {
+		t.Fatalf("Unexpected hover %q", hover.Contents.Value)
+	}
+	if hover.Range == nil || *hover.Range != (Range{Start: Position{4, 0}, End: Position{4, 5}}) {
+		t.Fatalf("Unexpected hover range %+v", hover.Range)
+	}
+}
+
+func TestServerDocumentSymbols(t *testing.T) {
+	c := &script{}
+	openDocument(c, program)
+	id := c.request("textDocument/documentSymbol", DocumentSymbolParams{TextDocument: TextDocumentIdentifier{URI: testURI}})
+	closeSession(c)
+	tr, err := c.run(t)
+	if err != nil {
+		t.Fatalf("Serve failed: %v", err)
+	}
+
+	var syms []DocumentSymbol
+	tr.result(t, id, &syms)
+	got := describe(syms)
+	want := "add(function)[a(variable) b(variable)] total(variable)"
+	if got != want {
+		t.Fatalf("Expected symbols %q, got %q", want, got)
+	}
+	if syms[0].Range.Start != (Position{0, 0}) || syms[0].Range.End != (Position{2, 1}) {
+		t.Fatalf("Expected the function to span its body, got %+v", syms[0].Range)
+	}
+}
+
+func describe(syms []DocumentSymbol) string {
+	var parts []string
+	for _, s := range syms {
+		kind := "variable"
+		if s.Kind == SymbolKindFunction {
+			kind = "function"
+		}
+		part := fmt.Sprintf("%s(%s)", s.Name, kind)
+		if len(s.Children) > 0 {
+			part += "[" + describe(s.Children) + "]"
+		}
+		parts = append(parts, part)
+	}
+	return strings.Join(parts, " ")
+}
+
+func TestServerCompletion(t *testing.T) {
+	c := &script{}
+	openDocument(c, program)
+	id := c.request("textDocument/completion", at(5, 0))
+	closeSession(c)
+	tr, err := c.run(t)
+	if err != nil {
+		t.Fatalf("Serve failed: %v", err)
+	}
+
+	var items []CompletionItem
+	tr.result(t, id, &items)
+	kinds := map[string]CompletionItemKind{}
+	for _, item := range items {
+		kinds[item.Label] = item.Kind
+	}
+	expected := map[string]CompletionItemKind{
+		"let":          CompletionKindKeyword,
+		"while":        CompletionKindKeyword,
+		"__write":      CompletionKindFunction,
+		"__random_int": CompletionKindFunction,
+		"__read":       CompletionKindFunction,
+		"add":          CompletionKindFunction,
+		"total":        CompletionKindVariable,
+	}
+	for label, kind := range expected {
+		if got, ok := kinds[label]; !ok || got != kind {
+			t.Errorf("Expected completion %q of kind %d, got %d (present: %v)", label, kind, got, ok)
+		}
+	}
+}
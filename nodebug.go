@@ -0,0 +1,5 @@
+//go:build !debug
+
+package compiler
+
+const debug = false
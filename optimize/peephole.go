@@ -0,0 +1,559 @@
+// Package optimize rewrites generated PArIR into shorter, equivalent code.
+package optimize
+
+import (
+	"math"
+
+	"github.com/giuszeppe/compiler-theory/parir"
+	"github.com/giuszeppe/compiler-theory/vm"
+)
+
+// Peephole returns instrs optimised at the given level:
+//
+//   - 0 returns instrs unchanged.
+//   - 1 folds arithmetic on constants, removes frames and allocations of
+//     size zero, jumps to the next instruction and conditional jumps on
+//     constants, and cancels values that are pushed and immediately dropped
+//     or stored back where they were loaded from.
+//   - 2 also threads jumps to jumps and removes unreachable code, such as
+//     functions that are never called.
+//
+// Removing code moves instructions, so every jump address, relative (#PC+n,
+// #PC-n) or absolute, is recomputed afterwards. Code whose control flow
+// cannot be followed statically is returned unchanged.
+func Peephole(instrs []parir.Instruction, level int) []parir.Instruction {
+	if level <= 0 {
+		return instrs
+	}
+	p, ok := resolve(instrs)
+	if !ok {
+		return instrs
+	}
+
+	passes := []func(*program) bool{
+		(*program).foldConstants,
+		(*program).removeEmptyAllocs,
+		(*program).removeEmptyFrames,
+		(*program).removeDeadJumps,
+		(*program).removeDeadPushes,
+	}
+	if level >= 2 {
+		passes = append(passes, (*program).threadJumps, (*program).removeUnreachable)
+	}
+	// every pass either shortens the code or leaves it alone, except jump
+	// threading which cannot undo itself, so this terminates
+	for changed := true; changed; {
+		changed = false
+		for _, pass := range passes {
+			if pass(p) {
+				changed = true
+			}
+		}
+	}
+	return p.assemble()
+}
+
+// instr is an instruction being optimised. A push of a jump address points
+// at the target instruction instead of holding an offset, so that
+// instructions can be removed without breaking control flow.
+type instr struct {
+	parir.Instruction
+	target   *instr
+	absolute bool // the address is written "push N" rather than "push #PC+n"
+}
+
+type program struct {
+	code []*instr
+	// end stands after the last instruction. Jumps to removed code at the
+	// end of the program go there; it halts, just as running off the end
+	// of the program does.
+	end *instr
+}
+
+// resolve points each jump address in instrs at its target. It fails if a
+// jump does not take its address from the push before it.
+func resolve(instrs []parir.Instruction) (*program, bool) {
+	p := &program{end: &instr{Instruction: parir.Instruction{Op: parir.OpHalt}}}
+	for _, in := range instrs {
+		p.code = append(p.code, &instr{Instruction: in})
+	}
+	for i, in := range p.code {
+		if in.Op != parir.OpJmp && in.Op != parir.OpCJmp {
+			continue
+		}
+		if i == 0 || p.code[i-1].Op != parir.OpPush {
+			return nil, false
+		}
+		addr := p.code[i-1]
+		var target int
+		switch addr.Arg.Kind {
+		case parir.KindPC:
+			target = i - 1 + addr.Arg.Int
+		case parir.KindInt:
+			target = addr.Arg.Int
+			addr.absolute = true
+		default:
+			return nil, false
+		}
+		if target < 0 || target >= len(p.code) {
+			return nil, false
+		}
+		addr.target = p.code[target]
+	}
+	p.code = append(p.code, p.end)
+	return p, true
+}
+
+// assemble returns the program's instructions, recomputing every jump
+// address.
+func (p *program) assemble() []parir.Instruction {
+	code := p.code
+	if len(code) > 0 && code[len(code)-1] == p.end && !p.targets()[p.end] {
+		code = code[:len(code)-1]
+	}
+	pos := positions(code)
+	out := make([]parir.Instruction, len(code))
+	for i, in := range code {
+		switch {
+		case in.target == nil:
+		case in.absolute:
+			in.Arg = parir.Int(pos[in.target])
+		default:
+			in.Arg = parir.PC(pos[in.target] - i)
+		}
+		out[i] = in.Instruction
+	}
+	return out
+}
+
+func positions(code []*instr) map[*instr]int {
+	pos := make(map[*instr]int, len(code))
+	for i, in := range code {
+		pos[in] = i
+	}
+	return pos
+}
+
+// targets returns the instructions control can reach other than by falling
+// through: jump targets and labels.
+func (p *program) targets() map[*instr]bool {
+	t := make(map[*instr]bool)
+	for _, in := range p.code {
+		if in.target != nil {
+			t[in.target] = true
+		}
+		if in.Op == parir.OpLabel {
+			t[in] = true
+		}
+	}
+	return t
+}
+
+// remove deletes the instructions in dead. Jumps to a deleted instruction go
+// to the next one kept instead.
+func (p *program) remove(dead map[*instr]bool) bool {
+	if len(dead) == 0 {
+		return false
+	}
+	next := make(map[*instr]*instr)
+	following := p.end
+	for i := len(p.code) - 1; i >= 0; i-- {
+		if dead[p.code[i]] {
+			next[p.code[i]] = following
+		} else {
+			following = p.code[i]
+		}
+	}
+	kept := make([]*instr, 0, len(p.code))
+	for _, in := range p.code {
+		if dead[in] {
+			continue
+		}
+		if in.target != nil && dead[in.target] {
+			in.target = next[in.target]
+		}
+		kept = append(kept, in)
+	}
+	if kept[len(kept)-1] != p.end {
+		kept = append(kept, p.end)
+	}
+	p.code = kept
+	return true
+}
+
+// literal returns the value in pushes if it pushes a constant.
+func literal(in *instr) (vm.Value, bool) {
+	if in.Op != parir.OpPush || in.target != nil {
+		return 0, false
+	}
+	n, ok := in.Arg.Number()
+	return vm.Value(n), ok
+}
+
+// number returns the operand that pushes val, an int where possible.
+func number(val vm.Value) parir.Operand {
+	if f := float64(val); f == math.Trunc(f) && math.Abs(f) <= math.MaxInt32 {
+		return parir.Int(int(f))
+	}
+	return parir.Float(float64(val))
+}
+
+// isPush reports whether in is "push n".
+func isPush(in *instr, n int) bool {
+	return in.Op == parir.OpPush && in.target == nil && in.Arg == parir.Int(n)
+}
+
+// successors returns the positions control can pass to from code[i]. Calls
+// are assumed to return. It fails for a jump whose target is unknown.
+func (p *program) successors(i int, pos map[*instr]int) ([]int, bool) {
+	var succ []int
+	switch p.code[i].Op {
+	case parir.OpRet, parir.OpRetA, parir.OpHalt:
+		return nil, true
+	case parir.OpJmp, parir.OpCJmp:
+		if i == 0 || p.code[i-1].target == nil {
+			return nil, false
+		}
+		succ = append(succ, pos[p.code[i-1].target])
+		if p.code[i].Op == parir.OpJmp {
+			return succ, true
+		}
+	}
+	if i+1 < len(p.code) {
+		succ = append(succ, i+1)
+	}
+	return succ, true
+}
+
+// foldConstants evaluates operations on constants, as in "push 2; push 3;
+// add" or the "push 0; sub" of a negated literal, at compile time.
+func (p *program) foldConstants() bool {
+	code, targets := p.code, p.targets()
+	dead := make(map[*instr]bool)
+	for i := 0; i+1 < len(code); i++ {
+		a, ok := literal(code[i])
+		if !ok || targets[code[i+1]] {
+			continue
+		}
+		if val, err := vm.UnaryOp(code[i+1].Op, a); err == nil {
+			code[i].Arg = number(val)
+			dead[code[i+1]] = true
+			i++
+			continue
+		}
+		if i+2 >= len(code) || targets[code[i+2]] {
+			continue
+		}
+		b, ok := literal(code[i+1])
+		if !ok {
+			continue
+		}
+		// the value pushed last is popped first; division by zero is left
+		// to fail at run time
+		if val, err := vm.BinaryOp(code[i+2].Op, b, a); err == nil {
+			code[i].Arg = number(val)
+			dead[code[i+1]], dead[code[i+2]] = true, true
+			i += 2
+		}
+	}
+	return p.remove(dead)
+}
+
+// removeEmptyAllocs removes "push 0; alloc" from functions without locals.
+func (p *program) removeEmptyAllocs() bool {
+	code, targets := p.code, p.targets()
+	dead := make(map[*instr]bool)
+	for i := 0; i+1 < len(code); i++ {
+		if code[i+1].Op == parir.OpAlloc && isPush(code[i], 0) && !targets[code[i+1]] {
+			dead[code[i]], dead[code[i+1]] = true, true
+		}
+	}
+	return p.remove(dead)
+}
+
+// removeEmptyFrames removes a "push 0; oframe" and the cframes closing it.
+// Frame levels that reach past the removed frame, in [i:l] operands and in
+// the level pushed for st and sta, are lowered by one. A frame is only
+// removed if the code it covers is entered through the oframe alone, closes
+// the frame on every path and makes no calls, since a callee would see the
+// frame stack change under it.
+func (p *program) removeEmptyFrames() bool {
+	pos := positions(p.code)
+	preds, ok := p.predecessors(pos)
+	if !ok {
+		return false
+	}
+	for i := 0; i+2 < len(p.code); i++ {
+		if !isPush(p.code[i], 0) || p.code[i+1].Op != parir.OpOFrame || len(preds[i+1]) != 1 {
+			continue
+		}
+		if p.removeFrame(i, pos, preds) {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *program) removeFrame(at int, pos map[*instr]int, preds map[int][]int) bool {
+	start := at + 2
+	depth := map[int]int{start: 1}
+	var closers []int
+	work := []int{start}
+	for len(work) > 0 {
+		i := work[len(work)-1]
+		work = work[:len(work)-1]
+		d := depth[i]
+		switch p.code[i].Op {
+		case parir.OpCFrame:
+			if d == 1 {
+				closers = append(closers, i)
+				continue
+			}
+			d--
+		case parir.OpOFrame:
+			d++
+		case parir.OpAlloc:
+			if d == 1 {
+				return false
+			}
+		case parir.OpCall, parir.OpRet, parir.OpRetA:
+			return false
+		}
+		succ, ok := p.successors(i, pos)
+		if !ok || (len(succ) == 0 && p.code[i].Op != parir.OpHalt) {
+			return false
+		}
+		for _, s := range succ {
+			if s < start && s >= at {
+				return false
+			}
+			if ds, seen := depth[s]; seen {
+				if ds != d {
+					return false
+				}
+				continue
+			}
+			depth[s] = d
+			work = append(work, s)
+		}
+	}
+
+	// the frame must only be entered through its oframe
+	for i := range depth {
+		for _, q := range preds[i] {
+			if _, inside := depth[q]; !inside && q != at+1 {
+				return false
+			}
+		}
+	}
+
+	// a reference from depth d at level l is to the frame opened d-l frames
+	// after the removed one; one at or below 0 is to an enclosing frame
+	type fix struct {
+		in  *instr
+		arg parir.Operand
+	}
+	var fixes []fix
+	targets := p.targets()
+	for i, d := range depth {
+		in := p.code[i]
+		switch in.Op {
+		case parir.OpPush, parir.OpPushA:
+			if in.Arg.Kind != parir.KindSlot && in.Arg.Kind != parir.KindIndexedSlot {
+				continue
+			}
+			switch level := in.Arg.Level; {
+			case d-level == 1:
+				return false
+			case d-level < 1:
+				arg := in.Arg
+				arg.Level--
+				fixes = append(fixes, fix{in, arg})
+			}
+		case parir.OpSt, parir.OpStA:
+			if _, inside := depth[i-1]; !inside || targets[in] {
+				return false
+			}
+			val, ok := literal(p.code[i-1])
+			level := int(val)
+			if !ok || vm.Value(level) != val || d-level == 1 {
+				return false
+			}
+			if d-level < 1 {
+				fixes = append(fixes, fix{p.code[i-1], parir.Int(level - 1)})
+			}
+		}
+	}
+
+	for _, f := range fixes {
+		f.in.Arg = f.arg
+	}
+	dead := map[*instr]bool{p.code[at]: true, p.code[at+1]: true}
+	for _, i := range closers {
+		dead[p.code[i]] = true
+	}
+	return p.remove(dead)
+}
+
+// predecessors maps each position to the positions control can reach it
+// from. Entry points, the first instruction and labels, have -1 among them.
+func (p *program) predecessors(pos map[*instr]int) (map[int][]int, bool) {
+	preds := map[int][]int{0: {-1}}
+	for i, in := range p.code {
+		if in.Op == parir.OpLabel {
+			preds[i] = append(preds[i], -1)
+		}
+		succ, ok := p.successors(i, pos)
+		if !ok {
+			return nil, false
+		}
+		for _, s := range succ {
+			preds[s] = append(preds[s], i)
+		}
+	}
+	return preds, true
+}
+
+// removeDeadJumps removes jumps to the next instruction and resolves
+// conditional jumps on a constant.
+func (p *program) removeDeadJumps() bool {
+	code, targets := p.code, p.targets()
+	dead := make(map[*instr]bool)
+	for i := 0; i+2 < len(code); i++ {
+		addr, jump, next := code[i], code[i+1], code[i+2]
+		if addr.target == nil || targets[jump] {
+			continue
+		}
+		switch {
+		case jump.Op == parir.OpJmp && addr.target == next:
+			dead[addr], dead[jump] = true, true
+		case jump.Op == parir.OpCJmp && addr.target == next:
+			// the condition must still be popped
+			addr.Instruction, addr.target = parir.Instruction{Op: parir.OpDrop}, nil
+			dead[jump] = true
+		case jump.Op == parir.OpCJmp && i > 0 && !targets[addr] && !dead[code[i-1]]:
+			cond, ok := literal(code[i-1])
+			if !ok {
+				continue
+			}
+			dead[code[i-1]] = true
+			if cond != 0 {
+				jump.Op = parir.OpJmp
+			} else {
+				dead[addr], dead[jump] = true, true
+			}
+		}
+		i++
+	}
+	return p.remove(dead)
+}
+
+// removeDeadPushes cancels a value that is pushed and dropped straight away,
+// and a load that is stored straight back: "push [i:l]; push i; push l; st".
+func (p *program) removeDeadPushes() bool {
+	code, targets := p.code, p.targets()
+	dead := make(map[*instr]bool)
+	for i := 0; i+1 < len(code); i++ {
+		a, b := code[i], code[i+1]
+		if targets[b] {
+			continue
+		}
+		if b.Op == parir.OpDrop && (pure(a) || a.Op == parir.OpDup) {
+			dead[a], dead[b] = true, true
+			i++
+			continue
+		}
+		if i+3 < len(code) && isStoreBack(code[i:i+4]) && !targets[code[i+2]] && !targets[code[i+3]] {
+			for _, in := range code[i : i+4] {
+				dead[in] = true
+			}
+			i += 3
+		}
+	}
+	return p.remove(dead)
+}
+
+// pure reports whether in only pushes a value, with no other effect.
+func pure(in *instr) bool {
+	switch in.Op {
+	case parir.OpPush:
+		return in.target == nil && in.Arg.Kind != parir.KindIndexedSlot
+	case parir.OpWidth, parir.OpHeight:
+		return true
+	}
+	return false
+}
+
+func isStoreBack(code []*instr) bool {
+	if code[0].Op != parir.OpPush || code[0].Arg.Kind != parir.KindSlot || code[3].Op != parir.OpSt {
+		return false
+	}
+	return isPush(code[1], code[0].Arg.Index) && isPush(code[2], code[0].Arg.Level)
+}
+
+// threadJumps sends a jump to an unconditional jump straight to its target.
+func (p *program) threadJumps() bool {
+	pos := positions(p.code)
+	changed := false
+	for _, in := range p.code {
+		if in.target == nil {
+			continue
+		}
+		target := in.target
+		seen := map[*instr]bool{}
+		for !seen[target] {
+			seen[target] = true
+			i := pos[target]
+			if target.target == nil || i+1 >= len(p.code) || p.code[i+1].Op != parir.OpJmp {
+				break
+			}
+			target = target.target
+		}
+		if target != in.target {
+			in.target = target
+			changed = true
+		}
+	}
+	return changed
+}
+
+// removeUnreachable removes code that no path from the first instruction
+// reaches, such as the bodies of functions that are never called.
+func (p *program) removeUnreachable() bool {
+	pos := positions(p.code)
+	labels := make(map[string]int)
+	for i, in := range p.code {
+		if in.Op == parir.OpLabel {
+			labels[in.Arg.Name] = i
+		}
+	}
+	reached := make(map[int]bool)
+	work := []int{0}
+	reached[0] = true
+	for len(work) > 0 {
+		i := work[len(work)-1]
+		work = work[:len(work)-1]
+		succ, ok := p.successors(i, pos)
+		if !ok {
+			return false
+		}
+		if in := p.code[i]; in.Op == parir.OpPush && in.Arg.Kind == parir.KindLabel {
+			if l, ok := labels[in.Arg.Name]; ok {
+				succ = append(succ, l)
+			}
+		}
+		for _, s := range succ {
+			if !reached[s] {
+				reached[s] = true
+				work = append(work, s)
+			}
+		}
+	}
+	dead := make(map[*instr]bool)
+	for i, in := range p.code {
+		if !reached[i] && in != p.end {
+			dead[in] = true
+		}
+	}
+	return p.remove(dead)
+}
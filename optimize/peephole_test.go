@@ -0,0 +1,239 @@
+package optimize
+
+import (
+	"bytes"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/giuszeppe/compiler-theory/codegen"
+	"github.com/giuszeppe/compiler-theory/diag"
+	"github.com/giuszeppe/compiler-theory/ir"
+	"github.com/giuszeppe/compiler-theory/parir"
+	"github.com/giuszeppe/compiler-theory/parser"
+	"github.com/giuszeppe/compiler-theory/sema"
+	"github.com/giuszeppe/compiler-theory/vm"
+)
+
+func TestPeephole(t *testing.T) {
+	tests := []struct {
+		name  string
+		level int
+		in    string
+		want  string
+	}{
+		{
+			"level 0 is a no-op",
+			0,
+			"push 0; push 5; sub; print",
+			"push 0; push 5; sub; print",
+		},
+		{
+			"unary minus",
+			1,
+			"push 5; push 0; sub; print",
+			"push -5; print",
+		},
+		{
+			"constant arithmetic",
+			1,
+			"push 3; push 2; push 4; mul; add; not; print",
+			"push 0; print",
+		},
+		{
+			"division by zero is kept",
+			1,
+			"push 0; push 1; div; print",
+			"push 0; push 1; div; print",
+		},
+		{
+			"empty allocation",
+			1,
+			".f; push 0; alloc; push 1; ret",
+			".f; push 1; ret",
+		},
+		{
+			"empty frame lowers outer levels",
+			1,
+			"push 1; oframe; push 0; oframe; push [0:1]; print; push 7; push 0; push 1; st; cframe; cframe",
+			"push 1; oframe; push [0:0]; print; push 7; push 0; push 0; st; cframe",
+		},
+		{
+			"frame with locals is kept",
+			1,
+			"push 1; oframe; push 0; oframe; push 1; oframe; push [0:0]; print; cframe; cframe; cframe",
+			"push 1; oframe; push 1; oframe; push [0:0]; print; cframe; cframe",
+		},
+		{
+			"frame around a call is kept",
+			1,
+			"push 0; oframe; push 0; push .f; call; cframe; halt; .f; push 0; ret",
+			"push 0; oframe; push 0; push .f; call; cframe; halt; .f; push 0; ret",
+		},
+		{
+			"jump to next",
+			1,
+			"push #PC+2; jmp; push 1; print",
+			"push 1; print",
+		},
+		{
+			"conditional jump on a constant",
+			1,
+			"push 0; push #PC+4; cjmp; push 1; print; push 2; print",
+			"push 1; print; push 2; print",
+		},
+		{
+			"conditional jump to next",
+			1,
+			"push [0:0]; push #PC+2; cjmp; push 1; print",
+			"push 1; print",
+		},
+		{
+			"offsets are recomputed",
+			1,
+			"push [0:0]; push #PC+6; cjmp; push 0; push 1; add; print; push 2; print; push #PC-9; jmp",
+			"push [0:0]; push #PC+4; cjmp; push 1; print; push 2; print; push #PC-7; jmp",
+		},
+		{
+			"absolute back edge",
+			1,
+			"push 0; push 1; add; print; push 1; push 1; add; print; push 4; jmp",
+			"push 1; print; push 2; print; push 2; jmp",
+		},
+		{
+			"store back",
+			1,
+			"push [1:0]; push 1; push 0; st; push [1:0]; push 2; push 0; st",
+			"push [1:0]; push 2; push 0; st",
+		},
+		{
+			"jump target is not folded",
+			1,
+			"push [0:0]; push #PC+4; cjmp; push 1; push 2; add; print",
+			"push [0:0]; push #PC+4; cjmp; push 1; push 2; add; print",
+		},
+		{
+			"jump threading",
+			2,
+			"push [0:0]; push #PC+5; cjmp; push 1; print; halt; push #PC+3; jmp; halt; push 2; print",
+			"push [0:0]; push #PC+5; cjmp; push 1; print; halt; push 2; print",
+		},
+		{
+			"unreachable code",
+			2,
+			".main; push #PC+3; jmp; halt; push #PC+5; jmp; .f; push 0; ret; push 1; print",
+			".main; push 1; print",
+		},
+		{
+			"computed jump is left alone",
+			2,
+			"push [0:0]; jmp; halt; push 1; push 2; add",
+			"push [0:0]; jmp; halt; push 1; push 2; add",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Peephole(parse(t, tt.in), tt.level)
+			if want := parse(t, tt.want); !reflect.DeepEqual(got, want) {
+				t.Fatalf("Expected:\n%s\ngot:\n%s", listing(want), listing(got))
+			}
+		})
+	}
+}
+
+// parse reads a program written one instruction after another, separated
+// by "; ".
+func parse(t *testing.T, src string) []parir.Instruction {
+	t.Helper()
+	prog, err := parir.Parse(strings.ReplaceAll(src, "; ", "\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return prog
+}
+
+func listing(prog []parir.Instruction) string {
+	var b strings.Builder
+	parir.Print(&b, prog)
+	return b.String()
+}
+
+// run compiles and runs src at the given level and returns what it printed
+// and drew.
+func run(t *testing.T, src string, level int) (string, []int, error) {
+	t.Helper()
+	p := parser.NewParser(src)
+	node, err := p.Parse(parser.NewGrammar())
+	if err != nil {
+		t.Skipf("Program does not parse: %v", err)
+	}
+	if diags := sema.NewSemanticVisitor().Analyze(node); diag.HasErrors(diags) {
+		t.Skipf("Program does not check: %v", diags)
+	}
+	instrs := Peephole(codegen.Generate(ir.Build(node)), level)
+	if errs := parir.Verify(instrs); len(errs) != 0 {
+		t.Fatalf("Invalid code at -O%d: %v\n%s", level, errs, listing(instrs))
+	}
+
+	machine, err := vm.NewVM(instrs)
+	if err != nil {
+		t.Fatalf("Failed to load program at -O%d: %v", level, err)
+	}
+	var out bytes.Buffer
+	machine.Out = &out
+	machine.Rand = rand.New(rand.NewSource(1))
+	machine.MaxSteps = 1000000
+	err = machine.Run()
+	return out.String(), machine.Pad.Pixels, err
+}
+
+// TestPeepholePreservesBehaviour runs programs at every level and checks
+// they print and draw the same.
+func TestPeepholePreservesBehaviour(t *testing.T) {
+	programs := map[string]string{
+		"arithmetic": `let x:int = 7; let y:int = x * 2 - 3; __print y; __print -x; __print -(2 + 3) * 4;`,
+		"calls":      `fun add(a:int, b:int) -> int { return a + b; } let z:int = add(3, 4); __print z;`,
+		"returns":    `fun a() -> int { if (1 < 2) { return 0; } return 1; } let b:int = a(); __print b;`,
+		"loops": `let n:int = 0;
+			for (let i:int = 0; i < 3; i = i + 1) { while (n < i) { n = n + 1; { __print n; } } }
+			let j:int = 0; while (j < 2) { j = j + 1; } __print j;`,
+		"nested frames": `let x:int = 1;
+			if (x > 0) { let y:int = x + 2; while (x < y) { if (x > 1) { __print y; } x = x + 1; } }
+			__print x;`,
+		"arrays": `let xs:int[] = [4, 5, 6]; xs[1] = 9; __print xs[1]; __print xs;`,
+		"pad":    `__clear #000011; for (let i:int = 0; i < 4; i = i + 1) { __write i, i, #ff0000; } __write_box 3, 3, 2, 2, #00ff00;`,
+		"unused": `fun f() -> int { return 1; } if (false) { __print 1; } __print 2;`,
+	}
+	paths, _ := filepath.Glob("../examples/*.prl")
+	for _, path := range paths {
+		src, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		programs[filepath.Base(path)] = string(src)
+	}
+
+	for name, src := range programs {
+		t.Run(name, func(t *testing.T) {
+			wantOut, wantPad, err := run(t, src, 0)
+			if err != nil {
+				t.Skipf("Program fails without optimisation: %v", err)
+			}
+			for level := 1; level <= 2; level++ {
+				out, pad, err := run(t, src, level)
+				if err != nil {
+					t.Fatalf("-O%d failed: %v", level, err)
+				}
+				if out != wantOut {
+					t.Fatalf("-O%d printed %q, want %q", level, out, wantOut)
+				}
+				if !reflect.DeepEqual(pad, wantPad) {
+					t.Fatalf("-O%d drew a different pad", level)
+				}
+			}
+		})
+	}
+}
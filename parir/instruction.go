@@ -0,0 +1,250 @@
+// Package parir models PArIR, the stack machine code the compiler
+// generates and the VM runs.
+//
+// A program is a slice of Instructions. Each has an opcode and at most one
+// typed operand; String prints an instruction in PArIR text syntax and
+// Parse reads that syntax back.
+package parir
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Op is a PArIR opcode.
+type Op uint8
+
+const (
+	OpNop Op = iota
+
+	// Operand stack and memory
+	OpPush  // push an operand, see Kind
+	OpPushA // pop n, push the n values from a frame slot onwards
+	OpSt    // pop l, i and a value, store the value at [i:l]
+	OpStA   // pop l, i, n and n values, store them from [i:l] onwards
+	OpDrop
+	OpDup
+
+	// Frames
+	OpOFrame // pop n, open a frame of n slots
+	OpCFrame // close the top frame
+	OpAlloc  // pop n, add n slots to the top frame
+
+	// Control flow
+	OpJmp  // pop an address and jump to it
+	OpCJmp // pop an address and a condition, jump if the condition holds
+	OpCall // pop an address, n and n arguments, open a frame and jump
+	OpRet
+	OpRetA // as ret, leaving an array on the operand stack
+	OpHalt
+
+	// Arithmetic and logic
+	OpAdd
+	OpSub
+	OpMul
+	OpDiv
+	OpMod
+	OpMax
+	OpMin
+	OpLt
+	OpLe
+	OpGt
+	OpGe
+	OpEq
+	OpAnd
+	OpOr
+	OpNot
+	OpInc
+	OpDec
+	OpIRnd
+
+	// Output and pad
+	OpPrint
+	OpPrintA
+	OpDelay
+	OpWidth
+	OpHeight
+	OpClear
+	OpWrite
+	OpWriteBox
+	OpRead
+
+	// OpLabel declares the label named by its operand, written .name. It
+	// does nothing when run.
+	OpLabel
+	// OpMark places the assembler label named by its operand, written
+	// @name. Marks are removed when code is assembled and never run.
+	OpMark
+)
+
+var opNames = [...]string{
+	OpNop:      "nop",
+	OpPush:     "push",
+	OpPushA:    "pusha",
+	OpSt:       "st",
+	OpStA:      "sta",
+	OpDrop:     "drop",
+	OpDup:      "dup",
+	OpOFrame:   "oframe",
+	OpCFrame:   "cframe",
+	OpAlloc:    "alloc",
+	OpJmp:      "jmp",
+	OpCJmp:     "cjmp",
+	OpCall:     "call",
+	OpRet:      "ret",
+	OpRetA:     "reta",
+	OpHalt:     "halt",
+	OpAdd:      "add",
+	OpSub:      "sub",
+	OpMul:      "mul",
+	OpDiv:      "div",
+	OpMod:      "mod",
+	OpMax:      "max",
+	OpMin:      "min",
+	OpLt:       "lt",
+	OpLe:       "le",
+	OpGt:       "gt",
+	OpGe:       "ge",
+	OpEq:       "eq",
+	OpAnd:      "and",
+	OpOr:       "or",
+	OpNot:      "not",
+	OpInc:      "inc",
+	OpDec:      "dec",
+	OpIRnd:     "irnd",
+	OpPrint:    "print",
+	OpPrintA:   "printa",
+	OpDelay:    "delay",
+	OpWidth:    "width",
+	OpHeight:   "height",
+	OpClear:    "clear",
+	OpWrite:    "write",
+	OpWriteBox: "writebox",
+	OpRead:     "read",
+	OpLabel:    ".",
+	OpMark:     "@",
+}
+
+func (op Op) String() string {
+	if int(op) < len(opNames) {
+		return opNames[op]
+	}
+	return fmt.Sprintf("Op(%d)", op)
+}
+
+// opsByName maps mnemonics back to opcodes. Labels and marks are written
+// with their operand and have no mnemonic.
+var opsByName = func() map[string]Op {
+	m := make(map[string]Op, len(opNames))
+	for op, name := range opNames {
+		if Op(op) != OpLabel && Op(op) != OpMark {
+			m[name] = Op(op)
+		}
+	}
+	return m
+}()
+
+// Kind is the type of an Operand.
+type Kind uint8
+
+const (
+	KindNone        Kind = iota
+	KindInt              // 5
+	KindFloat            // 1.5
+	KindColour           // #ff0000
+	KindSlot             // [i:l], slot i of the frame l levels down
+	KindIndexedSlot      // +[i:l], offset by a value popped from the stack
+	KindPC               // #PC+n or #PC-n, relative to the instruction
+	KindLabel            // .name
+	KindLocal            // @name, resolved to #PC+n by the assembler
+)
+
+// Operand is the argument of an instruction.
+type Operand struct {
+	Kind Kind
+	// Int is the value of an int or colour, and the offset of a PC
+	// address.
+	Int   int
+	Float float64
+	// Index and Level locate a frame slot.
+	Index, Level int
+	// Name names a label.
+	Name string
+}
+
+// Operand constructors, one per kind.
+func Int(n int) Operand            { return Operand{Kind: KindInt, Int: n} }
+func Float(f float64) Operand      { return Operand{Kind: KindFloat, Float: f} }
+func Colour(c int) Operand         { return Operand{Kind: KindColour, Int: c} }
+func Slot(i, l int) Operand        { return Operand{Kind: KindSlot, Index: i, Level: l} }
+func IndexedSlot(i, l int) Operand { return Operand{Kind: KindIndexedSlot, Index: i, Level: l} }
+func PC(offset int) Operand        { return Operand{Kind: KindPC, Int: offset} }
+func Label(name string) Operand    { return Operand{Kind: KindLabel, Name: name} }
+func Local(name string) Operand    { return Operand{Kind: KindLocal, Name: name} }
+
+// Number returns the value of an int, float or colour operand.
+func (o Operand) Number() (float64, bool) {
+	switch o.Kind {
+	case KindInt, KindColour:
+		return float64(o.Int), true
+	case KindFloat:
+		return o.Float, true
+	}
+	return 0, false
+}
+
+func (o Operand) String() string {
+	switch o.Kind {
+	case KindInt:
+		return strconv.Itoa(o.Int)
+	case KindFloat:
+		s := strconv.FormatFloat(o.Float, 'f', -1, 64)
+		if !strings.ContainsAny(s, ".IN") {
+			s += ".0" // keep it a float when read back
+		}
+		return s
+	case KindColour:
+		return fmt.Sprintf("#%06x", o.Int)
+	case KindSlot:
+		return fmt.Sprintf("[%d:%d]", o.Index, o.Level)
+	case KindIndexedSlot:
+		return fmt.Sprintf("+[%d:%d]", o.Index, o.Level)
+	case KindPC:
+		return fmt.Sprintf("#PC%+d", o.Int)
+	case KindLabel:
+		return "." + o.Name
+	case KindLocal:
+		return "@" + o.Name
+	}
+	return ""
+}
+
+// Instruction is a single PArIR instruction.
+type Instruction struct {
+	Op  Op
+	Arg Operand
+}
+
+// String prints in in PArIR text syntax.
+func (in Instruction) String() string {
+	switch {
+	case in.Op == OpLabel || in.Op == OpMark:
+		return in.Arg.String()
+	case in.Arg.Kind == KindNone:
+		return in.Op.String()
+	}
+	return in.Op.String() + " " + in.Arg.String()
+}
+
+// Print writes prog to w, one instruction per line.
+func Print(w io.Writer, prog []Instruction) error {
+	var b strings.Builder
+	for _, in := range prog {
+		b.WriteString(in.String())
+		b.WriteByte('\n')
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
@@ -0,0 +1,123 @@
+package parir
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRoundTrip(t *testing.T) {
+	src := `.main
+push #PC+3
+jmp
+halt
+push 2
+oframe
+push 1.5
+push -2.0
+push #00ff80
+push [1:0]
+push +[0:2]
+push #PC-4
+push .main
+push @loop
+@loop
+pusha [0:1]
+st
+sta
+drop
+dup
+cframe
+alloc
+cjmp
+call
+ret
+reta
+add
+sub
+mul
+div
+mod
+max
+min
+lt
+le
+gt
+ge
+eq
+and
+or
+not
+inc
+dec
+irnd
+print
+printa
+delay
+width
+height
+clear
+write
+writebox
+read
+nop
+`
+	prog, err := Parse(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out strings.Builder
+	Print(&out, prog)
+	if out.String() != src {
+		t.Fatalf("Expected:\n%s\ngot:\n%s", src, out.String())
+	}
+}
+
+func TestParseOperands(t *testing.T) {
+	tests := []struct {
+		text string
+		want Operand
+	}{
+		{"push 42", Int(42)},
+		{"push -7", Int(-7)},
+		{"push 0.25", Float(0.25)},
+		{"push #FFF", Colour(0xffffff)},
+		{"push [3:1]", Slot(3, 1)},
+		{"push +[0:0]", IndexedSlot(0, 0)},
+		{"push #PC+12", PC(12)},
+		{"push .f", Label("f")},
+		{"  push   @end  ", Local("end")},
+	}
+	for _, tt := range tests {
+		in, err := ParseInstruction(tt.text)
+		if err != nil {
+			t.Errorf("%q: %v", tt.text, err)
+			continue
+		}
+		if in.Op != OpPush || in.Arg != tt.want {
+			t.Errorf("%q: expected push %v, got %v", tt.text, tt.want, in)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, text := range []string{
+		"frobnicate",
+		"push",
+		"push 1 2",
+		"add 1",
+		"pusha 3",
+		"push #PC3",
+		"push #12345",
+		"push [1]",
+		"push x",
+		".",
+	} {
+		if in, err := ParseInstruction(text); err == nil {
+			t.Errorf("Expected an error parsing %q, got %v", text, in)
+		}
+	}
+
+	if _, err := Parse("push 1\n\nbogus\n"); err == nil || !strings.HasPrefix(err.Error(), "line 3:") {
+		t.Errorf("Expected an error on line 3, got %v", err)
+	}
+}
@@ -0,0 +1,137 @@
+package parir
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Parse reads a program in PArIR text syntax, one instruction per line.
+// Blank lines are skipped.
+func Parse(src string) ([]Instruction, error) {
+	var prog []Instruction
+	for i, line := range strings.Split(src, "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		in, err := ParseInstruction(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", i+1, err)
+		}
+		prog = append(prog, in)
+	}
+	return prog, nil
+}
+
+// ParseInstruction reads a single instruction.
+func ParseInstruction(text string) (Instruction, error) {
+	fields := strings.Fields(text)
+	if len(fields) == 0 || len(fields) > 2 {
+		return Instruction{}, fmt.Errorf("malformed instruction %q", strings.TrimSpace(text))
+	}
+
+	name := fields[0]
+	if len(fields) == 1 && len(name) > 1 && (name[0] == '.' || name[0] == '@') {
+		arg, err := ParseOperand(name)
+		if err != nil {
+			return Instruction{}, err
+		}
+		if arg.Kind == KindLabel {
+			return Instruction{Op: OpLabel, Arg: arg}, nil
+		}
+		return Instruction{Op: OpMark, Arg: arg}, nil
+	}
+	op, ok := opsByName[name]
+	if !ok {
+		return Instruction{}, fmt.Errorf("unknown instruction %q", name)
+	}
+	in := Instruction{Op: op}
+	if len(fields) == 2 {
+		arg, err := ParseOperand(fields[1])
+		if err != nil {
+			return Instruction{}, err
+		}
+		in.Arg = arg
+	}
+
+	switch {
+	case op == OpPush && in.Arg.Kind == KindNone:
+		return in, fmt.Errorf("push needs an operand")
+	case op == OpPushA && in.Arg.Kind != KindSlot:
+		return in, fmt.Errorf("pusha needs a frame slot operand")
+	case op != OpPush && op != OpPushA && in.Arg.Kind != KindNone:
+		return in, fmt.Errorf("%s takes no operand", op)
+	}
+	return in, nil
+}
+
+// ParseOperand reads an operand in any of the forms Operand.String prints.
+// Colours may also be written in the short #rgb form.
+func ParseOperand(arg string) (Operand, error) {
+	switch {
+	case strings.HasPrefix(arg, "#PC"):
+		n, err := strconv.Atoi(arg[3:])
+		if err != nil || len(arg) == 3 || (arg[3] != '+' && arg[3] != '-') {
+			return Operand{}, fmt.Errorf("malformed PC offset %q", arg)
+		}
+		return PC(n), nil
+	case strings.HasPrefix(arg, "#"):
+		c, err := parseColour(arg)
+		return Colour(c), err
+	case strings.HasPrefix(arg, "."), strings.HasPrefix(arg, "@"):
+		if len(arg) == 1 {
+			return Operand{}, fmt.Errorf("malformed label %q", arg)
+		}
+		if arg[0] == '.' {
+			return Label(arg[1:]), nil
+		}
+		return Local(arg[1:]), nil
+	case strings.HasPrefix(arg, "+["):
+		i, l, err := parseSlot(arg[1:])
+		return IndexedSlot(i, l), err
+	case strings.HasPrefix(arg, "["):
+		i, l, err := parseSlot(arg)
+		return Slot(i, l), err
+	}
+	if n, err := strconv.Atoi(arg); err == nil {
+		return Int(n), nil
+	}
+	f, err := strconv.ParseFloat(arg, 64)
+	if err != nil {
+		return Operand{}, fmt.Errorf("malformed operand %q", arg)
+	}
+	return Float(f), nil
+}
+
+// parseColour accepts #rrggbb and the short #rgb form.
+func parseColour(s string) (int, error) {
+	hex := s[1:]
+	if len(hex) == 3 {
+		hex = string([]byte{hex[0], hex[0], hex[1], hex[1], hex[2], hex[2]})
+	}
+	if len(hex) != 6 {
+		return 0, fmt.Errorf("malformed colour %q", s)
+	}
+	c, err := strconv.ParseInt(hex, 16, 32)
+	if err != nil {
+		return 0, fmt.Errorf("malformed colour %q", s)
+	}
+	return int(c), nil
+}
+
+// parseSlot parses a frame slot of the form [i:l].
+func parseSlot(s string) (int, int, error) {
+	if !strings.HasPrefix(s, "[") || !strings.HasSuffix(s, "]") {
+		return 0, 0, fmt.Errorf("malformed frame slot %q", s)
+	}
+	parts := strings.Split(s[1:len(s)-1], ":")
+	if len(parts) != 2 {
+		return 0, 0, fmt.Errorf("malformed frame slot %q", s)
+	}
+	i, err1 := strconv.Atoi(parts[0])
+	l, err2 := strconv.Atoi(parts[1])
+	if err1 != nil || err2 != nil {
+		return 0, 0, fmt.Errorf("malformed frame slot %q", s)
+	}
+	return i, l, nil
+}
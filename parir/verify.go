@@ -0,0 +1,454 @@
+package parir
+
+import (
+	"fmt"
+	"sort"
+)
+
+// VerifyError reports a problem with the instruction at index At.
+type VerifyError struct {
+	At  int
+	Msg string
+}
+
+func (e VerifyError) Error() string {
+	return fmt.Sprintf("instruction %d: %s", e.At, e.Msg)
+}
+
+// Verify checks that prog is well formed before it is run:
+//
+//   - every label is declared once and every push .name refers to one;
+//   - every #PC+n and #PC-n address, and every jump, lands inside the
+//     program;
+//   - frames are balanced: along every path, the program closes each frame
+//     it opens before it halts, and a function closes each frame it opens
+//     before it returns;
+//   - the operand stack never underflows and has the same depth on every
+//     path into an instruction, and calls and returns find the values they
+//     need on it.
+//
+// The code of the program proper is checked from the first instruction,
+// and each function from its label. The errors are sorted by instruction.
+func Verify(prog []Instruction) []VerifyError {
+	v := &verifier{prog: prog, labels: make(map[string]int), reported: make(map[int]bool)}
+	v.checkLabels()
+	if len(v.errs) > 0 {
+		return v.errs
+	}
+
+	entries := []int{0}
+	for _, in := range prog {
+		if in.Op == OpPush && in.Arg.Kind == KindLabel {
+			entries = append(entries, v.labels[in.Arg.Name])
+		}
+	}
+	v.returns = make(map[int]int)
+	for _, entry := range entries[1:] {
+		if _, done := v.returns[entry]; !done {
+			v.returns[entry] = v.returnSize(entry)
+		}
+	}
+	if len(prog) > 0 {
+		v.run(0, false)
+	}
+	analysed := map[int]bool{}
+	for _, entry := range entries[1:] {
+		if !analysed[entry] {
+			analysed[entry] = true
+			v.run(entry, true)
+		}
+	}
+	sort.SliceStable(v.errs, func(i, j int) bool { return v.errs[i].At < v.errs[j].At })
+	return v.errs
+}
+
+type verifier struct {
+	prog   []Instruction
+	labels map[string]int
+	// returns maps each function entry to the number of values it leaves
+	// on the operand stack, or -1 if that is unknown
+	returns  map[int]int
+	errs     []VerifyError
+	reported map[int]bool
+}
+
+func (v *verifier) errorf(at int, format string, args ...any) {
+	if v.reported[at] {
+		return
+	}
+	v.reported[at] = true
+	v.errs = append(v.errs, VerifyError{At: at, Msg: fmt.Sprintf(format, args...)})
+}
+
+func (v *verifier) checkLabels() {
+	for i, in := range v.prog {
+		if in.Op != OpLabel {
+			continue
+		}
+		if _, dup := v.labels[in.Arg.Name]; dup {
+			v.errorf(i, "label .%s declared twice", in.Arg.Name)
+		}
+		v.labels[in.Arg.Name] = i
+	}
+	for i, in := range v.prog {
+		switch {
+		case in.Op == OpMark || in.Arg.Kind == KindLocal:
+			v.errorf(i, "unassembled label @%s", in.Arg.Name)
+		case in.Arg.Kind == KindLabel && in.Op != OpLabel:
+			if _, ok := v.labels[in.Arg.Name]; !ok {
+				v.errorf(i, "undefined label .%s", in.Arg.Name)
+			}
+		case in.Arg.Kind == KindPC:
+			if t := i + in.Arg.Int; t < 0 || t >= len(v.prog) {
+				v.errorf(i, "address %s lands outside the program", in.Arg)
+			}
+		}
+	}
+}
+
+// jumpTarget returns the target of the jump at i, whose address must be
+// pushed by the instruction before it.
+func (v *verifier) jumpTarget(i int) (int, bool) {
+	if i == 0 || v.prog[i-1].Op != OpPush {
+		v.errorf(i, "%s target is not a constant address", v.prog[i].Op)
+		return 0, false
+	}
+	addr := v.prog[i-1].Arg
+	var target int
+	switch addr.Kind {
+	case KindPC:
+		target = i - 1 + addr.Int
+	case KindInt:
+		target = addr.Int
+	default:
+		v.errorf(i, "%s target is not a constant address", v.prog[i].Op)
+		return 0, false
+	}
+	if target < 0 || target >= len(v.prog) {
+		v.errorf(i, "%s lands outside the program", v.prog[i].Op)
+		return 0, false
+	}
+	return target, true
+}
+
+// successors returns where control goes after the instruction at i. Calls
+// return to the next instruction. A successor of len(prog) means running
+// off the end.
+func (v *verifier) successors(i int) ([]int, bool) {
+	switch v.prog[i].Op {
+	case OpHalt, OpRet, OpRetA:
+		return nil, true
+	case OpJmp:
+		t, ok := v.jumpTarget(i)
+		return []int{t}, ok
+	case OpCJmp:
+		t, ok := v.jumpTarget(i)
+		return []int{i + 1, t}, ok
+	}
+	return []int{i + 1}, true
+}
+
+// returnSize finds how many values the function at entry leaves on the
+// operand stack from its ret and reta instructions.
+func (v *verifier) returnSize(entry int) int {
+	size := -1
+	seen := map[int]bool{entry: true}
+	work := []int{entry}
+	for len(work) > 0 {
+		i := work[len(work)-1]
+		work = work[:len(work)-1]
+		n := -1
+		switch v.prog[i].Op {
+		case OpRet:
+			n = 1
+		case OpRetA:
+			if i > 0 && v.prog[i-1].Op == OpPush && v.prog[i-1].Arg.Kind == KindInt {
+				n = v.prog[i-1].Arg.Int
+			}
+		}
+		if n >= 0 {
+			if size >= 0 && size != n {
+				return -1
+			}
+			size = n
+		}
+		succ, _ := v.successors(i)
+		for _, s := range succ {
+			if s < len(v.prog) && !seen[s] {
+				seen[s] = true
+				work = append(work, s)
+			}
+		}
+	}
+	return size
+}
+
+// value is an abstract operand stack entry: a constant, if known.
+type value struct {
+	n     int
+	known bool
+}
+
+// state is what is known before an instruction runs. Frames counts the
+// frames opened since the entry, including the one a call opens.
+type state struct {
+	frames int
+	stack  []value
+}
+
+func (s *state) pop(n int) ([]value, bool) {
+	if n > len(s.stack) {
+		return nil, false
+	}
+	top := s.stack[len(s.stack)-n:]
+	s.stack = s.stack[:len(s.stack)-n]
+	// the topmost value first
+	vals := make([]value, n)
+	for k := range top {
+		vals[k] = top[n-1-k]
+	}
+	return vals, true
+}
+
+func (s *state) push(vals ...value) {
+	s.stack = append(s.stack, vals...)
+}
+
+// unknowns returns n values that are not constants.
+func unknowns(n int) []value {
+	return make([]value, n)
+}
+
+// run follows every path from entry, checking frame and stack use.
+func (v *verifier) run(entry int, function bool) {
+	states := make(map[int]*state)
+	start := &state{}
+	if function {
+		start.frames = 1
+	}
+	states[entry] = start
+	work := []int{entry}
+	for len(work) > 0 {
+		i := work[len(work)-1]
+		work = work[:len(work)-1]
+		if i == len(v.prog) {
+			if function {
+				v.errorf(i-1, "function runs off the end of the program")
+			} else if states[i].frames != 0 {
+				v.errorf(i-1, "program ends with %d frames open", states[i].frames)
+			}
+			continue
+		}
+
+		s := &state{frames: states[i].frames, stack: append([]value(nil), states[i].stack...)}
+		if !v.step(i, s, function) {
+			continue
+		}
+		succ, ok := v.successors(i)
+		if !ok {
+			continue
+		}
+		for _, t := range succ {
+			if v.merge(states, t, s) {
+				work = append(work, t)
+			}
+		}
+	}
+}
+
+// merge joins s into the state before instruction t and reports whether
+// that state changed.
+func (v *verifier) merge(states map[int]*state, t int, s *state) bool {
+	old, seen := states[t]
+	if !seen {
+		states[t] = &state{frames: s.frames, stack: append([]value(nil), s.stack...)}
+		return true
+	}
+	at := min(t, len(v.prog)-1)
+	if old.frames != s.frames {
+		v.errorf(at, "reached with %d and with %d frames open", old.frames, s.frames)
+		return false
+	}
+	if len(old.stack) != len(s.stack) {
+		v.errorf(at, "reached with %d and with %d values on the operand stack", len(old.stack), len(s.stack))
+		return false
+	}
+	changed := false
+	for k := range old.stack {
+		if old.stack[k].known && old.stack[k] != s.stack[k] {
+			old.stack[k] = value{}
+			changed = true
+		}
+	}
+	return changed
+}
+
+// step applies the instruction at i to s. It reports an error and returns
+// false if the instruction cannot run in s.
+func (v *verifier) step(i int, s *state, function bool) bool {
+	in := v.prog[i]
+	underflow := func() bool {
+		v.errorf(i, "%s: operand stack underflow", in.Op)
+		return false
+	}
+	// count pops the constant count on top of the stack.
+	count := func() (int, bool) {
+		vals, ok := s.pop(1)
+		if !ok {
+			return 0, underflow()
+		}
+		if !vals[0].known || vals[0].n < 0 {
+			v.errorf(i, "%s: count is not a constant", in.Op)
+			return 0, false
+		}
+		return vals[0].n, true
+	}
+	simple := func(pops, pushes int) bool {
+		if _, ok := s.pop(pops); !ok {
+			return underflow()
+		}
+		s.push(unknowns(pushes)...)
+		return true
+	}
+
+	switch in.Op {
+	case OpNop, OpLabel, OpHalt:
+		if in.Op == OpHalt && !function && s.frames != 0 {
+			v.errorf(i, "halt with %d frames open", s.frames)
+			return false
+		}
+		return true
+	case OpPush:
+		switch in.Arg.Kind {
+		case KindInt:
+			s.push(value{n: in.Arg.Int, known: true})
+		case KindIndexedSlot:
+			return simple(1, 1)
+		default:
+			s.push(value{})
+		}
+		return true
+	case OpPushA:
+		n, ok := count()
+		if ok {
+			s.push(unknowns(n)...)
+		}
+		return ok
+	case OpSt:
+		return simple(3, 0)
+	case OpStA:
+		if _, ok := s.pop(2); !ok {
+			return underflow()
+		}
+		n, ok := count()
+		if ok {
+			if _, ok = s.pop(n); !ok {
+				return underflow()
+			}
+		}
+		return ok
+	case OpDrop:
+		return simple(1, 0)
+	case OpDup:
+		vals, ok := s.pop(1)
+		if !ok {
+			return underflow()
+		}
+		s.push(vals[0], vals[0])
+		return true
+
+	case OpOFrame:
+		s.frames++
+		return simple(1, 0)
+	case OpCFrame:
+		if s.frames == 0 || (function && s.frames == 1) {
+			v.errorf(i, "cframe with no frame open")
+			return false
+		}
+		s.frames--
+		return true
+	case OpAlloc:
+		if s.frames == 0 {
+			v.errorf(i, "alloc with no frame open")
+			return false
+		}
+		return simple(1, 0)
+
+	case OpJmp:
+		return simple(1, 0)
+	case OpCJmp:
+		return simple(2, 0)
+	case OpCall:
+		if i == 0 || v.prog[i-1].Op != OpPush || v.prog[i-1].Arg.Kind != KindLabel {
+			v.errorf(i, "call target is not a label")
+			return false
+		}
+		name := v.prog[i-1].Arg.Name
+		if _, ok := s.pop(1); !ok {
+			return underflow()
+		}
+		n, ok := count()
+		if !ok {
+			return false
+		}
+		if _, ok := s.pop(n); !ok {
+			return underflow()
+		}
+		results := v.returns[v.labels[name]]
+		if results < 0 {
+			v.errorf(i, "cannot tell what .%s returns", name)
+			return false
+		}
+		s.push(unknowns(results)...)
+		return true
+	case OpRet, OpRetA:
+		if !function {
+			v.errorf(i, "%s outside of a function", in.Op)
+			return false
+		}
+		want := 1
+		if in.Op == OpRetA {
+			n, ok := count()
+			if !ok {
+				return false
+			}
+			want = n
+		}
+		if len(s.stack) != want {
+			v.errorf(i, "%s with %d values on the operand stack, want %d", in.Op, len(s.stack), want)
+			return false
+		}
+		if s.frames != 1 {
+			v.errorf(i, "%s with %d frames open", in.Op, s.frames-1)
+			return false
+		}
+		return true
+
+	case OpAdd, OpSub, OpMul, OpDiv, OpMod, OpMax, OpMin,
+		OpLt, OpLe, OpGt, OpGe, OpEq, OpAnd, OpOr:
+		return simple(2, 1)
+	case OpNot, OpInc, OpDec, OpIRnd:
+		return simple(1, 1)
+
+	case OpPrint, OpDelay, OpClear:
+		return simple(1, 0)
+	case OpPrintA:
+		n, ok := count()
+		if ok {
+			if _, ok = s.pop(n); !ok {
+				return underflow()
+			}
+		}
+		return ok
+	case OpWidth, OpHeight:
+		return simple(0, 1)
+	case OpWrite:
+		return simple(3, 0)
+	case OpWriteBox:
+		return simple(5, 0)
+	case OpRead:
+		return simple(2, 1)
+	}
+	v.errorf(i, "cannot verify %s", in)
+	return false
+}
@@ -0,0 +1,90 @@
+package parir
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestVerifyValid(t *testing.T) {
+	src := `.main
+push #PC+3
+jmp
+halt
+push 1
+oframe
+push #PC+9
+jmp
+.f
+push 0
+alloc
+push [0:0]
+push 1
+add
+ret
+push 4
+push 1
+push .f
+call
+push 0
+push 0
+st
+push 0
+push #PC+5
+cjmp
+push 1
+push #PC+3
+jmp
+push 2
+print
+push 3
+push 2
+push 1
+push 3
+printa
+cframe
+halt`
+	prog, err := Parse(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if errs := Verify(prog); len(errs) != 0 {
+		t.Fatalf("Expected no errors, got %v", errs)
+	}
+}
+
+func TestVerifyErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		at   int
+		msg  string
+	}{
+		{"undefined label", "push 0; push .f; call", 1, "undefined label .f"},
+		{"duplicate label", ".f; .f", 1, "declared twice"},
+		{"address outside", "push #PC+5; jmp", 0, "outside the program"},
+		{"computed jump", "push [0:0]; jmp", 1, "not a constant address"},
+		{"frame left open", "push 0; oframe; halt", 2, "1 frames open"},
+		{"extra cframe", "push 0; oframe; cframe; cframe; halt", 3, "no frame open"},
+		{"unbalanced branches", "push 1; push #PC+4; cjmp; push 0; oframe; halt", 5, "reached with 0 and with 1 frames open"},
+		{"stack mismatch at join", "push 7; push [0:0]; push #PC+3; cjmp; push 1; halt", 5, "values on the operand stack"},
+		{"underflow", "add", 0, "underflow"},
+		{"ret outside function", "push 1; ret", 1, "outside of a function"},
+		{"ret with frame open", "push 0; push .f; call; halt; .f; push 0; oframe; push 1; ret", 8, "1 frames open"},
+		{"ret without value", "push 0; push .f; call; halt; .f; ret", 5, "0 values"},
+		{"reta count", "push 0; push .f; call; halt; .f; push 1; push 2; reta", 7, "want 2"},
+		{"call count", "push [0:0]; push .f; call; halt; .f; push 1; ret", 2, "count is not a constant"},
+		{"unassembled", "push @x; jmp; @x", 0, "unassembled label @x"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			prog, err := Parse(strings.ReplaceAll(tt.src, "; ", "\n"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			errs := Verify(prog)
+			if len(errs) == 0 || errs[0].At != tt.at || !strings.Contains(errs[0].Msg, tt.msg) {
+				t.Fatalf("Expected an error at %d containing %q, got %v", tt.at, tt.msg, errs)
+			}
+		})
+	}
+}
@@ -0,0 +1,32 @@
+package parser
+
+import (
+	"github.com/giuszeppe/compiler-theory/diag"
+	"github.com/giuszeppe/compiler-theory/lexer"
+)
+
+// Lexical and syntax error codes.
+const (
+	CodeInvalidToken    = "L001"
+	CodeUnexpectedToken = "S001"
+	CodeNoRule          = "S002"
+	CodeExtraneousInput = "S003"
+	// CodeInvalidSymbol means the grammar itself is malformed.
+	CodeInvalidSymbol = "S000"
+)
+
+func ErrInvalidToken(tok lexer.Token) diag.Diagnostic {
+	return diag.NewError(CodeInvalidToken, diag.SpanOf(tok), "Invalid token: %q", tok.Lexeme)
+}
+
+func ErrUnexpectedToken(expected lexer.TokenType, tok lexer.Token) diag.Diagnostic {
+	return diag.NewError(CodeUnexpectedToken, diag.SpanOf(tok), "Syntax error: expected %v, got %v %q", expected, tok.Type, tok.Lexeme)
+}
+
+func ErrNoRule(nonterminal string, tok lexer.Token) diag.Diagnostic {
+	return diag.NewError(CodeNoRule, diag.SpanOf(tok), "Syntax error: unexpected %v %q while parsing %s", tok.Type, tok.Lexeme, nonterminal)
+}
+
+func ErrExtraneousInput(tok lexer.Token) diag.Diagnostic {
+	return diag.NewError(CodeExtraneousInput, diag.SpanOf(tok), "Syntax error: extraneous input starting at %v %q", tok.Type, tok.Lexeme)
+}
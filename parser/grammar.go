@@ -0,0 +1,1471 @@
+package parser
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/giuszeppe/compiler-theory/ast"
+	"github.com/giuszeppe/compiler-theory/diag"
+	"github.com/giuszeppe/compiler-theory/lexer"
+	"github.com/giuszeppe/compiler-theory/types"
+)
+
+// NewGrammar returns PArL's grammar with its LL(1) table. It does not fail
+// on conflicts, which are left in Conflicts for prlc grammar to report;
+// TestGrammarIsLL1 asserts there are none.
+func NewGrammar() *Grammar {
+	g := &Grammar{
+		StartSymbol: "Program",
+		Rules:       []Rule{},
+		Table:       make(map[string]map[lexer.TokenType]int),
+	}
+
+	// — Program → StmtList
+	g.Rules = append(g.Rules, Rule{
+		LHS: "Program",
+		RHS: []Symbol{"StmtList"},
+		Action: func(ch []ast.ASTNode) ast.ASTNode {
+			// ch[0] is *ASTBlockNode
+			blk := ch[0].(*ast.ASTBlockNode)
+			// A program wrapped in { } parses as a single block statement
+			// (a separate Program → { StmtList } rule would be a FIRST/FIRST
+			// conflict); its statements are the program's.
+			if len(blk.Stmts) == 1 {
+				if inner, ok := blk.Stmts[0].(*ast.ASTBlockNode); ok {
+					blk = inner
+				}
+			}
+			return &ast.ASTProgramNode{Block: *blk}
+		},
+	})
+	// — StmtList → Statement StmtList
+	g.Rules = append(g.Rules, Rule{
+		LHS: "StmtList",
+		RHS: []Symbol{"Statement", "StmtList"},
+		Action: func(ch []ast.ASTNode) ast.ASTNode {
+			// ch[0] is one ASTNode stmt, ch[1] is *ASTBlockNode with a slice
+			stmt := ch[0]
+			tail := ch[1].(*ast.ASTBlockNode)
+			return &ast.ASTBlockNode{
+				Name:  "",
+				Stmts: append([]ast.ASTNode{stmt}, tail.Stmts...),
+			}
+		},
+	})
+
+	// — StmtList → ε
+	g.Rules = append(g.Rules, Rule{
+		LHS: "StmtList",
+		RHS: []Symbol{}, // empty
+		Action: func(ch []ast.ASTNode) ast.ASTNode {
+			return &ast.ASTBlockNode{Name: "", Stmts: []ast.ASTNode{}}
+		},
+	})
+
+	// — Statement → Assignment ';'
+	g.Rules = append(g.Rules, Rule{
+		LHS: "Statement",
+		RHS: []Symbol{"Identifier", lexer.EqualsToken, "Expr", lexer.SemicolonToken},
+		Action: func(ch []ast.ASTNode) ast.ASTNode {
+			// ch[0] and ch[1] were terminals; ch[2] is *ASTExpressionNode
+			exprN := ch[2]
+			varNode, _ := ch[0].(*ast.ASTVariableNode)
+			return &ast.ASTAssignmentNode{
+				Id:   *varNode,
+				Expr: exprN,
+			}
+		},
+	})
+
+	g.Rules = append(g.Rules, Rule{
+		LHS: "Identifier",
+		RHS: []Symbol{lexer.Identifier, "IdentifierOrArrayAccess"},
+		Action: func(ch []ast.ASTNode) ast.ASTNode {
+			return &ast.ASTVariableNode{
+				Token:  ch[0].(*ast.ASTSimpleExpression).Token,
+				Offset: ch[1],
+			}
+		},
+	})
+
+	g.Rules = append(g.Rules, Rule{
+		LHS: "IdentifierOrArrayAccess",
+		RHS: []Symbol{lexer.LeftBracketToken, "Expr", lexer.RightBracketToken},
+		Action: func(ch []ast.ASTNode) ast.ASTNode {
+			return ch[1]
+		},
+	})
+
+	g.Rules = append(g.Rules, Rule{
+		LHS: "IdentifierOrArrayAccess",
+		RHS: []Symbol{},
+		Action: func(ch []ast.ASTNode) ast.ASTNode {
+			return &ast.ASTEpsilon{}
+		},
+	})
+
+	// — Statement → VariableDecl ';'
+	g.Rules = append(g.Rules, Rule{
+		LHS: "Statement",
+		RHS: []Symbol{lexer.Let, lexer.Identifier, lexer.ColonToken, "TypeRule", "VarDeclSuffix", lexer.SemicolonToken},
+		Action: func(ch []ast.ASTNode) ast.ASTNode {
+			return &ast.ASTVarDeclNode{
+				Token:      ch[1].(*ast.ASTSimpleExpression).Token,
+				Type:       varDeclType(ch[3], ch[4]),
+				Expression: ch[4],
+			}
+		},
+	})
+
+	// — VarDeclSuffix →  '=' Expr
+	g.Rules = append(g.Rules, Rule{
+		LHS: "VarDeclSuffix",
+		RHS: []Symbol{lexer.EqualsToken, "Expr"},
+		Action: func(ch []ast.ASTNode) ast.ASTNode {
+			return ch[1]
+		},
+	})
+
+	// - VarDeclSuffix → '[' VarDeclArray
+	g.Rules = append(g.Rules, Rule{
+		LHS: "VarDeclSuffix",
+		RHS: []Symbol{lexer.LeftBracketToken, "VarDeclArray"},
+		Action: func(ch []ast.ASTNode) ast.ASTNode {
+			// ch[0] is a left bracket, ch[1] is *ASTVarDeclArrayNode
+			return ch[1]
+		},
+	})
+
+	// - VarDeclArray → Integer ']' = '[' Literal VarDeclArrayTail ]'
+	g.Rules = append(g.Rules, Rule{
+		LHS: "VarDeclArray",
+		RHS: []Symbol{lexer.Integer, lexer.RightBracketToken, lexer.EqualsToken, lexer.LeftBracketToken, "Literal", "VarDeclArrayTail"},
+		Action: func(ch []ast.ASTNode) ast.ASTNode {
+			arrayNode := ch[5].(*ast.ASTArrayNode)
+			arraySize := ch[0].(*ast.ASTSimpleExpression).Token.Lexeme
+			v, _ := strconv.Atoi(arraySize)
+			arrayNode.Size = v
+			arrayNode.Sized = true
+			arrayNode.Items = append([]ast.ASTNode{ch[4]}, arrayNode.Items...)
+			arrayNode.Token = ch[3].(*ast.ASTSimpleExpression).Token
+			return arrayNode
+		},
+	})
+
+	// - VarDeclArrayTail → ',' Literal VarDeclArrayTail
+	g.Rules = append(g.Rules, Rule{
+		LHS: "VarDeclArrayTail",
+		RHS: []Symbol{lexer.CommaToken, "Literal", "VarDeclArrayTail"},
+		Action: func(ch []ast.ASTNode) ast.ASTNode {
+			return &ast.ASTArrayNode{
+				Size:  ch[2].(*ast.ASTArrayNode).Size + 1,
+				Items: append([]ast.ASTNode{ch[1]}, ch[2].(*ast.ASTArrayNode).Items...),
+			}
+		},
+	})
+	// - VarDeclArrayTail → epsilon
+	g.Rules = append(g.Rules, Rule{
+		LHS: "VarDeclArrayTail",
+		RHS: []Symbol{lexer.RightBracketToken},
+		Action: func(ch []ast.ASTNode) ast.ASTNode {
+			return &ast.ASTArrayNode{
+				Items: []ast.ASTNode{},
+			}
+		},
+	})
+
+	// - VarDeclArray →  ']' = '[' Literal VarDeclArrayTail ']'
+	g.Rules = append(g.Rules, Rule{
+		LHS: "VarDeclArray",
+		RHS: []Symbol{lexer.RightBracketToken, lexer.EqualsToken, lexer.LeftBracketToken, "Literal", "VarDeclArrayTail"},
+		Action: func(ch []ast.ASTNode) ast.ASTNode {
+			arrayNode := ch[4].(*ast.ASTArrayNode)
+			arrayNode.Size = arrayNode.Size + 1
+			arrayNode.Items = append([]ast.ASTNode{ch[3]}, arrayNode.Items...)
+			return arrayNode
+		},
+	})
+
+	// - Literal → IntegerLiteral
+	g.Rules = append(g.Rules, Rule{
+		LHS: "Literal",
+		RHS: []Symbol{lexer.Integer},
+		Action: func(ch []ast.ASTNode) ast.ASTNode {
+			tok := ch[0].(*ast.ASTSimpleExpression).Token
+			v, _ := strconv.Atoi(tok.Lexeme)
+			return &ast.ASTIntegerNode{Name: tok.Lexeme, Value: v}
+		},
+	})
+
+	// - Literal → FloatLiteral
+	g.Rules = append(g.Rules, Rule{
+		LHS: "Literal",
+		RHS: []Symbol{lexer.Float},
+		Action: func(ch []ast.ASTNode) ast.ASTNode {
+			tok := ch[0].(*ast.ASTSimpleExpression).Token
+			v, _ := strconv.ParseFloat(tok.Lexeme, 64)
+			return &ast.ASTFloatNode{Name: tok.Lexeme, Value: v}
+		},
+	})
+
+	// - Literal → True
+	g.Rules = append(g.Rules, Rule{
+		LHS: "Literal",
+		RHS: []Symbol{lexer.True},
+		Action: func(ch []ast.ASTNode) ast.ASTNode {
+			return &ast.ASTBooleanNode{Value: true}
+		},
+	})
+
+	// - Literal → False
+	g.Rules = append(g.Rules, Rule{
+		LHS: "Literal",
+		RHS: []Symbol{lexer.False},
+		Action: func(ch []ast.ASTNode) ast.ASTNode {
+			return &ast.ASTBooleanNode{Value: false}
+		},
+	})
+
+	// - Literal → Color
+	g.Rules = append(g.Rules, Rule{
+		LHS: "Literal",
+		RHS: []Symbol{lexer.HexNumber},
+		Action: func(ch []ast.ASTNode) ast.ASTNode {
+			return &ast.ASTColorNode{
+				Value: ch[0].(*ast.ASTSimpleExpression).Token.Lexeme,
+			}
+		},
+	})
+
+	// - VarDeclArray → ']' | ',' Expr VarDeclArray
+	// g.Rules = append(g.Rules, Rule{
+	// 	LHS: "VarDeclArray",
+	// 	RHS: []Symbol{RightBracketToken},
+	// 	Action: func(ch []ASTNode) ASTNode {
+	// 		return &ASTEpsilon{}
+	// 	},
+	// })
+
+	// — TypeRule → 'float' | 'int' | 'color' | 'bool' |
+	g.Rules = append(g.Rules, Rule{
+		LHS: "TypeRule",
+		RHS: []Symbol{lexer.FloatType},
+		Action: func(ch []ast.ASTNode) ast.ASTNode {
+			return &ast.ASTTypeNode{
+				Type: primitiveType(ch[0]),
+			}
+		},
+	})
+	// — TypeRule → 'float' | 'int' | 'color' | 'bool' |
+	g.Rules = append(g.Rules, Rule{
+		LHS: "TypeRule",
+		RHS: []Symbol{lexer.IntType},
+		Action: func(ch []ast.ASTNode) ast.ASTNode {
+			return &ast.ASTTypeNode{
+				Type: primitiveType(ch[0]),
+			}
+		},
+	})
+	// — TypeRule → 'float' | 'int' | 'color' | 'bool' |
+	g.Rules = append(g.Rules, Rule{
+		LHS: "TypeRule",
+		RHS: []Symbol{lexer.BoolType},
+		Action: func(ch []ast.ASTNode) ast.ASTNode {
+			return &ast.ASTTypeNode{
+				Type: primitiveType(ch[0]),
+			}
+
+		},
+	})
+	// — TypeRule → 'float' | 'int' | 'color' | 'bool' |
+	g.Rules = append(g.Rules, Rule{
+		LHS: "TypeRule",
+		RHS: []Symbol{lexer.ColourType},
+		Action: func(ch []ast.ASTNode) ast.ASTNode {
+			return &ast.ASTTypeNode{
+				Type: primitiveType(ch[0]),
+			}
+		},
+	})
+
+	// — Expr → SimpleExpr ExprPrime ExprTail
+	g.Rules = append(g.Rules, Rule{
+		LHS: "Expr",
+		RHS: []Symbol{"SimpleExpr", "ExprPrime", "ExprTail"},
+		Action: func(ch []ast.ASTNode) ast.ASTNode {
+			node := ch[0] // the first term (e.g., "2")
+			typeCastNode, isTypeCasted := ch[2].(*ast.ASTTypeCastNode)
+
+			opListExpr := ch[1].(*ast.ASTExpressionNode)
+			opList := opListExpr.Expr.(*ast.ASTOpList)
+
+			for _, pair := range opList.Pairs {
+				left := node
+				node = &ast.ASTBinaryOpNode{
+					Token:    pair.Op,
+					Operator: pair.Op.Lexeme,
+					Left:     left,
+					Right:    pair.Right,
+				}
+				node.SetSpan(ast.Cover(left, pair.Right))
+			}
+
+			if isTypeCasted {
+				return &ast.ASTTypeCastNode{
+					Type: typeCastNode.Type,
+					Expr: node,
+				}
+			}
+			return node
+		},
+	})
+	// — ExprTail → "as" TypeRule
+	g.Rules = append(g.Rules, Rule{
+		LHS: "ExprTail",
+		RHS: []Symbol{lexer.As, "TypeRule"},
+		Action: func(ch []ast.ASTNode) ast.ASTNode {
+			return &ast.ASTTypeCastNode{Type: ch[1].(*ast.ASTTypeNode).Type}
+		},
+	})
+
+	// — ExprTail → ε
+	g.Rules = append(g.Rules, Rule{
+		LHS: "ExprTail",
+		RHS: []Symbol{},
+		Action: func(ch []ast.ASTNode) ast.ASTNode {
+			return &ast.ASTEpsilon{}
+		},
+	})
+
+	// — ExprPrime → RelOp SimpleExpr ExprPrime
+	g.Rules = append(g.Rules, Rule{
+		LHS: "ExprPrime",
+		RHS: []Symbol{lexer.RelOpToken, "SimpleExpr", "ExprPrime"},
+		Action: func(ch []ast.ASTNode) ast.ASTNode {
+			op := ch[0].(*ast.ASTSimpleExpression).Token
+			term := ch[1]
+			tailExpr := ch[2].(*ast.ASTExpressionNode)
+			tail := tailExpr.Expr.(*ast.ASTOpList)
+
+			pairs := append([]struct {
+				Op    lexer.Token
+				Right ast.ASTNode
+			}{{op, term}}, tail.Pairs...)
+
+			return &ast.ASTExpressionNode{
+				Expr: &ast.ASTOpList{Pairs: pairs},
+			}
+		},
+	})
+
+	// — ExprPrime → ε
+	g.Rules = append(g.Rules, Rule{
+		LHS: "ExprPrime",
+		RHS: []Symbol{},
+		Action: func(ch []ast.ASTNode) ast.ASTNode {
+			if len(ch) == 0 {
+				return &ast.ASTExpressionNode{
+					Expr: &ast.ASTOpList{},
+				}
+			}
+			return ch[0]
+		},
+	})
+
+	// — SimpleExpr → Term SimpleExprPrime
+	g.Rules = append(g.Rules, Rule{
+		LHS: "SimpleExpr",
+		RHS: []Symbol{"Term", "SimpleExprPrime"},
+		Action: func(ch []ast.ASTNode) ast.ASTNode {
+			node := ch[0] // the first term (e.g., "2")
+
+			opListExpr := ch[1].(*ast.ASTExpressionNode)
+			opList := opListExpr.Expr.(*ast.ASTOpList)
+
+			for _, pair := range opList.Pairs {
+				left := node
+				node = &ast.ASTBinaryOpNode{
+					Token:    pair.Op,
+					Operator: pair.Op.Lexeme,
+					Left:     left,
+					Right:    pair.Right,
+				}
+				node.SetSpan(ast.Cover(left, pair.Right))
+			}
+
+			return node
+
+		},
+	})
+
+	// — SimpleExprPrime → '+' Term SimpleExprPrime
+	g.Rules = append(g.Rules, Rule{
+		LHS: "SimpleExprPrime",
+		RHS: []Symbol{"AdditiveOperator", "Term", "SimpleExprPrime"},
+		Action: func(ch []ast.ASTNode) ast.ASTNode {
+			op := ch[0].(*ast.ASTSimpleExpression).Token
+			term := ch[1]
+			tailExpr := ch[2].(*ast.ASTExpressionNode)
+			tail := tailExpr.Expr.(*ast.ASTOpList)
+
+			pairs := append([]struct {
+				Op    lexer.Token
+				Right ast.ASTNode
+			}{{op, term}}, tail.Pairs...)
+
+			return &ast.ASTExpressionNode{
+				Expr: &ast.ASTOpList{Pairs: pairs},
+			}
+		},
+	})
+
+	// — SimpleExprPrime → ε
+	g.Rules = append(g.Rules, Rule{
+		LHS: "SimpleExprPrime",
+		RHS: []Symbol{},
+		Action: func(ch []ast.ASTNode) ast.ASTNode {
+			if len(ch) == 0 {
+				return &ast.ASTExpressionNode{
+					Expr: &ast.ASTOpList{},
+				}
+			}
+			return ch[0]
+		},
+	})
+
+	// — Term → Factor TermPrime
+	g.Rules = append(g.Rules, Rule{
+		LHS: "Term",
+		RHS: []Symbol{"Factor", "TermPrime"},
+		Action: func(ch []ast.ASTNode) ast.ASTNode {
+			node := ch[0] // the first term (e.g., "2")
+
+			opListExpr := ch[1].(*ast.ASTExpressionNode)
+			opList := opListExpr.Expr.(*ast.ASTOpList)
+
+			for _, pair := range opList.Pairs {
+				left := node
+				node = &ast.ASTBinaryOpNode{
+					Token:    pair.Op,
+					Operator: pair.Op.Lexeme,
+					Left:     left,
+					Right:    pair.Right,
+				}
+				node.SetSpan(ast.Cover(left, pair.Right))
+			}
+
+			return node
+		},
+	})
+
+	// — TermPrime → MultiplicativeOperator Factor TermPrime
+	g.Rules = append(g.Rules, Rule{
+		LHS: "TermPrime",
+		RHS: []Symbol{"MultiplicativeOperator", "Factor", "TermPrime"},
+		Action: func(ch []ast.ASTNode) ast.ASTNode {
+			op := ch[0].(*ast.ASTSimpleExpression).Token
+			term := ch[1]
+			tailExpr := ch[2].(*ast.ASTExpressionNode)
+			tail := tailExpr.Expr.(*ast.ASTOpList)
+
+			pairs := append([]struct {
+				Op    lexer.Token
+				Right ast.ASTNode
+			}{{op, term}}, tail.Pairs...)
+
+			return &ast.ASTExpressionNode{
+				Expr: &ast.ASTOpList{Pairs: pairs},
+			}
+		},
+	})
+
+	// - MultiplicativeOperator → '*' | '/' | 'and'
+	g.Rules = append(g.Rules, Rule{
+		LHS: "MultiplicativeOperator",
+		RHS: []Symbol{lexer.StarToken},
+		Action: func(ch []ast.ASTNode) ast.ASTNode {
+			return &ast.ASTSimpleExpression{
+				Token: ch[0].(*ast.ASTSimpleExpression).Token,
+			}
+		},
+	})
+	g.Rules = append(g.Rules, Rule{
+		LHS: "MultiplicativeOperator",
+		RHS: []Symbol{lexer.SlashToken},
+		Action: func(ch []ast.ASTNode) ast.ASTNode {
+			return &ast.ASTSimpleExpression{
+				Token: ch[0].(*ast.ASTSimpleExpression).Token,
+			}
+		},
+	})
+	g.Rules = append(g.Rules, Rule{
+		LHS: "MultiplicativeOperator",
+		RHS: []Symbol{lexer.AndToken},
+		Action: func(ch []ast.ASTNode) ast.ASTNode {
+			return &ast.ASTSimpleExpression{
+				Token: ch[0].(*ast.ASTSimpleExpression).Token,
+			}
+		},
+	})
+
+	// — AdditiveOperator → '+' | '-' | 'or'
+	g.Rules = append(g.Rules, Rule{
+		LHS: "AdditiveOperator",
+		RHS: []Symbol{lexer.PlusToken},
+		Action: func(ch []ast.ASTNode) ast.ASTNode {
+			return &ast.ASTSimpleExpression{
+				Token: ch[0].(*ast.ASTSimpleExpression).Token,
+			}
+		},
+	})
+	g.Rules = append(g.Rules, Rule{
+		LHS: "AdditiveOperator",
+		RHS: []Symbol{lexer.MinusToken},
+		Action: func(ch []ast.ASTNode) ast.ASTNode {
+			return &ast.ASTSimpleExpression{
+				Token: ch[0].(*ast.ASTSimpleExpression).Token,
+			}
+		},
+	})
+	g.Rules = append(g.Rules, Rule{
+		LHS: "AdditiveOperator",
+		RHS: []Symbol{lexer.OrToken},
+		Action: func(ch []ast.ASTNode) ast.ASTNode {
+			return &ast.ASTSimpleExpression{
+				Token: ch[0].(*ast.ASTSimpleExpression).Token,
+			}
+		},
+	})
+
+	// — TermPrime → ε
+	g.Rules = append(g.Rules, Rule{
+		LHS: "TermPrime",
+		RHS: []Symbol{},
+		Action: func(ch []ast.ASTNode) ast.ASTNode {
+			if len(ch) == 0 {
+				return &ast.ASTExpressionNode{
+					Expr: &ast.ASTOpList{},
+				}
+			}
+			return ch[0]
+		},
+	})
+
+	// — Factor → IntegerLiteral
+	g.Rules = append(g.Rules, Rule{
+		LHS: "Factor",
+		RHS: []Symbol{lexer.Integer},
+		Action: func(ch []ast.ASTNode) ast.ASTNode {
+			tok := ch[0].(*ast.ASTSimpleExpression).Token
+			v, _ := strconv.Atoi(tok.Lexeme)
+			return &ast.ASTIntegerNode{Name: tok.Lexeme, Value: v}
+		},
+	})
+
+	// — Factor → FloatLiteral
+	g.Rules = append(g.Rules, Rule{
+		LHS: "Factor",
+		RHS: []Symbol{lexer.Float},
+		Action: func(ch []ast.ASTNode) ast.ASTNode {
+			tok := ch[0].(*ast.ASTSimpleExpression).Token
+			v, _ := strconv.ParseFloat(tok.Lexeme, 64)
+			return &ast.ASTFloatNode{Name: tok.Lexeme, Value: v}
+		},
+	})
+
+	// — Factor → SubExpr
+	g.Rules = append(g.Rules, Rule{
+		LHS: "Factor",
+		RHS: []Symbol{"SubExpr"},
+		Action: func(ch []ast.ASTNode) ast.ASTNode {
+			return ch[0]
+		},
+	})
+
+	// — SubExpr → '(' Expr ')'
+	g.Rules = append(g.Rules, Rule{
+		LHS: "SubExpr",
+		RHS: []Symbol{lexer.LeftParenToken, "Expr", lexer.RightParenToken},
+		Action: func(ch []ast.ASTNode) ast.ASTNode {
+			return ch[1] // the Expr inside
+		},
+	})
+
+	// - Statement → 'if' '(' Expr ')' <Block> [ 'else' <Block> ]
+	g.Rules = append(g.Rules, Rule{
+		LHS: "Statement",
+		RHS: []Symbol{lexer.If, "Expr", "Block", "IfTail"},
+		Action: func(ch []ast.ASTNode) ast.ASTNode {
+			ifNode := ast.ASTIfNode{
+				Condition: ch[1],
+				ThenBlock: ch[2].(*ast.ASTBlockNode),
+				ElseBlock: ch[3],
+			}
+			return &ifNode
+		},
+	})
+	// - IfTail → 'else' <Block>
+	g.Rules = append(g.Rules, Rule{
+		LHS: "IfTail",
+		RHS: []Symbol{lexer.Else, "Block"},
+		Action: func(ch []ast.ASTNode) ast.ASTNode {
+			return ch[1]
+		},
+	})
+
+	// - IfTail → ε
+	g.Rules = append(g.Rules, Rule{
+		LHS: "IfTail",
+		RHS: []Symbol{},
+		Action: func(ch []ast.ASTNode) ast.ASTNode {
+			return &ast.ASTEpsilon{}
+		},
+	})
+	// - Block → '{' StmtList '}'
+	g.Rules = append(g.Rules, Rule{
+		LHS: "Block",
+		RHS: []Symbol{lexer.LeftCurlyToken, "StmtList", lexer.RightCurlyToken},
+		Action: func(ch []ast.ASTNode) ast.ASTNode {
+			// ch[1] is *ASTBlockNode
+			blk := ch[1].(*ast.ASTBlockNode)
+			blk.Name = "Block"
+			// the braces belong to the block
+			blk.SetSpan(ast.Cover(ch...))
+			return blk
+		},
+	})
+
+	// - Statement -> 'while'  Expr  <Block>
+	g.Rules = append(g.Rules, Rule{
+		LHS: "Statement",
+		RHS: []Symbol{lexer.While, "Expr", "Block"},
+		Action: func(ch []ast.ASTNode) ast.ASTNode {
+			whileNode := ast.ASTWhileNode{
+				Condition: ch[1],
+				Block:     ch[2].(*ast.ASTBlockNode),
+			}
+			return &whileNode
+		},
+	})
+
+	// - Statement -> 'for' '(' Assignment ';' Expr ';' Assignment ')' <Block>
+	g.Rules = append(g.Rules, Rule{
+		LHS: "Statement",
+		RHS: []Symbol{lexer.For, lexer.LeftParenToken, "ForVarDecl", lexer.SemicolonToken, "Expr", lexer.SemicolonToken, "ForAssignment", lexer.RightParenToken, "Block"},
+		Action: func(ch []ast.ASTNode) ast.ASTNode {
+			forNode := ast.ASTForNode{
+				VarDecl:   ch[2],
+				Condition: ch[4],
+				Increment: ch[6],
+				Block:     ch[8].(*ast.ASTBlockNode),
+			}
+			return &forNode
+		},
+	})
+	// - ForVarDecl → 'let' Identifier ':' TypeRule '=' Expr
+	g.Rules = append(g.Rules, Rule{
+		LHS: "ForVarDecl",
+		RHS: []Symbol{lexer.Let, lexer.Identifier, lexer.ColonToken, "TypeRule", "VarDeclSuffix"},
+		Action: func(ch []ast.ASTNode) ast.ASTNode {
+			return &ast.ASTVarDeclNode{
+				Token:      ch[1].(*ast.ASTSimpleExpression).Token,
+				Type:       varDeclType(ch[3], ch[4]),
+				Expression: ch[4],
+			}
+		},
+	})
+	// - ForVarDecl → ε
+	g.Rules = append(g.Rules, Rule{
+		LHS: "ForVarDecl",
+		RHS: []Symbol{},
+		Action: func(ch []ast.ASTNode) ast.ASTNode {
+			return &ast.ASTEpsilon{}
+		},
+	})
+
+	// - ForAssignment → Identifier '=' Expr
+	g.Rules = append(g.Rules, Rule{
+		LHS: "ForAssignment",
+		RHS: []Symbol{"Identifier", lexer.EqualsToken, "Expr"},
+		Action: func(ch []ast.ASTNode) ast.ASTNode {
+			// ch[0] is *ASTSimpleExpression wrapping the var token
+			varnode := ch[0].(*ast.ASTVariableNode)
+			exprN := ch[2]
+			return &ast.ASTAssignmentNode{
+				Id:   *varnode,
+				Expr: exprN,
+			}
+		},
+	})
+	// - ForAssignment → ε
+	g.Rules = append(g.Rules, Rule{
+		LHS: "ForAssignment",
+		RHS: []Symbol{},
+		Action: func(ch []ast.ASTNode) ast.ASTNode {
+			return &ast.ASTEpsilon{}
+		},
+	})
+	// - Statement → Fun Identifier '(' FormalParams ')' '->' TypeRule Block
+	g.Rules = append(g.Rules, Rule{
+		LHS: "Statement",
+		RHS: []Symbol{lexer.Fun, lexer.Identifier, lexer.LeftParenToken, "FormalParams", lexer.RightParenToken, lexer.LeftArrowToken, "TypeRule", "ArrayTypeSignature", "Block"},
+		Action: func(ch []ast.ASTNode) ast.ASTNode {
+			return &ast.ASTFuncDeclNode{
+				Token:      ch[1].(*ast.ASTSimpleExpression).Token,
+				Params:     ch[3],
+				ReturnType: signatureType(ch[6], ch[7]),
+				Block:      ch[8].(*ast.ASTBlockNode),
+			}
+		},
+	})
+
+	// - FormalParams → Identifier ':' TypeRule FormalParamsTail
+	g.Rules = append(g.Rules, Rule{
+		LHS: "FormalParams",
+		RHS: []Symbol{lexer.Identifier, lexer.ColonToken, "TypeRule", "ArrayTypeSignature", "FormalParamsTail"},
+		Action: func(ch []ast.ASTNode) ast.ASTNode {
+			varType := signatureType(ch[2], ch[3])
+			param := formalParam(ch[0].(*ast.ASTSimpleExpression).Token, varType, ast.Cover(ch[0], ch[2], ch[3]))
+			tail := ch[4].(*ast.ASTFormalParamsNode)
+
+			return &ast.ASTFormalParamsNode{
+				Params: append([]ast.ASTNode{param}, tail.Params...),
+			}
+		},
+	})
+
+	// - FormalParamsTail → ',' Identifier ':' TypeRule FormalParamsTail
+	g.Rules = append(g.Rules, Rule{
+		LHS: "FormalParamsTail",
+		RHS: []Symbol{lexer.CommaToken, lexer.Identifier, lexer.ColonToken, "TypeRule", "ArrayTypeSignature", "FormalParamsTail"},
+		Action: func(ch []ast.ASTNode) ast.ASTNode {
+			varType := signatureType(ch[3], ch[4])
+			param := formalParam(ch[1].(*ast.ASTSimpleExpression).Token, varType, ast.Cover(ch[1], ch[3], ch[4]))
+			tail := ch[5].(*ast.ASTFormalParamsNode)
+
+			return &ast.ASTFormalParamsNode{
+				Params: append([]ast.ASTNode{param}, tail.Params...),
+			}
+		},
+	})
+
+	// - ArrayTypeSignature → '[' Integer ']'
+	g.Rules = append(g.Rules, Rule{
+		LHS: "ArrayTypeSignature",
+		RHS: []Symbol{lexer.LeftBracketToken, lexer.Integer, lexer.RightBracketToken},
+		Action: func(ch []ast.ASTNode) ast.ASTNode {
+			// ch[0] is a left bracket, ch[1] is *ASTVarDeclArrayNode
+			return ch[1]
+		},
+	})
+
+	// - ArrayTypeSignature → ε
+	g.Rules = append(g.Rules, Rule{
+		LHS: "ArrayTypeSignature",
+		RHS: []Symbol{},
+		Action: func(ch []ast.ASTNode) ast.ASTNode {
+			// ch[0] is a left bracket, ch[1] is *ASTVarDeclArrayNode
+			return &ast.ASTEpsilon{}
+		},
+	})
+
+	// - FormalParamsTail → ε
+	g.Rules = append(g.Rules, Rule{
+		LHS: "FormalParamsTail",
+		RHS: []Symbol{},
+		Action: func(ch []ast.ASTNode) ast.ASTNode {
+			return &ast.ASTFormalParamsNode{
+				Params: []ast.ASTNode{},
+			}
+		},
+	})
+
+	// - FormalParams → ε
+	g.Rules = append(g.Rules, Rule{
+		LHS: "FormalParams",
+		RHS: []Symbol{},
+		Action: func(ch []ast.ASTNode) ast.ASTNode {
+			return &ast.ASTFormalParamsNode{
+				Params: []ast.ASTNode{},
+			}
+		},
+	})
+
+	// - Statement → __print Expr ';'
+	g.Rules = append(g.Rules, Rule{
+		LHS: "Statement",
+		RHS: []Symbol{lexer.Print, "Expr", lexer.SemicolonToken},
+		Action: func(ch []ast.ASTNode) ast.ASTNode {
+			return &ast.ASTBuiltinFuncNode{
+				Token: ch[0].(*ast.ASTSimpleExpression).Token,
+				Args:  []ast.ASTNode{ch[1]},
+			}
+		},
+	})
+
+	// - Statement → __delay Expr ';'
+	g.Rules = append(g.Rules, Rule{
+		LHS: "Statement",
+		RHS: []Symbol{lexer.Delay, "Expr", lexer.SemicolonToken},
+		Action: func(ch []ast.ASTNode) ast.ASTNode {
+			return &ast.ASTBuiltinFuncNode{
+				Token: ch[0].(*ast.ASTSimpleExpression).Token,
+				Args:  []ast.ASTNode{ch[1]},
+			}
+		},
+	})
+
+	// - Statement → __write Expr ',' Expr ',' Expr ';'
+	g.Rules = append(g.Rules, Rule{
+		LHS: "Statement",
+		RHS: []Symbol{lexer.Write, "Expr", lexer.CommaToken, "Expr", lexer.CommaToken, "Expr", lexer.SemicolonToken},
+		Action: func(ch []ast.ASTNode) ast.ASTNode {
+			return &ast.ASTBuiltinFuncNode{
+				Token: ch[0].(*ast.ASTSimpleExpression).Token,
+				Args:  []ast.ASTNode{ch[1], ch[3], ch[5]},
+			}
+		},
+	})
+
+	// - Statement → __write_box Expr ',' Expr ',' Expr ',' Expr ',' Expr ';'
+	g.Rules = append(g.Rules, Rule{
+		LHS: "Statement",
+		RHS: []Symbol{lexer.WriteBox, "Expr", lexer.CommaToken, "Expr", lexer.CommaToken, "Expr", lexer.CommaToken, "Expr", lexer.CommaToken, "Expr", lexer.SemicolonToken},
+		Action: func(ch []ast.ASTNode) ast.ASTNode {
+			return &ast.ASTBuiltinFuncNode{
+				Token: ch[0].(*ast.ASTSimpleExpression).Token,
+				Args:  []ast.ASTNode{ch[1], ch[3], ch[5], ch[7], ch[9]},
+			}
+		},
+	})
+
+	g.Rules = append(g.Rules, Rule{
+		LHS: "Statement",
+		RHS: []Symbol{lexer.ClearToken, "Expr", lexer.SemicolonToken},
+		Action: func(ch []ast.ASTNode) ast.ASTNode {
+			return &ast.ASTBuiltinFuncNode{
+				Token: ch[0].(*ast.ASTSimpleExpression).Token,
+				Args:  []ast.ASTNode{ch[1]},
+			}
+		},
+	})
+
+	// - Factor → __width
+	g.Rules = append(g.Rules, Rule{
+		LHS: "Factor",
+		RHS: []Symbol{lexer.PadWidth},
+		Action: func(ch []ast.ASTNode) ast.ASTNode {
+			return &ast.ASTBuiltinFuncNode{
+				Token: ch[0].(*ast.ASTSimpleExpression).Token,
+				Args:  []ast.ASTNode{},
+			}
+		},
+	})
+
+	// - Factor → __height
+	g.Rules = append(g.Rules, Rule{
+		LHS: "Factor",
+		RHS: []Symbol{lexer.PadHeight},
+		Action: func(ch []ast.ASTNode) ast.ASTNode {
+			return &ast.ASTBuiltinFuncNode{
+				Token: ch[0].(*ast.ASTSimpleExpression).Token,
+				Args:  []ast.ASTNode{},
+			}
+		},
+	})
+
+	// - Factor → __read Expr ',' Expr
+	g.Rules = append(g.Rules, Rule{
+		LHS: "Factor",
+		RHS: []Symbol{"ReadExpr"},
+		Action: func(ch []ast.ASTNode) ast.ASTNode {
+			return ch[0]
+		},
+	})
+
+	g.Rules = append(g.Rules, Rule{
+		LHS: "ReadExpr",
+		RHS: []Symbol{lexer.PadRead, lexer.LeftParenToken, "Expr", lexer.CommaToken, "Expr", lexer.RightParenToken},
+		Action: func(ch []ast.ASTNode) ast.ASTNode {
+			return &ast.ASTBuiltinFuncNode{
+				Token: ch[0].(*ast.ASTSimpleExpression).Token,
+				Args:  []ast.ASTNode{ch[2], ch[4]},
+			}
+		},
+	})
+
+	//	- Factor → __random_int Expr
+	g.Rules = append(g.Rules, Rule{
+		LHS: "Factor",
+		RHS: []Symbol{lexer.PadRandI, lexer.LeftParenToken, "Expr", lexer.RightParenToken},
+		Action: func(ch []ast.ASTNode) ast.ASTNode {
+			return &ast.ASTBuiltinFuncNode{
+				Token: ch[0].(*ast.ASTSimpleExpression).Token,
+				Args:  []ast.ASTNode{ch[2]},
+			}
+		},
+	})
+
+	// — Factor → Identifier IdentifierOrFunctionCall
+	g.Rules = append(g.Rules, Rule{
+		LHS: "Factor",
+		RHS: []Symbol{lexer.Identifier, "IdentifierOrFunctionCall"},
+		Action: func(ch []ast.ASTNode) ast.ASTNode {
+			_, isFuncCall := ch[1].(*ast.ASTFuncCallNode)
+			if isFuncCall {
+				funcCall := ch[1].(*ast.ASTFuncCallNode)
+				funcCall.Name = ch[0].(*ast.ASTSimpleExpression).Token
+				// the call was given the span of its arguments alone
+				funcCall.SetSpan(ast.Cover(ch...))
+				return funcCall
+			}
+			return &ast.ASTVariableNode{Token: ch[0].(*ast.ASTSimpleExpression).Token, Offset: ch[1]}
+		},
+	})
+
+	// — IdentifierOrFunctionCall → [ Expr ]
+	g.Rules = append(g.Rules, Rule{
+		LHS: "IdentifierOrFunctionCall",
+		RHS: []Symbol{lexer.LeftBracketToken, "Expr", lexer.RightBracketToken},
+		Action: func(ch []ast.ASTNode) ast.ASTNode {
+			return ch[1]
+		},
+	})
+	// — IdentifierOrFunctionCall → ε
+	g.Rules = append(g.Rules, Rule{
+		LHS: "IdentifierOrFunctionCall",
+		RHS: []Symbol{},
+		Action: func(ch []ast.ASTNode) ast.ASTNode {
+			return &ast.ASTEpsilon{}
+		},
+	})
+
+	// - IdentifierOrFunctionCall →  '(' ActualParams ')'
+	g.Rules = append(g.Rules, Rule{
+		LHS: "IdentifierOrFunctionCall",
+		RHS: []Symbol{lexer.LeftParenToken, "ActualParams", lexer.RightParenToken},
+		Action: func(ch []ast.ASTNode) ast.ASTNode {
+			return &ast.ASTFuncCallNode{
+				Params: ch[1].(*ast.ASTActualParamsNode),
+			}
+		},
+	})
+
+	// - ActualParams → Expr ActualParamsTail
+	g.Rules = append(g.Rules, Rule{
+		LHS: "ActualParams",
+		RHS: []Symbol{"Expr", "ActualParamsTail"},
+		Action: func(ch []ast.ASTNode) ast.ASTNode {
+			param := ch[0]
+			tail := ch[1].(*ast.ASTActualParamsNode)
+			return &ast.ASTActualParamsNode{
+				Params: append([]ast.ASTNode{param}, tail.Params...),
+			}
+		},
+	})
+
+	// - ActualParams → epsilon
+	g.Rules = append(g.Rules, Rule{
+		LHS: "ActualParams",
+		RHS: []Symbol{},
+		Action: func(ch []ast.ASTNode) ast.ASTNode {
+			return &ast.ASTActualParamsNode{
+				Params: []ast.ASTNode{},
+			}
+		},
+	})
+
+	// - ActualParamsTail → ',' Expr ActualParamsTail
+	g.Rules = append(g.Rules, Rule{
+		LHS: "ActualParamsTail",
+		RHS: []Symbol{lexer.CommaToken, "Expr", "ActualParamsTail"},
+		Action: func(ch []ast.ASTNode) ast.ASTNode {
+			param := ch[1]
+			tail := ch[2].(*ast.ASTActualParamsNode)
+			return &ast.ASTActualParamsNode{
+				Params: append([]ast.ASTNode{param}, tail.Params...),
+			}
+		},
+	})
+
+	// - ActualParamsTail → ε
+	g.Rules = append(g.Rules, Rule{
+		LHS: "ActualParamsTail",
+		RHS: []Symbol{},
+		Action: func(ch []ast.ASTNode) ast.ASTNode {
+			return &ast.ASTActualParamsNode{
+				Params: []ast.ASTNode{},
+			}
+		},
+	})
+
+	// - Statement → Block
+	g.Rules = append(g.Rules, Rule{
+		LHS: "Statement",
+		RHS: []Symbol{"Block"},
+		Action: func(ch []ast.ASTNode) ast.ASTNode {
+			// ch[0] is *ASTBlockNode
+			blk := ch[0].(*ast.ASTBlockNode)
+			return &ast.ASTBlockNode{Name: "Statement", Stmts: blk.Stmts}
+		},
+	})
+
+	// - Factor -> Unary
+	g.Rules = append(g.Rules, Rule{
+		LHS: "Factor",
+		RHS: []Symbol{"Unary"},
+		Action: func(ch []ast.ASTNode) ast.ASTNode {
+			return ch[0]
+		},
+	})
+
+	// - Unary -> '-' Factor
+	g.Rules = append(g.Rules, Rule{
+		LHS: "Unary",
+		RHS: []Symbol{"UnaryOperator", "Factor"},
+		Action: func(ch []ast.ASTNode) ast.ASTNode {
+			return &ast.ASTUnaryOpNode{
+				Operator: ch[0].(*ast.ASTSimpleExpression).Token.Lexeme,
+				Operand:  ch[1],
+			}
+		},
+	})
+
+	// - UnaryOperator -> '-' | 'not'
+	g.Rules = append(g.Rules, Rule{
+		LHS: "UnaryOperator",
+		RHS: []Symbol{lexer.MinusToken},
+		Action: func(ch []ast.ASTNode) ast.ASTNode {
+			return &ast.ASTSimpleExpression{
+				Token: ch[0].(*ast.ASTSimpleExpression).Token,
+			}
+		},
+	})
+	// - UnaryOperator -> '-' | 'not'
+	g.Rules = append(g.Rules, Rule{
+		LHS: "UnaryOperator",
+		RHS: []Symbol{lexer.NotToken},
+		Action: func(ch []ast.ASTNode) ast.ASTNode {
+			return &ast.ASTSimpleExpression{
+				Token: ch[0].(*ast.ASTSimpleExpression).Token,
+			}
+		},
+	})
+
+	// - Factor -> 'true'
+	g.Rules = append(g.Rules, Rule{
+		LHS: "Factor",
+		RHS: []Symbol{lexer.True},
+		Action: func(ch []ast.ASTNode) ast.ASTNode {
+			return &ast.ASTBooleanNode{
+				Value: true,
+			}
+		},
+	})
+	// - Factor -> 'false'
+	g.Rules = append(g.Rules, Rule{
+		LHS: "Factor",
+		RHS: []Symbol{lexer.False},
+		Action: func(ch []ast.ASTNode) ast.ASTNode {
+			return &ast.ASTBooleanNode{
+				Value: false,
+			}
+		},
+	})
+
+	// - Factor -> HexNumber
+	g.Rules = append(g.Rules, Rule{
+		LHS: "Factor",
+		RHS: []Symbol{lexer.HexNumber},
+		Action: func(ch []ast.ASTNode) ast.ASTNode {
+			return &ast.ASTColorNode{
+				Token: ch[0].(*ast.ASTSimpleExpression).Token,
+				Value: ch[0].(*ast.ASTSimpleExpression).Token.Lexeme,
+			}
+		},
+	})
+
+	// - Statement -> 'return' Expr ';'
+	g.Rules = append(g.Rules, Rule{
+		LHS: "Statement",
+		RHS: []Symbol{lexer.Return, "Expr", lexer.SemicolonToken},
+		Action: func(ch []ast.ASTNode) ast.ASTNode {
+			return &ast.ASTReturnNode{
+				Token: ch[0].(*ast.ASTSimpleExpression).Token,
+				Expr:  ch[1],
+			}
+		},
+	})
+
+	// — finally, build the LL(1) table:
+	g.Table, g.Conflicts = genTable(g)
+	return g
+}
+
+// formalParam returns the declaration of a function parameter. Parameters
+// have no initialiser, so the empty expression sits at the end of span.
+func formalParam(name lexer.Token, varType types.Type, span diag.Span) *ast.ASTVarDeclNode {
+	end := diag.Span{Start: span.End, End: span.End}
+	init := &ast.ASTEpsilon{}
+	init.SetSpan(end)
+	expr := &ast.ASTExpressionNode{Expr: init}
+	expr.SetSpan(end)
+	param := &ast.ASTVarDeclNode{
+		Token:      name,
+		Type:       varType,
+		Expression: expr,
+	}
+	param.SetSpan(span)
+	return param
+}
+
+// genTable builds the LL(1) parsing table for g.
+// It returns table[A][a] = index of the rule in g.Rules to apply when
+// the current nonterminal is A and the lookahead token is a, together with
+// a *Conflict for every cell claimed by more than one rule. On a conflict
+// the earlier rule keeps the cell.
+func genTable(g *Grammar) (map[string]map[lexer.TokenType]int, []error) {
+	// 1) collect all nonterminals
+	nonterms := make(map[string]struct{})
+	for _, r := range g.Rules {
+		nonterms[r.LHS] = struct{}{}
+	}
+
+	// 2) prepare FIRST sets and nullable map
+	first := make(map[string]map[lexer.TokenType]struct{})
+	nullable := make(map[string]bool)
+	for A := range nonterms {
+		first[A] = make(map[lexer.TokenType]struct{})
+		nullable[A] = false
+	}
+
+	// helper: add a terminal to first[A], return true if it was new
+	addFirst := func(A lexer.TokenType, set map[lexer.TokenType]struct{}) bool {
+		if _, ok := set[A]; !ok {
+			set[A] = struct{}{}
+			return true
+		}
+		return false
+	}
+
+	// 3) iterate to compute FIRST and nullable
+	changed := true
+	for changed {
+		changed = false
+		for _, rule := range g.Rules {
+			A := rule.LHS
+			// compute FIRST(RHS) and whether RHS is nullable
+			rhsNullable := true
+			for _, sym := range rule.RHS {
+				switch s := sym.(type) {
+				case lexer.TokenType:
+					// terminal: FIRST(RHS) includes itself, and RHS isn't nullable past here
+					if addFirst(s, first[A]) {
+						changed = true
+					}
+					rhsNullable = false
+					break
+				case string:
+					// nonterminal: inherit FIRST(s) minus ε
+					for t := range first[s] {
+						if addFirst(t, first[A]) {
+							changed = true
+						}
+					}
+					if !nullable[s] {
+						rhsNullable = false
+						break
+					}
+				}
+				if !rhsNullable {
+					// if this symbol blocks ε, stop scanning RHS
+					break
+				}
+			}
+			// if all symbols were nullable (or RHS empty), A is nullable
+			if rhsNullable && !nullable[A] {
+				nullable[A] = true
+				changed = true
+			}
+		}
+	}
+
+	// 4) compute FOLLOW sets
+	follow := make(map[string]map[lexer.TokenType]struct{})
+	for A := range nonterms {
+		follow[A] = make(map[lexer.TokenType]struct{})
+	}
+	// start symbol gets End-of-input
+	follow[g.StartSymbol][lexer.End] = struct{}{}
+
+	// helper to compute FIRST of a sequence of symbols
+	firstOfSeq := func(seq []Symbol) (map[lexer.TokenType]struct{}, bool) {
+		res := make(map[lexer.TokenType]struct{})
+		seqNullable := true
+		for _, sym := range seq {
+			if !seqNullable {
+				break
+			}
+			switch s := sym.(type) {
+			case lexer.TokenType:
+				res[s] = struct{}{}
+				seqNullable = false
+			case string:
+				for t := range first[s] {
+					res[t] = struct{}{}
+				}
+				if !nullable[s] {
+					seqNullable = false
+				}
+			}
+		}
+		return res, seqNullable
+	}
+
+	// iterate to fixpoint on FOLLOW
+	changed = true
+	for changed {
+		changed = false
+		for _, rule := range g.Rules {
+			A := rule.LHS
+			rhs := rule.RHS
+			for i, sym := range rhs {
+				B, ok := sym.(string)
+				if !ok {
+					continue // skip terminals
+				}
+				// compute FIRST of β = rhs[i+1:]
+				beta := rhs[i+1:]
+				firstBeta, betaNullable := firstOfSeq(beta)
+				// add FIRST(β) minus ε to FOLLOW(B)
+				for t := range firstBeta {
+					if t == lexer.End {
+						continue
+					}
+					if _, seen := follow[B][t]; !seen {
+						follow[B][t] = struct{}{}
+						changed = true
+					}
+				}
+				// if β nullable, add FOLLOW(A) to FOLLOW(B)
+				if betaNullable {
+					for t := range follow[A] {
+						if _, seen := follow[B][t]; !seen {
+							follow[B][t] = struct{}{}
+							changed = true
+						}
+					}
+				}
+			}
+		}
+	}
+
+	g.Nullable = nullable
+	g.First = first
+	g.Follow = follow
+
+	// 5) build the parsing table
+	table := make(map[string]map[lexer.TokenType]int)
+	for A := range nonterms {
+		table[A] = make(map[lexer.TokenType]int)
+	}
+	// viaFollow[A][a] is set when table[A][a] was filled because its rule is nullable
+	viaFollow := make(map[string]map[lexer.TokenType]bool)
+	for A := range nonterms {
+		viaFollow[A] = make(map[lexer.TokenType]bool)
+	}
+
+	var conflicts []error
+	assign := func(A string, t lexer.TokenType, i int, fromFollow bool) {
+		if j, ok := table[A][t]; ok {
+			if j == i {
+				return
+			}
+			kind := FirstFirst
+			if fromFollow || viaFollow[A][t] {
+				kind = FirstFollow
+			}
+			conflicts = append(conflicts, &Conflict{
+				Kind:        kind,
+				Nonterminal: A,
+				Lookahead:   t,
+				Kept:        g.Rules[j],
+				Dropped:     g.Rules[i],
+			})
+			return
+		}
+		table[A][t] = i
+		viaFollow[A][t] = fromFollow
+	}
+
+	for i, rule := range g.Rules {
+		A := rule.LHS
+		firstRHS, rhsNullable := firstOfSeq(rule.RHS)
+		// for each terminal in FIRST(RHS), assign rule i
+		for _, t := range sortedTerminals(firstRHS) {
+			assign(A, t, i, false)
+		}
+		// if RHS nullable, for each b in FOLLOW(A), assign rule i
+		if rhsNullable {
+			for _, b := range sortedTerminals(follow[A]) {
+				assign(A, b, i, true)
+			}
+		}
+	}
+
+	return table, conflicts
+}
+
+// ConflictKind distinguishes the two ways a grammar can fail to be LL(1).
+type ConflictKind int
+
+const (
+	// FirstFirst: two alternatives of A can both start with the lookahead.
+	FirstFirst ConflictKind = iota
+	// FirstFollow: one alternative of A can derive ε and the lookahead is
+	// both in FOLLOW(A) and able to start another alternative.
+	FirstFollow
+)
+
+func (k ConflictKind) String() string {
+	if k == FirstFollow {
+		return "FIRST/FOLLOW"
+	}
+	return "FIRST/FIRST"
+}
+
+// Conflict is a parsing table cell claimed by two rules.
+type Conflict struct {
+	Kind        ConflictKind
+	Nonterminal string
+	Lookahead   lexer.TokenType
+	Kept        Rule
+	Dropped     Rule
+}
+
+func (c *Conflict) Error() string {
+	return fmt.Sprintf("%s conflict in %s on %v: %q and %q", c.Kind, c.Nonterminal, c.Lookahead, fmtRule(c.Kept), fmtRule(c.Dropped))
+}
+
+func sortedTerminals(set map[lexer.TokenType]struct{}) []lexer.TokenType {
+	ts := make([]lexer.TokenType, 0, len(set))
+	for t := range set {
+		ts = append(ts, t)
+	}
+	sort.Slice(ts, func(i, j int) bool { return ts[i] < ts[j] })
+	return ts
+}
+
+func sortedNonterminals[V any](m map[string]V) []string {
+	names := make([]string, 0, len(m))
+	for A := range m {
+		names = append(names, A)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func fmtTerminals(ts []lexer.TokenType) string {
+	names := make([]string, len(ts))
+	for i, t := range ts {
+		names[i] = t.String()
+	}
+	return "{" + strings.Join(names, ", ") + "}"
+}
+
+// WriteReport writes the nullable, FIRST and FOLLOW sets of g followed by its
+// parsing table to w. Nonterminals are listed alphabetically and terminals in
+// TokenType order, so the output is stable and can be diffed between grammar
+// edits. Any conflicts are listed last.
+func (g *Grammar) WriteReport(w io.Writer) {
+	nonterms := sortedNonterminals(g.Table)
+
+	fmt.Fprintln(w, "Nullable:")
+	for _, A := range nonterms {
+		if g.Nullable[A] {
+			fmt.Fprintf(w, "  %s\n", A)
+		}
+	}
+
+	fmt.Fprintln(w, "\nFIRST:")
+	for _, A := range nonterms {
+		fmt.Fprintf(w, "  %s = %s\n", A, fmtTerminals(sortedTerminals(g.First[A])))
+	}
+
+	fmt.Fprintln(w, "\nFOLLOW:")
+	for _, A := range nonterms {
+		fmt.Fprintf(w, "  %s = %s\n", A, fmtTerminals(sortedTerminals(g.Follow[A])))
+	}
+
+	fmt.Fprintln(w, "\nLL(1) Parsing Table:")
+	for _, A := range nonterms {
+		row := g.Table[A]
+		lookaheads := make([]lexer.TokenType, 0, len(row))
+		for t := range row {
+			lookaheads = append(lookaheads, t)
+		}
+		sort.Slice(lookaheads, func(i, j int) bool { return lookaheads[i] < lookaheads[j] })
+
+		fmt.Fprintf(w, "  %s\n", A)
+		for _, t := range lookaheads {
+			fmt.Fprintf(w, "    %-18s %s\n", t.String(), fmtRule(g.Rules[row[t]]))
+		}
+	}
+
+	if len(g.Conflicts) > 0 {
+		fmt.Fprintln(w, "\nConflicts:")
+		for _, err := range g.Conflicts {
+			fmt.Fprintf(w, "  %v\n", err)
+		}
+	}
+}
+
+// fmtRule formats a rule into a string like "A → B c"
+func fmtRule(r Rule) string {
+	result := r.LHS + " →"
+	for _, sym := range r.RHS {
+		switch s := sym.(type) {
+		case lexer.TokenType:
+			result += " " + s.String()
+		case string:
+			result += " " + s
+		}
+	}
+	if len(r.RHS) == 0 {
+		result += " ε"
+	}
+	return result
+}
+
+// primitiveType returns the type named by a type keyword.
+func primitiveType(keyword ast.ASTNode) types.Type {
+	// the lexer only makes type tokens of the names types.Lookup knows
+	p, _ := types.Lookup(keyword.(*ast.ASTSimpleExpression).Token.Lexeme)
+	return p
+}
+
+// signatureType returns the type named by typ, or an array of it if the
+// ArrayTypeSignature sig gives a length.
+func signatureType(typ, sig ast.ASTNode) types.Type {
+	t := typ.(*ast.ASTTypeNode).Type
+	if length, ok := sig.(*ast.ASTSimpleExpression); ok {
+		n, _ := strconv.Atoi(length.Token.Lexeme)
+		t = types.Array{Elem: t, Len: n}
+	}
+	return t
+}
+
+// varDeclType returns the declared type of a variable: the type named by
+// typ, or an array of it, as long as the declaration says, if init is an
+// array literal. The literal is given the same type.
+func varDeclType(typ, init ast.ASTNode) types.Type {
+	t := typ.(*ast.ASTTypeNode).Type
+	if arr, ok := init.(*ast.ASTArrayNode); ok {
+		t = types.Array{Elem: t, Len: arr.Size}
+		arr.Type = t
+	}
+	return t
+}
@@ -0,0 +1,77 @@
+package parser
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/giuszeppe/compiler-theory/lexer"
+)
+
+// TestGrammarIsLL1 is what keeps PArL's grammar free of conflicts, since
+// NewGrammar builds its table regardless.
+func TestGrammarIsLL1(t *testing.T) {
+	g := NewGrammar()
+	if len(g.Conflicts) != 0 {
+		t.Fatalf("Expected no conflicts, got %d: %v", len(g.Conflicts), g.Conflicts)
+	}
+}
+
+func TestGrammarFirstFirstConflict(t *testing.T) {
+	g := &Grammar{
+		StartSymbol: "S",
+		Rules: []Rule{
+			{LHS: "S", RHS: []Symbol{lexer.Identifier, lexer.SemicolonToken}},
+			{LHS: "S", RHS: []Symbol{lexer.Identifier, lexer.EqualsToken}},
+		},
+	}
+	_, conflicts := genTable(g)
+	if len(conflicts) != 1 {
+		t.Fatalf("Expected 1 conflict, got %d: %v", len(conflicts), conflicts)
+	}
+	c := conflicts[0].(*Conflict)
+	if c.Kind != FirstFirst || c.Nonterminal != "S" || c.Lookahead != lexer.Identifier {
+		t.Fatalf("Unexpected conflict: %v", c)
+	}
+	msg := c.Error()
+	if !strings.Contains(msg, fmtRule(g.Rules[0])) || !strings.Contains(msg, fmtRule(g.Rules[1])) {
+		t.Fatalf("Conflict should name both rules, got %q", msg)
+	}
+}
+
+func TestGrammarFirstFollowConflict(t *testing.T) {
+	// S → A Identifier ; A → Identifier | ε
+	g := &Grammar{
+		StartSymbol: "S",
+		Rules: []Rule{
+			{LHS: "S", RHS: []Symbol{"A", lexer.Identifier}},
+			{LHS: "A", RHS: []Symbol{lexer.Identifier}},
+			{LHS: "A", RHS: []Symbol{}},
+		},
+	}
+	table, conflicts := genTable(g)
+	if len(conflicts) != 1 {
+		t.Fatalf("Expected 1 conflict, got %d: %v", len(conflicts), conflicts)
+	}
+	c := conflicts[0].(*Conflict)
+	if c.Kind != FirstFollow || c.Nonterminal != "A" || c.Lookahead != lexer.Identifier {
+		t.Fatalf("Unexpected conflict: %v", c)
+	}
+	if table["A"][lexer.Identifier] != 1 {
+		t.Fatalf("Expected the earlier rule to keep the cell, got rule %d", table["A"][lexer.Identifier])
+	}
+}
+
+func TestGrammarReportIsStable(t *testing.T) {
+	var first, second bytes.Buffer
+	NewGrammar().WriteReport(&first)
+	NewGrammar().WriteReport(&second)
+	if first.String() != second.String() {
+		t.Fatalf("Grammar report differs between runs")
+	}
+	for _, section := range []string{"Nullable:", "FIRST:", "FOLLOW:", "LL(1) Parsing Table:"} {
+		if !strings.Contains(first.String(), section) {
+			t.Fatalf("Grammar report is missing section %q", section)
+		}
+	}
+}
@@ -0,0 +1,333 @@
+// Package parser builds the PArL LL(1) grammar and parses tokens into an AST.
+package parser
+
+import (
+	"github.com/giuszeppe/compiler-theory/ast"
+	"github.com/giuszeppe/compiler-theory/diag"
+	"github.com/giuszeppe/compiler-theory/lexer"
+)
+
+func NewParser(program string) Parser {
+	lex := lexer.NewLexer()
+	tokens := lex.GenerateTokens(program)
+	parser := Parser{
+		Name:       "Parser",
+		Lex:        &lex,
+		Index:      -1,
+		SrcProgram: program,
+		Tokens:     tokens,
+		CrtToken:   lexer.NewToken(lexer.Error, ""),
+		nextToken:  lexer.NewToken(lexer.Error, ""),
+		ASTRoot:    ast.ASTBlockNode{},
+	}
+	return parser
+
+}
+
+type Parser struct {
+	Name       string
+	Lex        *lexer.Lexer
+	Index      int
+	SrcProgram string
+	Tokens     []lexer.Token
+	CrtToken   lexer.Token
+	nextToken  lexer.Token
+	ASTRoot    ast.ASTBlockNode
+	Errors     []diag.Diagnostic
+}
+
+// Action builds the AST node for a rule from the nodes of its right-hand
+// side. The parser gives the returned node a span covering its children
+// unless the action has set one; an action that builds nested nodes of its
+// own (e.g. a chain of binary operations) must set their spans itself.
+type Action func(children []ast.ASTNode) ast.ASTNode
+
+type Rule struct {
+	LHS    string   // name of the nonterminal
+	RHS    []Symbol // sequence of terminals (TokenType) and nonterminals (string)
+	Action Action   // builds the AST node when this rule is reduced
+}
+
+type Grammar struct {
+	StartSymbol string
+	Rules       []Rule
+	Table       map[string]map[lexer.TokenType]int
+	Nullable    map[string]bool
+	First       map[string]map[lexer.TokenType]struct{}
+	Follow      map[string]map[lexer.TokenType]struct{}
+	// Conflicts holds a *Conflict for every table cell two rules compete
+	// for. It is empty for an LL(1) grammar.
+	Conflicts []error
+}
+
+// Symbol is either a terminal (TokenType) or a nonterminal (string).
+type Symbol interface{}
+
+func isTrivia(tok lexer.Token) bool {
+	return tok.Type == lexer.WhitespaceToken || tok.Type == lexer.NewLineToken || tok.Type == lexer.CommentSingleLine || tok.Type == lexer.CommentMultiLine
+}
+
+// isClosingToken reports whether t is a terminal the parser may pretend to
+// have seen when it is missing, e.g. the ';' in "let x:int = 1 let y:int = 2;".
+func isClosingToken(t lexer.TokenType) bool {
+	return t == lexer.SemicolonToken || t == lexer.RightCurlyToken || t == lexer.RightParenToken || t == lexer.RightBracketToken
+}
+
+// emptySpanAt returns the zero-width span just before tok.
+func emptySpanAt(tok lexer.Token) diag.Span {
+	start := diag.SpanOf(tok).Start
+	return diag.Span{Start: start, End: start}
+}
+
+// Parse performs an LL(1) parse of tokens against g, returning the root ASTNode.
+//
+// Syntax errors do not stop the parse. A missing ';', '}', ')' or ']' is
+// reported and assumed present; any other error discards the statement being
+// parsed, skipping input past the next ';' or up to the next '}' (see resync)
+// and leaving an ASTErrorNode in its place. Invalid characters are reported
+// and skipped. The returned error is
+// then a Diagnostics list holding every lexical and syntax error, which are
+// also kept in p.Errors.
+func (p *Parser) Parse(g *Grammar) (ast.ASTNode, error) {
+	tokens := p.Tokens
+	p.Errors = nil
+	// parsing table: g.Table[nonterminal][lookahead] = ruleIndex
+	table := g.Table
+
+	// stack of “what symbols remain” for each active rule
+	stack := make([][]Symbol, 0, 10)
+	stack = append(stack, []Symbol{g.StartSymbol})
+
+	// parallel stack of AST fragments.  Each frame’s first element is the Action,
+	// the rest are its (yet‑to‑be‑filled) children.
+	type frag struct {
+		act      Action
+		children []ast.ASTNode
+	}
+	astStack := make([]frag, 0, 10)
+	// dummy root action just returns its single child
+	astStack = append(astStack, frag{
+		act: func(ch []ast.ASTNode) ast.ASTNode { return ch[0] },
+	})
+
+	// epsilonRule returns the index of A → ε, if there is one
+	epsilonRule := func(A string) (int, bool) {
+		for i, rule := range g.Rules {
+			if rule.LHS == A && len(rule.RHS) == 0 {
+				return i, true
+			}
+		}
+		return -1, false
+	}
+
+	// inFollow reports whether t may follow a statement, i.e. whether it starts
+	// the next statement or closes the enclosing block. A missing closing
+	// token is assumed present when one of these comes instead.
+	inFollow := func(t lexer.TokenType) bool {
+		_, ok := g.Follow["Statement"][t]
+		return ok
+	}
+
+	pos := 0
+
+	// resync abandons the innermost statement being parsed, replacing it with
+	// an ASTErrorNode, and skips input up to the end of the statement: past
+	// the next ';' or the '}' closing a block the statement opened, or up to
+	// the '}' closing the enclosing block. Skipping to any token that can
+	// start a statement would resume inside the broken one, at an identifier
+	// say, and report its remains as errors of their own. It returns false
+	// if there is nothing left to skip.
+	resync := func() bool {
+		i := len(stack) - 1
+		for ; i > 0; i-- {
+			if parent := stack[i-1]; len(parent) > 0 && parent[0] == "Statement" {
+				break
+			}
+		}
+		if i == 0 {
+			// not inside a statement: drop the offending token and retry
+			if tokens[pos].Type == lexer.End {
+				return false
+			}
+			pos++
+			return true
+		}
+		stack = stack[:i]
+		astStack = astStack[:i]
+
+		errNode := &ast.ASTErrorNode{}
+		// next returns the type of the first token from pos that is not
+		// trivia
+		next := func() lexer.TokenType {
+			i := pos
+			for isTrivia(tokens[i]) {
+				i++
+			}
+			return tokens[i].Type
+		}
+		depth := 0
+	skip:
+		for tokens[pos].Type != lexer.End {
+			tok := tokens[pos]
+			if isTrivia(tok) {
+				pos++
+				continue
+			}
+			if tok.Type == lexer.RightCurlyToken && depth == 0 {
+				break
+			}
+			errNode.Skipped = append(errNode.Skipped, tok)
+			pos++
+			switch tok.Type {
+			case lexer.LeftCurlyToken:
+				depth++
+			case lexer.RightCurlyToken:
+				depth--
+				if depth == 0 && next() != lexer.Else {
+					break skip
+				}
+			case lexer.SemicolonToken:
+				if depth == 0 {
+					break skip
+				}
+			}
+		}
+
+		if len(errNode.Skipped) > 0 {
+			first := diag.SpanOf(errNode.Skipped[0])
+			last := diag.SpanOf(errNode.Skipped[len(errNode.Skipped)-1])
+			errNode.SetSpan(diag.Span{Start: first.Start, End: last.End})
+		} else {
+			errNode.SetSpan(emptySpanAt(tokens[pos]))
+		}
+
+		parent := &astStack[len(astStack)-1]
+		parent.children = append(parent.children, errNode)
+		parentFrame := &stack[len(stack)-1]
+		*parentFrame = (*parentFrame)[1:]
+		return true
+	}
+
+	for {
+		// if the root frame is empty, we’re done
+		if len(stack[0]) == 0 {
+			for isTrivia(tokens[pos]) {
+				pos++
+			}
+			if tokens[pos].Type != lexer.End {
+				p.Errors = append(p.Errors, ErrExtraneousInput(tokens[pos]))
+			}
+			break
+		}
+		tok := tokens[pos]
+		// Skip whitespace, newlines, and comments
+		if isTrivia(tok) {
+			pos++
+			continue
+		}
+		// Lexical errors are reported once and otherwise ignored
+		if tok.Type == lexer.Error {
+			p.Errors = append(p.Errors, ErrInvalidToken(tok))
+			pos++
+			continue
+		}
+
+		// look at innermost frame
+		topFrame := &stack[len(stack)-1]
+
+		// we’ve just completed a rule → reduce
+		if len(*topFrame) == 0 {
+			stack = stack[:len(stack)-1]
+			completed := astStack[len(astStack)-1]
+			astStack = astStack[:len(astStack)-1]
+
+			node := completed.act(completed.children)
+			if !node.Span().Start.IsValid() {
+				span := ast.Cover(completed.children...)
+				if !span.Start.IsValid() {
+					// an ε rule: an empty span where the input stands
+					span = emptySpanAt(tok)
+				}
+				node.SetSpan(span)
+			}
+
+			parent := &astStack[len(astStack)-1]
+			parent.children = append(parent.children, node)
+			// pop the nonterminal in the parent’s symbol list
+			parentFrame := &stack[len(stack)-1]
+			*parentFrame = (*parentFrame)[1:]
+			continue
+		}
+
+		// otherwise, we still have symbols to match
+		sym := (*topFrame)[0]
+
+		switch s := sym.(type) {
+		case lexer.TokenType:
+			// terminal: must match exactly
+			if tok.Type != s {
+				p.Errors = append(p.Errors, ErrUnexpectedToken(s, tok))
+				if isClosingToken(s) && (tok.Type == lexer.SemicolonToken || inFollow(tok.Type)) {
+					// assume the missing token was there and carry on
+					*topFrame = (*topFrame)[1:]
+					leaf := &ast.ASTSimpleExpression{Token: lexer.Token{Type: s, Line: tok.Line, Column: tok.Column, Offset: tok.Offset}}
+					leaf.SetSpan(emptySpanAt(tok))
+					astStack[len(astStack)-1].children = append(astStack[len(astStack)-1].children, leaf)
+				} else if !resync() {
+					return nil, diag.Diagnostics(p.Errors)
+				}
+				continue
+			}
+			// consume it
+			*topFrame = (*topFrame)[1:]
+			// wrap token into a leaf AST node
+			leaf := &ast.ASTSimpleExpression{Token: tok}
+			leaf.SetSpan(diag.SpanOf(tok))
+			astStack[len(astStack)-1].children = append(astStack[len(astStack)-1].children, leaf)
+			pos++
+
+		case string:
+			// nonterminal: consult table
+			ri, ok := table[s][tok.Type]
+			if !ok {
+				if s == "StmtList" {
+					// a token that cannot start a statement: skip it
+					p.Errors = append(p.Errors, ErrNoRule(s, tok))
+					pos++
+					continue
+				}
+				// defer the error to the next terminal by taking A → ε
+				if ri, ok = epsilonRule(s); !ok {
+					p.Errors = append(p.Errors, ErrNoRule(s, tok))
+					if !resync() {
+						return nil, diag.Diagnostics(p.Errors)
+					}
+					continue
+				}
+			}
+			rule := g.Rules[ri]
+
+			// push a new frame for that rule’s RHS
+			rhsCopy := make([]Symbol, len(rule.RHS))
+			copy(rhsCopy, rule.RHS)
+			stack = append(stack, rhsCopy)
+
+			// push its action to the AST stack
+			astStack = append(astStack, frag{
+				act: rule.Action,
+			})
+
+		default:
+			p.Errors = append(p.Errors, diag.NewError(CodeInvalidSymbol, diag.Span{}, "invalid symbol on stack: %T %#v", s, s))
+			return nil, diag.Diagnostics(p.Errors)
+		}
+	}
+
+	// apply the dummy root action
+	rootFrag := astStack[0]
+	root := rootFrag.act(rootFrag.children)
+	if len(p.Errors) > 0 {
+		return root, diag.Diagnostics(p.Errors)
+	}
+	return root, nil
+}
@@ -0,0 +1,933 @@
+package parser
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/giuszeppe/compiler-theory/ast"
+	"github.com/giuszeppe/compiler-theory/diag"
+	"github.com/giuszeppe/compiler-theory/lexer"
+	"github.com/giuszeppe/compiler-theory/types"
+)
+
+func assertASTNodeEqual(t *testing.T, expected, actual ast.ASTNode) {
+	if expected == nil && actual == nil {
+		return
+	}
+	if expected == nil || actual == nil {
+		t.Fatalf("AST nodes are not equal: expected %v, got %v", expected, actual)
+	}
+	if fmt.Sprintf("%T", expected) != fmt.Sprintf("%T", actual) {
+		t.Fatalf("AST node types are not equal: expected %T, got %T", expected, actual)
+	}
+
+	switch e := expected.(type) {
+	case *ast.ASTProgramNode:
+		a := actual.(*ast.ASTProgramNode)
+		assertASTNodeEqual(t, &e.Block, &a.Block)
+	case *ast.ASTBlockNode:
+		a := actual.(*ast.ASTBlockNode)
+		if len(e.Stmts) != len(a.Stmts) {
+			t.Fatalf("AST block statements length mismatch: expected %d, got %d", len(e.Stmts), len(a.Stmts))
+		}
+		for i := range e.Stmts {
+			assertASTNodeEqual(t, e.Stmts[i], a.Stmts[i])
+		}
+	case *ast.ASTAssignmentNode:
+		a := actual.(*ast.ASTAssignmentNode)
+		assertASTNodeEqual(t, &e.Id, &a.Id)
+		assertASTNodeEqual(t, e.Expr, a.Expr)
+	case *ast.ASTVariableNode:
+		a := actual.(*ast.ASTVariableNode)
+		if e.Token.Lexeme != a.Token.Lexeme {
+			t.Fatalf("AST variable tokens are not equal: expected %v, got %v", e.Token, a.Token)
+		}
+	case *ast.ASTIntegerNode:
+		a := actual.(*ast.ASTIntegerNode)
+		if e.Value != a.Value {
+			t.Fatalf("AST integer values are not equal: expected %d, got %d", e.Value, a.Value)
+		}
+	case *ast.ASTVarDeclNode:
+		a := actual.(*ast.ASTVarDeclNode)
+		if e.Token.Lexeme != a.Token.Lexeme {
+			t.Fatalf("AST variable declaration names are not equal: expected %s, got %s", e.Token.Lexeme, a.Token.Lexeme)
+		}
+		if e.Type != a.Type {
+			t.Fatalf("AST variable declaration types are not equal: expected %s, got %s", e.Type, a.Type)
+		}
+		assertASTNodeEqual(t, e.Expression, a.Expression)
+	case *ast.ASTTypeNode:
+		a := actual.(*ast.ASTTypeNode)
+		if e.Type != a.Type {
+			t.Fatalf("AST types are not equal: expected %s, got %s", e.Type, a.Type)
+		}
+	case *ast.ASTFuncDeclNode:
+		a := actual.(*ast.ASTFuncDeclNode)
+		if e.Token.Lexeme != a.Token.Lexeme {
+			t.Fatalf("AST function names are not equal: expected %s, got %s", e.Token.Lexeme, a.Token.Lexeme)
+		}
+		if e.ReturnType != a.ReturnType {
+			t.Fatalf("AST function return types are not equal: expected %s, got %s", e.ReturnType, a.ReturnType)
+		}
+
+		assertASTNodeEqual(t, e.Params, a.Params)
+		assertASTNodeEqual(t, e.Block, a.Block)
+	case *ast.ASTIfNode:
+		a := actual.(*ast.ASTIfNode)
+		assertASTNodeEqual(t, e.Condition, a.Condition)
+		assertASTNodeEqual(t, e.ThenBlock, a.ThenBlock)
+		if e.ElseBlock != nil && a.ElseBlock != nil {
+			assertASTNodeEqual(t, e.ElseBlock, a.ElseBlock)
+		} else if e.ElseBlock != nil || a.ElseBlock != nil {
+			t.Fatalf("AST if nodes else body mismatch: expected %v, got %v", e.ElseBlock, a.ElseBlock)
+		}
+	case *ast.ASTFormalParamsNode:
+		a := actual.(*ast.ASTFormalParamsNode)
+		if len(e.Params) != len(a.Params) {
+			t.Fatalf("AST formal parameters length mismatch: expected %d, got %d", len(e.Params), len(a.Params))
+		}
+		for i := range e.Params {
+			assertASTNodeEqual(t, e.Params[i], a.Params[i])
+		}
+	case *ast.ASTActualParamsNode:
+		a := actual.(*ast.ASTActualParamsNode)
+		if len(e.Params) != len(a.Params) {
+			t.Fatalf("AST actual parameters length mismatch: expected %d, got %d", len(e.Params), len(a.Params))
+		}
+		for i := range e.Params {
+			assertASTNodeEqual(t, e.Params[i], a.Params[i])
+		}
+	case *ast.ASTFuncCallNode:
+		a := actual.(*ast.ASTFuncCallNode)
+		if e.Name.Lexeme != a.Name.Lexeme {
+			t.Fatalf("AST function call names are not equal: expected %s, got %s", e.Name.Lexeme, a.Name.Lexeme)
+		}
+		assertASTNodeEqual(t, e.Params, a.Params)
+	case *ast.ASTBinaryOpNode:
+		a := actual.(*ast.ASTBinaryOpNode)
+		assertASTNodeEqual(t, e.Left, a.Left)
+		if e.Operator != a.Operator {
+			t.Fatalf("AST binary operator mismatch: expected %s, got %s", e.Operator, a.Operator)
+		}
+		assertASTNodeEqual(t, e.Right, a.Right)
+	case *ast.ASTFormalParamNode:
+		a := actual.(*ast.ASTFormalParamNode)
+		if e.Name != a.Name {
+			t.Fatalf("AST formal parameter names are not equal: expected %s, got %s", e.Name, a.Name)
+		}
+		if e.Type != a.Type {
+			t.Fatalf("AST formal parameter types are not equal: expected %s, got %s", e.Type, a.Type)
+		}
+	case *ast.ASTActualParamNode:
+		a := actual.(*ast.ASTActualParamNode)
+		assertASTNodeEqual(t, e.Value, a.Value)
+	case *ast.ASTWhileNode:
+		a := actual.(*ast.ASTWhileNode)
+		assertASTNodeEqual(t, e.Condition, a.Condition)
+		assertASTNodeEqual(t, e.Block, a.Block)
+	case *ast.ASTUnaryOpNode:
+		a := actual.(*ast.ASTUnaryOpNode)
+		if e.Operator != a.Operator {
+			t.Fatalf("AST unary operator mismatch: expected %s, got %s", e.Operator, a.Operator)
+		}
+		assertASTNodeEqual(t, e.Operand, a.Operand)
+	case *ast.ASTBooleanNode:
+		a := actual.(*ast.ASTBooleanNode)
+		if e.Value != a.Value {
+			t.Fatalf("AST boolean values are not equal: expected %v, got %v", e.Value, a.Value)
+		}
+	case *ast.ASTReturnNode:
+		a := actual.(*ast.ASTReturnNode)
+		assertASTNodeEqual(t, e.Expr, a.Expr)
+	case *ast.ASTColorNode:
+		a := actual.(*ast.ASTColorNode)
+		if e.Value != a.Value {
+			t.Fatalf("AST color values are not equal: expected %s, got %s", e.Value, a.Value)
+		}
+	case *ast.ASTArrayNode:
+		a := actual.(*ast.ASTArrayNode)
+		if len(e.Items) != len(a.Items) {
+			t.Fatalf("AST array elements length mismatch: expected %d, got %d", len(e.Items), len(a.Items))
+		}
+
+		for i := range e.Items {
+			assertASTNodeEqual(t, e.Items[i], a.Items[i])
+		}
+
+		if e.Type != a.Type {
+			t.Fatalf("AST array types are not equal: expected %s, got %s", e.Type, a.Type)
+		}
+	case *ast.ASTExpressionNode:
+		a := actual.(*ast.ASTExpressionNode)
+		assertASTNodeEqual(t, e.Expr, a.Expr)
+	case *ast.ASTEpsilon:
+	case *ast.ASTErrorNode:
+		a := actual.(*ast.ASTErrorNode)
+		if len(e.Skipped) != len(a.Skipped) {
+			t.Fatalf("AST error node skipped tokens mismatch: expected %d, got %d", len(e.Skipped), len(a.Skipped))
+		}
+	default:
+		t.Fatalf("Unsupported AST node type: %T", expected)
+	}
+}
+func TestParsingAssignment(t *testing.T) {
+	program := "x = 2;"
+	parser := NewParser(program)
+	grammar := NewGrammar()
+	node, err := parser.Parse(grammar)
+	if err != nil {
+		t.Fatalf("Failed to parse program: %v", err)
+	}
+
+	expectedAST := &ast.ASTProgramNode{
+		Block: ast.ASTBlockNode{Stmts: []ast.ASTNode{
+			&ast.ASTAssignmentNode{
+				Id:   ast.ASTVariableNode{Token: lexer.Token{Type: lexer.Identifier, Lexeme: "x"}},
+				Expr: &ast.ASTIntegerNode{Value: 2},
+			},
+		}},
+	}
+
+	assertASTNodeEqual(t, expectedAST, node)
+}
+
+func TestParsingIntVariableDeclaration(t *testing.T) {
+	program := "let x:int = 2;"
+	parser := NewParser(program)
+	grammar := NewGrammar()
+	node, err := parser.Parse(grammar)
+	if err != nil {
+		t.Fatalf("Failed to parse program: %v", err)
+	}
+
+	expectedAST := &ast.ASTProgramNode{
+		Block: ast.ASTBlockNode{Stmts: []ast.ASTNode{
+			&ast.ASTVarDeclNode{
+				Token:      lexer.Token{Type: lexer.Identifier, Lexeme: "x"},
+				Type:       types.Int,
+				Expression: &ast.ASTIntegerNode{Value: 2},
+			},
+		}},
+	}
+
+	assertASTNodeEqual(t, expectedAST, node)
+}
+
+func TestParsingFunctionDeclaration(t *testing.T) {
+	program := "fun main(a:int, b:int) -> int { a = a + b; }"
+	parser := NewParser(program)
+	grammar := NewGrammar()
+	node, err := parser.Parse(grammar)
+	if err != nil {
+		t.Fatalf("Failed to parse program: %v", err)
+	}
+
+	expectedAST := &ast.ASTProgramNode{
+		Block: ast.ASTBlockNode{Stmts: []ast.ASTNode{
+			&ast.ASTFuncDeclNode{
+				Token:      lexer.Token{Type: lexer.Identifier, Lexeme: "main"},
+				ReturnType: types.Int,
+				Params: &ast.ASTFormalParamsNode{
+					Params: []ast.ASTNode{
+						&ast.ASTVarDeclNode{Token: lexer.Token{Type: lexer.Identifier, Lexeme: "a"}, Type: types.Int, Expression: &ast.ASTExpressionNode{Expr: &ast.ASTEpsilon{}}},
+						&ast.ASTVarDeclNode{Token: lexer.Token{Type: lexer.Identifier, Lexeme: "b"}, Type: types.Int, Expression: &ast.ASTExpressionNode{Expr: &ast.ASTEpsilon{}}},
+					},
+				},
+				Block: &ast.ASTBlockNode{
+					Stmts: []ast.ASTNode{
+						&ast.ASTAssignmentNode{
+							Id: ast.ASTVariableNode{Token: lexer.Token{Type: lexer.Identifier, Lexeme: "a"}},
+
+							Expr: &ast.ASTBinaryOpNode{
+								Left:     &ast.ASTVariableNode{Token: lexer.Token{Type: lexer.Identifier, Lexeme: "a"}},
+								Operator: "+",
+								Right:    &ast.ASTVariableNode{Token: lexer.Token{Type: lexer.Identifier, Lexeme: "b"}},
+							},
+						},
+					},
+				},
+			},
+		}},
+	}
+
+	assertASTNodeEqual(t, expectedAST, node)
+}
+func TestParsingIfStatement(t *testing.T) {
+	program := "if (x > 0) { y = 1; } else { y = 1; }"
+	parser := NewParser(program)
+	grammar := NewGrammar()
+	node, err := parser.Parse(grammar)
+	if err != nil {
+		t.Fatalf("Failed to parse program: %v", err)
+	}
+
+	expectedAST := &ast.ASTProgramNode{
+		Block: ast.ASTBlockNode{Stmts: []ast.ASTNode{
+			&ast.ASTIfNode{
+				Condition: &ast.ASTBinaryOpNode{
+					Left:     &ast.ASTVariableNode{Token: lexer.Token{Type: lexer.Identifier, Lexeme: "x"}},
+					Operator: ">",
+					Right:    &ast.ASTIntegerNode{Value: 0},
+				},
+				ThenBlock: &ast.ASTBlockNode{Stmts: []ast.ASTNode{
+					&ast.ASTAssignmentNode{
+						Id:   ast.ASTVariableNode{Token: lexer.Token{Type: lexer.Identifier, Lexeme: "y"}},
+						Expr: &ast.ASTIntegerNode{Value: 1},
+					},
+				}},
+				ElseBlock: &ast.ASTBlockNode{Stmts: []ast.ASTNode{
+					&ast.ASTAssignmentNode{
+						Id:   ast.ASTVariableNode{Token: lexer.Token{Type: lexer.Identifier, Lexeme: "y"}},
+						Expr: &ast.ASTIntegerNode{Value: 1},
+					},
+				}},
+			},
+		}},
+	}
+
+	assertASTNodeEqual(t, expectedAST, node)
+}
+
+func TestParsingWhileLoop(t *testing.T) {
+	program := "while x < 10 { x = x + 1; }"
+	parser := NewParser(program)
+	grammar := NewGrammar()
+	node, err := parser.Parse(grammar)
+	if err != nil {
+		t.Fatalf("Failed to parse program: %v", err)
+	}
+
+	expectedAST := &ast.ASTProgramNode{
+		Block: ast.ASTBlockNode{Stmts: []ast.ASTNode{
+			&ast.ASTWhileNode{
+				Condition: &ast.ASTBinaryOpNode{
+					Left:     &ast.ASTVariableNode{Token: lexer.Token{Type: lexer.Identifier, Lexeme: "x"}},
+					Operator: "<",
+					Right:    &ast.ASTIntegerNode{Value: 10},
+				},
+				Block: &ast.ASTBlockNode{Stmts: []ast.ASTNode{
+					&ast.ASTAssignmentNode{
+						Id: ast.ASTVariableNode{Token: lexer.Token{Type: lexer.Identifier, Lexeme: "x"}},
+						Expr: &ast.ASTBinaryOpNode{
+							Left:     &ast.ASTVariableNode{Token: lexer.Token{Type: lexer.Identifier, Lexeme: "x"}},
+							Operator: "+",
+							Right:    &ast.ASTIntegerNode{Value: 1},
+						},
+					},
+				}},
+			},
+		}},
+	}
+
+	assertASTNodeEqual(t, expectedAST, node)
+}
+
+func TestParsingNestedBlocks(t *testing.T) {
+	program := "{ let x:int = 5; { let y:int = 10; } }"
+	parser := NewParser(program)
+	grammar := NewGrammar()
+	node, err := parser.Parse(grammar)
+	if err != nil {
+		t.Fatalf("Failed to parse program: %v", err)
+	}
+
+	expectedAST := &ast.ASTProgramNode{
+		Block: ast.ASTBlockNode{Stmts: []ast.ASTNode{
+			&ast.ASTVarDeclNode{
+				Token:      lexer.Token{Type: lexer.Identifier, Lexeme: "x"},
+				Type:       types.Int,
+				Expression: &ast.ASTIntegerNode{Value: 5},
+			},
+			&ast.ASTBlockNode{Stmts: []ast.ASTNode{
+				&ast.ASTVarDeclNode{
+					Token:      lexer.Token{Type: lexer.Identifier, Lexeme: "y"},
+					Type:       types.Int,
+					Expression: &ast.ASTIntegerNode{Value: 10},
+				},
+			}},
+		}},
+	}
+
+	assertASTNodeEqual(t, expectedAST, node)
+}
+
+func TestParsingFunctionCall(t *testing.T) {
+	program := "result = add(3, 4);"
+	parser := NewParser(program)
+	grammar := NewGrammar()
+	node, err := parser.Parse(grammar)
+	if err != nil {
+		t.Fatalf("Failed to parse program: %v", err)
+	}
+
+	expectedAST := &ast.ASTProgramNode{
+		Block: ast.ASTBlockNode{Stmts: []ast.ASTNode{
+			&ast.ASTAssignmentNode{
+				Id: ast.ASTVariableNode{Token: lexer.Token{Type: lexer.Identifier, Lexeme: "result"}},
+				Expr: &ast.ASTFuncCallNode{
+					Name: lexer.Token{Type: lexer.Identifier, Lexeme: "add"},
+					Params: &ast.ASTActualParamsNode{
+						Params: []ast.ASTNode{
+							&ast.ASTIntegerNode{Value: 3},
+							&ast.ASTIntegerNode{Value: 4},
+						},
+					},
+				},
+			},
+		}},
+	}
+
+	assertASTNodeEqual(t, expectedAST, node)
+}
+func TestParsingInvalidInput(t *testing.T) {
+	program := "x +"
+	parser := NewParser(program)
+	grammar := NewGrammar()
+	_, err := parser.Parse(grammar)
+	if err == nil {
+		t.Fatalf("Expected parsing to fail for invalid input, but it succeeded")
+	}
+}
+
+func TestParsingUnaryInput(t *testing.T) {
+	program := "a = -x;"
+	parser := NewParser(program)
+	grammar := NewGrammar()
+	node, err := parser.Parse(grammar)
+	if err != nil {
+		t.Fatalf("Failed to parse program: %v", err)
+	}
+
+	expectedAST := &ast.ASTProgramNode{
+		Block: ast.ASTBlockNode{Stmts: []ast.ASTNode{
+			&ast.ASTAssignmentNode{
+				Id: ast.ASTVariableNode{Token: lexer.Token{Type: lexer.Identifier, Lexeme: "a"}},
+				Expr: &ast.ASTUnaryOpNode{
+					Operator: "-",
+					Operand:  &ast.ASTVariableNode{Token: lexer.Token{Type: lexer.Identifier, Lexeme: "x"}},
+				},
+			},
+		}},
+	}
+
+	assertASTNodeEqual(t, expectedAST, node)
+}
+
+func TestParsingUnaryInputOnFunctionCall(t *testing.T) {
+	program := "a = -add(3, 4);"
+	parser := NewParser(program)
+	grammar := NewGrammar()
+	node, err := parser.Parse(grammar)
+	if err != nil {
+		t.Fatalf("Failed to parse program: %v", err)
+	}
+
+	expectedAST := &ast.ASTProgramNode{
+		Block: ast.ASTBlockNode{Stmts: []ast.ASTNode{
+			&ast.ASTAssignmentNode{
+				Id: ast.ASTVariableNode{Token: lexer.Token{Type: lexer.Identifier, Lexeme: "a"}},
+				Expr: &ast.ASTUnaryOpNode{
+					Operator: "-",
+					Operand: &ast.ASTFuncCallNode{
+						Name: lexer.Token{Type: lexer.Identifier, Lexeme: "add"},
+						Params: &ast.ASTActualParamsNode{
+							Params: []ast.ASTNode{
+								&ast.ASTIntegerNode{Value: 3},
+								&ast.ASTIntegerNode{Value: 4},
+							},
+						},
+					},
+				},
+			},
+		}},
+	}
+
+	assertASTNodeEqual(t, expectedAST, node)
+}
+
+func TestParsingDoubleUnaryInput(t *testing.T) {
+	program := "a = --x;"
+	parser := NewParser(program)
+	grammar := NewGrammar()
+	node, err := parser.Parse(grammar)
+	if err != nil {
+		t.Fatalf("Failed to parse program: %v", err)
+	}
+
+	expectedAST := &ast.ASTProgramNode{
+		Block: ast.ASTBlockNode{Stmts: []ast.ASTNode{
+			&ast.ASTAssignmentNode{
+				Id: ast.ASTVariableNode{Token: lexer.Token{Type: lexer.Identifier, Lexeme: "a"}},
+				Expr: &ast.ASTUnaryOpNode{
+					Operator: "-",
+					Operand: &ast.ASTUnaryOpNode{
+						Operator: "-",
+						Operand:  &ast.ASTVariableNode{Token: lexer.Token{Type: lexer.Identifier, Lexeme: "x"}},
+					},
+				},
+			},
+		}},
+	}
+
+	assertASTNodeEqual(t, expectedAST, node)
+}
+
+func TestParsingNegativeInteger(t *testing.T) {
+	program := "a = -5;"
+	parser := NewParser(program)
+	grammar := NewGrammar()
+	node, err := parser.Parse(grammar)
+	if err != nil {
+		t.Fatalf("Failed to parse program: %v", err)
+	}
+
+	expectedAST := &ast.ASTProgramNode{
+		Block: ast.ASTBlockNode{Stmts: []ast.ASTNode{
+			&ast.ASTAssignmentNode{
+				Id: ast.ASTVariableNode{Token: lexer.Token{Type: lexer.Identifier, Lexeme: "a"}},
+				Expr: &ast.ASTUnaryOpNode{
+					Operator: "-",
+					Operand:  &ast.ASTIntegerNode{Value: 5},
+				},
+			},
+		}},
+	}
+
+	assertASTNodeEqual(t, expectedAST, node)
+}
+
+func TestParsingMultiplicativeOperators(t *testing.T) {
+	program := "a = 3 * 4 / 2 and 5;"
+	parser := NewParser(program)
+	grammar := NewGrammar()
+	node, err := parser.Parse(grammar)
+	if err != nil {
+		t.Fatalf("Failed to parse program: %v", err)
+	}
+
+	expectedAST := &ast.ASTProgramNode{
+		Block: ast.ASTBlockNode{Stmts: []ast.ASTNode{
+			&ast.ASTAssignmentNode{
+				Id: ast.ASTVariableNode{Token: lexer.Token{Type: lexer.Identifier, Lexeme: "a"}},
+				Expr: &ast.ASTBinaryOpNode{
+					Left: &ast.ASTBinaryOpNode{
+						Left: &ast.ASTBinaryOpNode{
+							Left:     &ast.ASTIntegerNode{Value: 3},
+							Operator: "*",
+							Right:    &ast.ASTIntegerNode{Value: 4},
+						},
+						Operator: "/",
+						Right:    &ast.ASTIntegerNode{Value: 2},
+					},
+					Operator: "and",
+					Right:    &ast.ASTIntegerNode{Value: 5},
+				},
+			},
+		}},
+	}
+
+	assertASTNodeEqual(t, expectedAST, node)
+}
+
+func TestParsingAdditiveOperators(t *testing.T) {
+	program := "a = 3 + 4 - 2 or 5;"
+	parser := NewParser(program)
+	grammar := NewGrammar()
+	node, err := parser.Parse(grammar)
+	if err != nil {
+		t.Fatalf("Failed to parse program: %v", err)
+	}
+
+	expectedAST := &ast.ASTProgramNode{
+		Block: ast.ASTBlockNode{Stmts: []ast.ASTNode{
+			&ast.ASTAssignmentNode{
+				Id: ast.ASTVariableNode{Token: lexer.Token{Type: lexer.Identifier, Lexeme: "a"}},
+				Expr: &ast.ASTBinaryOpNode{
+
+					Left: &ast.ASTBinaryOpNode{
+						Left: &ast.ASTBinaryOpNode{
+							Left:     &ast.ASTIntegerNode{Value: 3},
+							Operator: "+",
+							Right:    &ast.ASTIntegerNode{Value: 4},
+						},
+						Operator: "-",
+						Right:    &ast.ASTIntegerNode{Value: 2},
+					},
+					Operator: "or",
+					Right:    &ast.ASTIntegerNode{Value: 5},
+				},
+			},
+		}},
+	}
+
+	assertASTNodeEqual(t, expectedAST, node)
+}
+
+func TestParsingUnaryOperators(t *testing.T) {
+	program := "a = not x;"
+	parser := NewParser(program)
+	grammar := NewGrammar()
+	node, err := parser.Parse(grammar)
+	if err != nil {
+		t.Fatalf("Failed to parse program: %v", err)
+	}
+
+	expectedAST := &ast.ASTProgramNode{
+		Block: ast.ASTBlockNode{Stmts: []ast.ASTNode{
+			&ast.ASTAssignmentNode{
+				Id: ast.ASTVariableNode{Token: lexer.Token{Type: lexer.Identifier, Lexeme: "a"}},
+				Expr: &ast.ASTUnaryOpNode{
+					Operator: "not",
+					Operand:  &ast.ASTVariableNode{Token: lexer.Token{Type: lexer.Identifier, Lexeme: "x"}},
+				},
+			},
+		}},
+	}
+
+	assertASTNodeEqual(t, expectedAST, node)
+}
+
+func TestParsingTrueFalse(t *testing.T) {
+	program := "a = true;b = false;"
+	parser := NewParser(program)
+	grammar := NewGrammar()
+	node, err := parser.Parse(grammar)
+	if err != nil {
+		t.Fatalf("Failed to parse program: %v", err)
+	}
+
+	expectedAST := &ast.ASTProgramNode{
+		Block: ast.ASTBlockNode{Stmts: []ast.ASTNode{
+			&ast.ASTAssignmentNode{
+				Id:   ast.ASTVariableNode{Token: lexer.Token{Type: lexer.Identifier, Lexeme: "a"}},
+				Expr: &ast.ASTBooleanNode{Value: true},
+			},
+			&ast.ASTAssignmentNode{
+				Id:   ast.ASTVariableNode{Token: lexer.Token{Type: lexer.Identifier, Lexeme: "b"}},
+				Expr: &ast.ASTBooleanNode{Value: false},
+			},
+		}},
+	}
+
+	assertASTNodeEqual(t, expectedAST, node)
+}
+
+func TestParsingColor(t *testing.T) {
+	program := "a = #ffaabb;"
+	parser := NewParser(program)
+	grammar := NewGrammar()
+	node, err := parser.Parse(grammar)
+	if err != nil {
+		t.Fatalf("Failed to parse program: %v", err)
+	}
+
+	expectedAST := &ast.ASTProgramNode{
+		Block: ast.ASTBlockNode{Stmts: []ast.ASTNode{
+			&ast.ASTAssignmentNode{
+				Id: ast.ASTVariableNode{Token: lexer.Token{Type: lexer.Identifier, Lexeme: "a"}},
+				Expr: &ast.ASTColorNode{
+					Value: "#ffaabb",
+				},
+			},
+		}},
+	}
+
+	assertASTNodeEqual(t, expectedAST, node)
+}
+
+func TestReturnStatement(t *testing.T) {
+	program := "return x;"
+	parser := NewParser(program)
+	grammar := NewGrammar()
+	node, err := parser.Parse(grammar)
+	if err != nil {
+		t.Fatalf("Failed to parse program: %v", err)
+	}
+
+	expectedAST := &ast.ASTProgramNode{
+		Block: ast.ASTBlockNode{Stmts: []ast.ASTNode{
+			&ast.ASTReturnNode{
+				Expr: &ast.ASTVariableNode{Token: lexer.Token{Type: lexer.Identifier, Lexeme: "x"}},
+			},
+		}},
+	}
+
+	assertASTNodeEqual(t, expectedAST, node)
+}
+
+func TestParsingEmptyProgram(t *testing.T) {
+	program := ""
+	parser := NewParser(program)
+	grammar := NewGrammar()
+	_, err := parser.Parse(grammar)
+	if err != nil {
+		t.Fatalf("Expected parsing to succeed for empty program, but it failed: %v", err)
+	}
+}
+
+func TestParsingArrVarDecl(t *testing.T) {
+	program := "let list_of_integers:int[5] = [23, 54, 3, 65, 99, 120, 34, 21];"
+	parser := NewParser(program)
+	grammar := NewGrammar()
+	node, err := parser.Parse(grammar)
+	if err != nil {
+		t.Fatalf("Failed to parse program: %v", err)
+	}
+
+	expectedAST := &ast.ASTProgramNode{
+		Block: ast.ASTBlockNode{Stmts: []ast.ASTNode{
+			&ast.ASTVarDeclNode{
+				Token: lexer.Token{Type: lexer.Identifier, Lexeme: "list_of_integers"},
+				Type:  types.Array{Elem: types.Int, Len: 5},
+				Expression: &ast.ASTArrayNode{
+					Type: types.Array{Elem: types.Int, Len: 5},
+					Items: []ast.ASTNode{
+						&ast.ASTIntegerNode{Value: 23},
+						&ast.ASTIntegerNode{Value: 54},
+						&ast.ASTIntegerNode{Value: 3},
+						&ast.ASTIntegerNode{Value: 65},
+						&ast.ASTIntegerNode{Value: 99},
+						&ast.ASTIntegerNode{Value: 120},
+						&ast.ASTIntegerNode{Value: 34},
+						&ast.ASTIntegerNode{Value: 21},
+					}},
+			},
+		}},
+	}
+
+	assertASTNodeEqual(t, expectedAST, node)
+}
+
+func TestParsingArrDeclarationWithoutArrSize(t *testing.T) {
+	program := "let list_of_integers:int[] = [23, 54, 3];"
+	parser := NewParser(program)
+	grammar := NewGrammar()
+	node, err := parser.Parse(grammar)
+	if err != nil {
+		t.Fatalf("Failed to parse program: %v", err)
+	}
+
+	expectedAST := &ast.ASTProgramNode{
+		Block: ast.ASTBlockNode{Stmts: []ast.ASTNode{
+			&ast.ASTVarDeclNode{
+				Token: lexer.Token{Type: lexer.Identifier, Lexeme: "list_of_integers"},
+				Type:  types.Array{Elem: types.Int, Len: 3},
+				Expression: &ast.ASTArrayNode{
+					Type: types.Array{Elem: types.Int, Len: 3},
+					Items: []ast.ASTNode{
+						&ast.ASTIntegerNode{Value: 23},
+						&ast.ASTIntegerNode{Value: 54},
+						&ast.ASTIntegerNode{Value: 3},
+					}},
+			},
+		}},
+	}
+
+	assertASTNodeEqual(t, expectedAST, node)
+}
+
+func TestParsingArrayOffsetAccessAsFactor(t *testing.T) {
+	program := "a = arr[1];"
+	parser := NewParser(program)
+	grammar := NewGrammar()
+	node, err := parser.Parse(grammar)
+	if err != nil {
+		t.Fatalf("Failed to parse program: %v", err)
+	}
+
+	expectedAST := &ast.ASTProgramNode{
+		Block: ast.ASTBlockNode{Stmts: []ast.ASTNode{
+			&ast.ASTAssignmentNode{
+				Id: ast.ASTVariableNode{Token: lexer.Token{Type: lexer.Identifier, Lexeme: "a"}},
+				Expr: &ast.ASTVariableNode{
+					Token:  lexer.Token{Type: lexer.Identifier, Lexeme: "arr"},
+					Offset: &ast.ASTIntegerNode{Value: 3},
+				},
+			},
+		}},
+	}
+
+	assertASTNodeEqual(t, expectedAST, node)
+}
+
+func TestParsingArrayAccessAssignment(t *testing.T) {
+	program := "arr[1] = 5;"
+	parser := NewParser(program)
+	grammar := NewGrammar()
+	node, err := parser.Parse(grammar)
+	if err != nil {
+		t.Fatalf("Failed to parse program: %v", err)
+	}
+
+	expectedAST := &ast.ASTProgramNode{
+		Block: ast.ASTBlockNode{Stmts: []ast.ASTNode{
+			&ast.ASTAssignmentNode{
+				Id: ast.ASTVariableNode{
+					Token:  lexer.Token{Type: lexer.Identifier, Lexeme: "arr"},
+					Offset: &ast.ASTIntegerNode{Value: 1},
+				},
+				Expr: &ast.ASTIntegerNode{Value: 5},
+			},
+		}},
+	}
+
+	assertASTNodeEqual(t, expectedAST, node)
+}
+
+func TestParsingArrayAccessAssignmentVariable(t *testing.T) {
+	program := "arr[x] = 5;"
+	parser := NewParser(program)
+	grammar := NewGrammar()
+	node, err := parser.Parse(grammar)
+	if err != nil {
+		t.Fatalf("Failed to parse program: %v", err)
+	}
+
+	expectedAST := &ast.ASTProgramNode{
+		Block: ast.ASTBlockNode{Stmts: []ast.ASTNode{
+			&ast.ASTAssignmentNode{
+				Id: ast.ASTVariableNode{
+					Token:  lexer.Token{Type: lexer.Identifier, Lexeme: "arr"},
+					Offset: &ast.ASTVariableNode{Token: lexer.Token{Type: lexer.Identifier, Lexeme: "x"}},
+				},
+				Expr: &ast.ASTIntegerNode{Value: 5},
+			},
+		}},
+	}
+
+	assertASTNodeEqual(t, expectedAST, node)
+}
+
+func expectSyntaxErrors(t *testing.T, err error, codes ...string) {
+	t.Helper()
+	diags, ok := err.(diag.Diagnostics)
+	if !ok {
+		t.Fatalf("Expected Diagnostics, got %T: %v", err, err)
+	}
+	if len(diags) != len(codes) {
+		t.Fatalf("Expected %d errors, got %d:\n%v", len(codes), len(diags), diags)
+	}
+	for i, code := range codes {
+		if diags[i].Code != code {
+			t.Fatalf("Error %d: expected %s, got %s (%v)", i, code, diags[i].Code, diags[i])
+		}
+	}
+}
+
+func TestParsingReportsMultipleErrors(t *testing.T) {
+	program := "let x:int = ;\nx = 1;\ny = * 2;\nlet z:int = 3;"
+	parser := NewParser(program)
+	grammar := NewGrammar()
+	node, err := parser.Parse(grammar)
+	expectSyntaxErrors(t, err, CodeNoRule, CodeNoRule)
+
+	expectedAST := &ast.ASTProgramNode{
+		Block: ast.ASTBlockNode{Stmts: []ast.ASTNode{
+			&ast.ASTErrorNode{Skipped: make([]lexer.Token, 1)},
+			&ast.ASTAssignmentNode{
+				Id:   ast.ASTVariableNode{Token: lexer.Token{Type: lexer.Identifier, Lexeme: "x"}},
+				Expr: &ast.ASTIntegerNode{Value: 1},
+			},
+			&ast.ASTErrorNode{Skipped: make([]lexer.Token, 3)},
+			&ast.ASTVarDeclNode{
+				Token:      lexer.Token{Type: lexer.Identifier, Lexeme: "z"},
+				Type:       types.Int,
+				Expression: &ast.ASTIntegerNode{Value: 3},
+			},
+		}},
+	}
+	assertASTNodeEqual(t, expectedAST, node)
+
+	if line := parser.Errors[1].Span.Start.Line; line != 3 {
+		t.Fatalf("Expected second error on line 3, got %d", line)
+	}
+}
+
+func TestParsingInsertsMissingSemicolon(t *testing.T) {
+	program := "x = 1\ny = 2;"
+	parser := NewParser(program)
+	grammar := NewGrammar()
+	node, err := parser.Parse(grammar)
+	expectSyntaxErrors(t, err, CodeUnexpectedToken)
+
+	expectedAST := &ast.ASTProgramNode{
+		Block: ast.ASTBlockNode{Stmts: []ast.ASTNode{
+			&ast.ASTAssignmentNode{
+				Id:   ast.ASTVariableNode{Token: lexer.Token{Type: lexer.Identifier, Lexeme: "x"}},
+				Expr: &ast.ASTIntegerNode{Value: 1},
+			},
+			&ast.ASTAssignmentNode{
+				Id:   ast.ASTVariableNode{Token: lexer.Token{Type: lexer.Identifier, Lexeme: "y"}},
+				Expr: &ast.ASTIntegerNode{Value: 2},
+			},
+		}},
+	}
+	assertASTNodeEqual(t, expectedAST, node)
+}
+
+func TestParsingRecoversInsideBlock(t *testing.T) {
+	program := "while (true) { x = ) ; __print 1; }\n__print 2;"
+	parser := NewParser(program)
+	grammar := NewGrammar()
+	node, err := parser.Parse(grammar)
+	expectSyntaxErrors(t, err, CodeNoRule)
+
+	block := node.(*ast.ASTProgramNode).Block
+	if len(block.Stmts) != 2 {
+		t.Fatalf("Expected 2 top-level statements, got %d", len(block.Stmts))
+	}
+	body := block.Stmts[0].(*ast.ASTWhileNode).Block.(*ast.ASTBlockNode)
+	if _, ok := body.Stmts[0].(*ast.ASTErrorNode); !ok || len(body.Stmts) != 2 {
+		t.Fatalf("Expected error node followed by a statement, got %#v", body.Stmts)
+	}
+}
+
+func TestParsingInvalidToken(t *testing.T) {
+	program := "x = 1; @"
+	parser := NewParser(program)
+	grammar := NewGrammar()
+	_, err := parser.Parse(grammar)
+	expectSyntaxErrors(t, err, CodeInvalidToken)
+}
+
+// TestParsingOneErrorPerTypo checks that recovery skips the rest of a broken
+// statement rather than resuming inside it.
+func TestParsingOneErrorPerTypo(t *testing.T) {
+	program := `let r:int[3] = [a, a + 1, a + 2];
+let m:int[3] = mk(4);
+if (x < ) { __print x; } else { __print 0; }
+while (true) { let y:int = 2 +; __print y; }
+let ok:int = 1;`
+	parser := NewParser(program)
+	grammar := NewGrammar()
+	node, err := parser.Parse(grammar)
+	expectSyntaxErrors(t, err, CodeNoRule, CodeUnexpectedToken, CodeNoRule, CodeNoRule)
+	for i, line := range []int{1, 2, 3, 4} {
+		if got := parser.Errors[i].Span.Start.Line; got != line {
+			t.Errorf("Expected error %d on line %d, got %d", i, line, got)
+		}
+	}
+
+	stmts := node.(*ast.ASTProgramNode).Block.Stmts
+	if len(stmts) != 5 {
+		t.Fatalf("Expected 5 top-level statements, got %d", len(stmts))
+	}
+	if _, ok := stmts[4].(*ast.ASTVarDeclNode); !ok {
+		t.Fatalf("Expected the last declaration to parse, got %#v", stmts[4])
+	}
+}
+
+func TestParsingSkipsInvalidCharacter(t *testing.T) {
+	program := "let x:int = 1 $;\nlet y:int = 2;"
+	parser := NewParser(program)
+	grammar := NewGrammar()
+	node, err := parser.Parse(grammar)
+	expectSyntaxErrors(t, err, CodeInvalidToken)
+	if !strings.Contains(parser.Errors[0].Message, `"$"`) {
+		t.Fatalf("Expected the error to quote the character, got %q", parser.Errors[0].Message)
+	}
+	if stmts := node.(*ast.ASTProgramNode).Block.Stmts; len(stmts) != 2 {
+		t.Fatalf("Expected both declarations, got %d statements", len(stmts))
+	}
+}
@@ -0,0 +1,132 @@
+package parser
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/giuszeppe/compiler-theory/ast"
+	"github.com/giuszeppe/compiler-theory/diag"
+)
+
+var nodeType = reflect.TypeOf((*ast.ASTNode)(nil)).Elem()
+
+// walkNodes calls fn for every AST node reachable from v through struct
+// fields, slices and interfaces.
+func walkNodes(v reflect.Value, path string, fn func(n ast.ASTNode, path string)) {
+	switch v.Kind() {
+	case reflect.Interface:
+		if !v.IsNil() {
+			walkNodes(v.Elem(), path, fn)
+		}
+	case reflect.Ptr:
+		if v.IsNil() {
+			return
+		}
+		if v.Type().Implements(nodeType) {
+			fn(v.Interface().(ast.ASTNode), path)
+		}
+		walkNodes(v.Elem(), path, fn)
+	case reflect.Struct:
+		if v.CanAddr() && v.Addr().Type().Implements(nodeType) && v.Type() != reflect.TypeOf(ast.Loc{}) {
+			// value fields such as ASTProgramNode.Block
+			if path != "" && v.Addr().Interface() != nil {
+				fn(v.Addr().Interface().(ast.ASTNode), path)
+			}
+		}
+		for i := 0; i < v.NumField(); i++ {
+			f := v.Type().Field(i)
+			if f.Type == reflect.TypeOf(ast.Loc{}) || !f.IsExported() {
+				continue
+			}
+			walkNodes(v.Field(i), path+"."+f.Name, fn)
+		}
+	case reflect.Slice:
+		for i := 0; i < v.Len(); i++ {
+			walkNodes(v.Index(i), path, fn)
+		}
+	}
+}
+
+func checkSpans(t *testing.T, src string, root ast.ASTNode) {
+	t.Helper()
+	seen := map[ast.ASTNode]bool{}
+	walkNodes(reflect.ValueOf(root), "root", func(n ast.ASTNode, path string) {
+		if seen[n] {
+			return
+		}
+		seen[n] = true
+		s := n.Span()
+		if !s.Start.IsValid() || !s.End.IsValid() {
+			t.Errorf("%s (%T) has no span", path, n)
+			return
+		}
+		if s.Start.Offset > s.End.Offset || s.End.Offset > len(src) {
+			t.Errorf("%s (%T) has a bad span %+v", path, n, s)
+		}
+		if pos := positionAt(src, s.Start.Offset); pos != s.Start {
+			t.Errorf("%s (%T) starts at %+v, but offset %d is %+v", path, n, s.Start, s.Start.Offset, pos)
+		}
+	})
+}
+
+func positionAt(src string, offset int) diag.Pos {
+	pos := diag.Pos{Line: 1, Column: 1}
+	for i := 0; i < offset; i++ {
+		if src[i] == '\n' {
+			pos.Line++
+			pos.Column = 1
+		} else {
+			pos.Column++
+		}
+	}
+	pos.Offset = offset
+	return pos
+}
+
+func TestEveryNodeHasASpan(t *testing.T) {
+	src := `fun add(a:int, b:int[2]) -> int {
+    let c:int = a + b[0] * 2 - 1;
+    /* a comment
+       over two lines */
+    if (c > 2 and not false) { return c; } else { return -c; }
+}
+let xs:int[3] = [1, 2, 3];
+let ys:float[] = [1.0, 2.5];
+let col:colour = #00ff00;
+for (let i:int = 0; i < 3; i = i + 1) {
+    __print xs[i];
+}
+while (true) {
+    __delay 16;
+    __write 1, 2, col;
+    __write_box 1, 2, 3, 4, #ffffff;
+    xs[0] = add(1, xs) as int;
+}
+let r:int = __random_int(__width + __height);
+let p:colour = __read(1, 2);
+__clear #000000;
+`
+	p := NewParser(src)
+	root, err := p.Parse(NewGrammar())
+	if err != nil {
+		t.Fatalf("Failed to parse program: %v", err)
+	}
+	checkSpans(t, src, root)
+}
+
+func TestBinaryOpSpan(t *testing.T) {
+	src := "x = a + b * c - d;"
+	p := NewParser(src)
+	root, err := p.Parse(NewGrammar())
+	if err != nil {
+		t.Fatalf("Failed to parse program: %v", err)
+	}
+	expr := root.(*ast.ASTProgramNode).Block.Stmts[0].(*ast.ASTAssignmentNode).Expr
+	if got := src[expr.Span().Start.Offset:expr.Span().End.Offset]; got != "a + b * c - d" {
+		t.Fatalf("Expected the expression span to cover %q, got %q", "a + b * c - d", got)
+	}
+	left := expr.(*ast.ASTBinaryOpNode).Left
+	if got := src[left.Span().Start.Offset:left.Span().End.Offset]; got != "a + b * c" {
+		t.Fatalf("Expected the left operand span to cover %q, got %q", "a + b * c", got)
+	}
+}
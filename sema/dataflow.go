@@ -0,0 +1,393 @@
+package sema
+
+import (
+	"github.com/giuszeppe/compiler-theory/ast"
+	"github.com/giuszeppe/compiler-theory/diag"
+	"github.com/giuszeppe/compiler-theory/flow"
+)
+
+// checkDataFlow warns about variables that may be read before they are
+// assigned, assignments whose value is never read, and variables and
+// functions that are never used. It relies on Uses, so it runs once the
+// whole program is checked, and only if no errors were found.
+func (v *SemanticVisitor) checkDataFlow(program *ast.ASTProgramNode) {
+	if diag.HasErrors(v.Diagnostics) {
+		return
+	}
+	reads := make(map[ast.ASTNode]int)
+	inspect(program, func(n ast.ASTNode) bool {
+		if _, ok := n.(*ast.ASTVariableNode); ok {
+			reads[v.Uses[n]]++
+		}
+		return true
+	})
+
+	v.checkBody(&program.Block, nil, reads)
+	var funcs []*ast.ASTFuncDeclNode
+	inspect(program, func(n ast.ASTNode) bool {
+		if fn, ok := n.(*ast.ASTFuncDeclNode); ok {
+			funcs = append(funcs, fn)
+		}
+		return true
+	})
+	params := make(map[ast.ASTNode]bool)
+	for _, fn := range funcs {
+		ps := formalParams(fn)
+		for _, p := range ps {
+			params[p] = true
+		}
+		if body, ok := fn.Block.(*ast.ASTBlockNode); ok {
+			v.checkBody(body, ps, reads)
+		}
+	}
+
+	inspect(program, func(n ast.ASTNode) bool {
+		switch n := n.(type) {
+		case *ast.ASTVarDeclNode:
+			if reads[n] > 0 {
+				break
+			}
+			if params[n] {
+				v.report(WarnUnusedParameter(n.Token))
+			} else {
+				v.report(WarnUnusedVariable(n.Token))
+			}
+		case *ast.ASTFuncDeclNode:
+			if !v.calledOutside(program, n) {
+				v.report(WarnUnusedFunction(n.Token))
+			}
+		}
+		return true
+	})
+}
+
+// calledOutside reports whether fn is called anywhere but in its own body,
+// so that a function only calling itself still counts as unused.
+func (v *SemanticVisitor) calledOutside(program *ast.ASTProgramNode, fn *ast.ASTFuncDeclNode) bool {
+	called := false
+	inspect(program, func(n ast.ASTNode) bool {
+		if n == fn.Block {
+			return false
+		}
+		if _, ok := n.(*ast.ASTFuncCallNode); ok && v.Uses[n] == fn {
+			called = true
+		}
+		return !called
+	})
+	return called
+}
+
+// bodyFlow holds the facts about one function body, or the top-level
+// program, that the data-flow checks share.
+type bodyFlow struct {
+	v         *SemanticVisitor
+	g         *flow.Graph
+	reachable map[*flow.Block]bool
+	// vars are the parameters and the variables declared in the body,
+	// outside any function nested in it.
+	vars map[*ast.ASTVarDeclNode]bool
+	// escaping are the vars used by a nested function, which can run
+	// whenever it is called.
+	escaping map[*ast.ASTVarDeclNode]bool
+}
+
+// varSet is a set of variables. A nil varSet stands for every variable,
+// the starting point of a block whose predecessors are not yet known.
+type varSet map[*ast.ASTVarDeclNode]bool
+
+// checkBody runs the checks that follow control flow through body, whose
+// function has the parameters params. reads counts the reads of each
+// declaration in the program.
+func (v *SemanticVisitor) checkBody(body *ast.ASTBlockNode, params []*ast.ASTVarDeclNode, reads map[ast.ASTNode]int) {
+	g := flow.NewGraph(body)
+	f := &bodyFlow{
+		v:         v,
+		g:         g,
+		reachable: g.Reachable(),
+		vars:      make(map[*ast.ASTVarDeclNode]bool),
+		escaping:  make(map[*ast.ASTVarDeclNode]bool),
+	}
+	for _, p := range params {
+		f.vars[p] = true
+	}
+	inspect(body, func(n ast.ASTNode) bool {
+		switch n := n.(type) {
+		case *ast.ASTVarDeclNode:
+			f.vars[n] = true
+		case *ast.ASTFuncDeclNode:
+			inspect(n.Block, func(n ast.ASTNode) bool {
+				if d := f.target(n); d != nil {
+					f.escaping[d] = true
+				}
+				if d, ok := v.Uses[n].(*ast.ASTVarDeclNode); ok {
+					f.escaping[d] = true
+				}
+				return true
+			})
+			return false
+		}
+		return true
+	})
+
+	f.checkAssigned(params)
+	f.checkLive(reads)
+}
+
+// checkAssigned warns about reads of variables that are not assigned on
+// every path leading to them. Parameters start out assigned, and so do the
+// variables of enclosing scopes, which are not tracked here.
+func (f *bodyFlow) checkAssigned(params []*ast.ASTVarDeclNode) {
+	out := make(map[*flow.Block]varSet)
+	in := func(blk *flow.Block) varSet {
+		if blk == f.g.Entry {
+			set := make(varSet)
+			for _, p := range params {
+				set[p] = true
+			}
+			return set
+		}
+		var set varSet
+		for _, pred := range f.g.Preds[blk] {
+			if !f.reachable[pred] || out[pred] == nil {
+				continue
+			}
+			if set == nil {
+				set = make(varSet)
+				for d := range out[pred] {
+					set[d] = true
+				}
+				continue
+			}
+			for d := range set {
+				if !out[pred][d] {
+					delete(set, d)
+				}
+			}
+		}
+		return set
+	}
+	// transfer applies the nodes of blk to assigned, reporting reads of
+	// unassigned variables if report is set.
+	transfer := func(blk *flow.Block, assigned varSet, report bool) {
+		for _, node := range blk.Nodes {
+			for _, r := range f.readsOf(node) {
+				if d, ok := f.v.Uses[r].(*ast.ASTVarDeclNode); ok && report && f.vars[d] && !assigned[d] {
+					f.v.report(WarnReadBeforeWrite(r.Token))
+				}
+			}
+			if decl, ok := node.(*ast.ASTVarDeclNode); ok && !hasInitialiser(decl) {
+				delete(assigned, decl)
+			} else if d := f.target(node); d != nil {
+				assigned[d] = true
+			}
+		}
+	}
+
+	for changed := true; changed; {
+		changed = false
+		for _, blk := range f.g.Blocks {
+			set := in(blk)
+			if !f.reachable[blk] || set == nil {
+				continue
+			}
+			transfer(blk, set, false)
+			if !sameSet(set, out[blk]) {
+				out[blk] = set
+				changed = true
+			}
+		}
+	}
+	for _, blk := range f.g.Blocks {
+		if set := in(blk); f.reachable[blk] && set != nil {
+			transfer(blk, set, true)
+		}
+	}
+}
+
+// checkLive warns about assignments to variables that are not read before
+// they are assigned again or go out of scope. Initialisers are left alone,
+// since a declaration must have one, and so are variables that are never
+// read at all or that a nested function uses.
+func (f *bodyFlow) checkLive(reads map[ast.ASTNode]int) {
+	in := make(map[*flow.Block]varSet)
+	out := func(blk *flow.Block) varSet {
+		set := make(varSet)
+		for _, succ := range blk.Succs {
+			for d := range in[succ] {
+				set[d] = true
+			}
+		}
+		return set
+	}
+	// transfer applies the nodes of blk to live, last to first, reporting
+	// dead assignments if report is set.
+	transfer := func(blk *flow.Block, live varSet, report bool) {
+		for i := len(blk.Nodes) - 1; i >= 0; i-- {
+			node := blk.Nodes[i]
+			if d := f.target(node); d != nil {
+				assign, isAssign := node.(*ast.ASTAssignmentNode)
+				if report && isAssign && f.vars[d] && !live[d] && !f.escaping[d] && reads[d] > 0 {
+					f.v.report(WarnUnusedAssignment(assign))
+				}
+				delete(live, d)
+			}
+			if decl, ok := node.(*ast.ASTVarDeclNode); ok {
+				delete(live, decl)
+			}
+			for _, r := range f.readsOf(node) {
+				if d, ok := f.v.Uses[r].(*ast.ASTVarDeclNode); ok {
+					live[d] = true
+				}
+			}
+		}
+	}
+
+	for changed := true; changed; {
+		changed = false
+		for i := len(f.g.Blocks) - 1; i >= 0; i-- {
+			blk := f.g.Blocks[i]
+			if !f.reachable[blk] {
+				continue
+			}
+			set := out(blk)
+			transfer(blk, set, false)
+			if !sameSet(set, in[blk]) {
+				in[blk] = set
+				changed = true
+			}
+		}
+	}
+	for _, blk := range f.g.Blocks {
+		if f.reachable[blk] {
+			transfer(blk, out(blk), true)
+		}
+	}
+}
+
+// readsOf returns the variables node reads, leaving out those read by a
+// function it declares.
+func (f *bodyFlow) readsOf(node ast.ASTNode) []*ast.ASTVariableNode {
+	var vars []*ast.ASTVariableNode
+	inspect(node, func(n ast.ASTNode) bool {
+		switch n := n.(type) {
+		case *ast.ASTFuncDeclNode:
+			return false
+		case *ast.ASTVariableNode:
+			vars = append(vars, n)
+		}
+		return true
+	})
+	return vars
+}
+
+// target returns the variable node assigns a value to as a whole: the one
+// an initialised declaration declares, or the target of an assignment to
+// a variable rather than to an element of an array.
+func (f *bodyFlow) target(node ast.ASTNode) *ast.ASTVarDeclNode {
+	switch n := node.(type) {
+	case *ast.ASTVarDeclNode:
+		if hasInitialiser(n) {
+			return n
+		}
+	case *ast.ASTAssignmentNode:
+		if _, isEpsilon := n.Id.Offset.(*ast.ASTEpsilon); isEpsilon || n.Id.Offset == nil {
+			d, _ := f.v.Uses[&n.Id].(*ast.ASTVarDeclNode)
+			return d
+		}
+	}
+	return nil
+}
+
+func hasInitialiser(decl *ast.ASTVarDeclNode) bool {
+	expr := decl.Expression
+	if e, ok := expr.(*ast.ASTExpressionNode); ok {
+		expr = e.Expr
+	}
+	_, isEpsilon := expr.(*ast.ASTEpsilon)
+	return expr != nil && !isEpsilon
+}
+
+// formalParams returns the parameters of fn.
+func formalParams(fn *ast.ASTFuncDeclNode) []*ast.ASTVarDeclNode {
+	var params []*ast.ASTVarDeclNode
+	if formal, ok := fn.Params.(*ast.ASTFormalParamsNode); ok {
+		for _, p := range formal.Params {
+			if decl, ok := p.(*ast.ASTVarDeclNode); ok {
+				params = append(params, decl)
+			}
+		}
+	}
+	return params
+}
+
+func sameSet(a, b varSet) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	if len(a) != len(b) {
+		return false
+	}
+	for d := range a {
+		if !b[d] {
+			return false
+		}
+	}
+	return true
+}
+
+// inspect calls f on node and, if f returns true, on each node under it in
+// source order. The target of an assignment is not visited, apart from its
+// offset, since it is written rather than read.
+func inspect(node ast.ASTNode, f func(ast.ASTNode) bool) {
+	if node == nil || !f(node) {
+		return
+	}
+	var children []ast.ASTNode
+	switch n := node.(type) {
+	case *ast.ASTProgramNode:
+		children = []ast.ASTNode{&n.Block}
+	case *ast.ASTBlockNode:
+		children = n.Stmts
+	case *ast.ASTVarDeclNode:
+		children = []ast.ASTNode{n.Expression}
+	case *ast.ASTAssignmentNode:
+		children = []ast.ASTNode{n.Id.Offset, n.Expr}
+	case *ast.ASTVariableNode:
+		children = []ast.ASTNode{n.Offset}
+	case *ast.ASTExpressionNode:
+		children = []ast.ASTNode{n.Expr}
+	case *ast.ASTBinaryOpNode:
+		children = []ast.ASTNode{n.Left, n.Right}
+	case *ast.ASTUnaryOpNode:
+		children = []ast.ASTNode{n.Operand}
+	case *ast.ASTTypeCastNode:
+		children = []ast.ASTNode{n.Expr}
+	case *ast.ASTPrintNode:
+		children = []ast.ASTNode{&n.Expr}
+	case *ast.ASTIfNode:
+		children = []ast.ASTNode{n.Condition, n.ThenBlock, n.ElseBlock}
+	case *ast.ASTWhileNode:
+		children = []ast.ASTNode{n.Condition, n.Block}
+	case *ast.ASTForNode:
+		children = []ast.ASTNode{n.VarDecl, n.Condition, n.Increment, n.Block}
+	case *ast.ASTFormalParamsNode:
+		children = n.Params
+	case *ast.ASTFuncDeclNode:
+		children = []ast.ASTNode{n.Params, n.Block}
+	case *ast.ASTBuiltinFuncNode:
+		children = n.Args
+	case *ast.ASTFuncCallNode:
+		children = []ast.ASTNode{n.Params}
+	case *ast.ASTActualParamsNode:
+		children = n.Params
+	case *ast.ASTActualParamNode:
+		children = []ast.ASTNode{n.Value}
+	case *ast.ASTReturnNode:
+		children = []ast.ASTNode{n.Expr}
+	case *ast.ASTArrayNode:
+		children = n.Items
+	}
+	for _, child := range children {
+		inspect(child, f)
+	}
+}
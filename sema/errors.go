@@ -0,0 +1,141 @@
+package sema
+
+import (
+	"github.com/giuszeppe/compiler-theory/ast"
+	"github.com/giuszeppe/compiler-theory/diag"
+	"github.com/giuszeppe/compiler-theory/lexer"
+	"github.com/giuszeppe/compiler-theory/types"
+)
+
+// Semantic error codes. Codes are stable: never renumber or reuse one.
+const (
+	CodeVariableNotDeclared      = "E001"
+	CodeVariableAlreadyDeclared  = "E002"
+	CodeTypeMismatch             = "E003"
+	CodeInvalidOffsetType        = "E004"
+	CodeNotVariableDeclaration   = "E005"
+	CodeFunctionNotDeclared      = "E006"
+	CodeFunctionAlreadyDeclared  = "E007"
+	CodeParameterAlreadyDeclared = "E008"
+	CodeArgumentCountMismatch    = "E009"
+	CodeInvalidColorValue        = "E010"
+	CodeArraySize                = "E011"
+	CodeArraySizeNegative        = "E012"
+	CodeUnknownExpressionType    = "E013"
+	CodeReturnTypeMismatch       = "E014"
+	CodeFunctionMustHaveReturn   = "E015"
+	CodeNotAnArray               = "E016"
+)
+
+// Semantic warning codes, numbered separately from the errors.
+const (
+	CodeUnreachableCode  = "W001"
+	CodeReadBeforeWrite  = "W002"
+	CodeUnusedVariable   = "W003"
+	CodeUnusedAssignment = "W004"
+	CodeUnusedFunction   = "W005"
+)
+
+func ErrVariableNotDeclared(tok lexer.Token) diag.Diagnostic {
+	return diag.NewError(CodeVariableNotDeclared, diag.SpanOf(tok), "Variable not declared: %s", tok.Lexeme)
+}
+
+func ErrVariableAlreadyDeclared(tok lexer.Token) diag.Diagnostic {
+	return diag.NewError(CodeVariableAlreadyDeclared, diag.SpanOf(tok), "Variable already declared: %s", tok.Lexeme)
+}
+
+func ErrTypeMismatch(expected, got types.Type, tok lexer.Token) diag.Diagnostic {
+	return ErrTypeMismatchAt(expected, got, diag.SpanOf(tok))
+}
+
+// ErrTypeMismatchAt is ErrTypeMismatch for an expression rather than a
+// token.
+func ErrTypeMismatchAt(expected, got types.Type, span diag.Span) diag.Diagnostic {
+	return withCastHelp(diag.NewError(CodeTypeMismatch, span, "Type mismatch: expected %v, got %v", expected, got), expected, got)
+}
+
+func ErrInvalidOffsetType(expected, got types.Type, tok lexer.Token) diag.Diagnostic {
+	return diag.NewError(CodeInvalidOffsetType, diag.SpanOf(tok), "Invalid offset type: expected %v, got %v", expected, got)
+}
+
+func ErrNotVariableDeclaration(tok lexer.Token) diag.Diagnostic {
+	return diag.NewError(CodeNotVariableDeclaration, diag.SpanOf(tok), "Not a variable declaration: %s", tok.Lexeme)
+}
+
+func ErrFunctionNotDeclared(tok lexer.Token) diag.Diagnostic {
+	return diag.NewError(CodeFunctionNotDeclared, diag.SpanOf(tok), "Function not declared: %s", tok.Lexeme)
+}
+
+func ErrFunctionAlreadyDeclared(tok lexer.Token) diag.Diagnostic {
+	return diag.NewError(CodeFunctionAlreadyDeclared, diag.SpanOf(tok), "Function already declared: %s", tok.Lexeme)
+}
+
+func ErrParameterAlreadyDeclared(name string, span diag.Span) diag.Diagnostic {
+	return diag.NewError(CodeParameterAlreadyDeclared, span, "Parameter already declared: %s", name)
+}
+
+func ErrArgumentCountMismatch(expected, got int, span diag.Span) diag.Diagnostic {
+	return diag.NewError(CodeArgumentCountMismatch, span, "Argument count mismatch: expected %d, got %d", expected, got)
+}
+
+func ErrInvalidColorValue(value string, tok lexer.Token) diag.Diagnostic {
+	return diag.NewError(CodeInvalidColorValue, diag.SpanOf(tok), "Invalid color value: %s", value)
+}
+
+func ErrArraySize(size, items int, tok lexer.Token) diag.Diagnostic {
+	return diag.NewError(CodeArraySize, diag.SpanOf(tok), "Array size must be greater than the number of items: %d < %d", size, items)
+}
+
+func ErrArraySizeNegative(size int, tok lexer.Token) diag.Diagnostic {
+	return diag.NewError(CodeArraySizeNegative, diag.SpanOf(tok), "Array size must be greater than 0: %d", size)
+}
+
+func ErrUnknownExpressionType(node ast.ASTNode) diag.Diagnostic {
+	return diag.NewError(CodeUnknownExpressionType, node.Span(), "Unknown expression type %T", node)
+}
+
+func ErrReturnTypeMismatch(expected, got types.Type, tok lexer.Token) diag.Diagnostic {
+	return withCastHelp(diag.NewError(CodeReturnTypeMismatch, diag.SpanOf(tok), "Return type mismatch: expected %v, got %v", expected, got), expected, got)
+}
+
+// withCastHelp suggests a cast for a mismatch between two types a value
+// can be cast between.
+func withCastHelp(d diag.Diagnostic, expected, got types.Type) diag.Diagnostic {
+	castable := map[types.Type]bool{types.Int: true, types.Float: true, types.Colour: true}
+	if castable[expected] && castable[got] {
+		return d.WithHelp("cast the value with `as %v`", expected)
+	}
+	return d
+}
+
+func ErrFunctionMustHaveReturn(tok lexer.Token) diag.Diagnostic {
+	return diag.NewError(CodeFunctionMustHaveReturn, diag.SpanOf(tok), "Function must return a value on every path: %s", tok.Lexeme)
+}
+
+func ErrNotAnArray(tok lexer.Token) diag.Diagnostic {
+	return diag.NewError(CodeNotAnArray, diag.SpanOf(tok), "Trying to access offset of non array: %s", tok.Lexeme)
+}
+
+func WarnUnreachableCode(node ast.ASTNode) diag.Diagnostic {
+	return diag.NewWarning(CodeUnreachableCode, node.Span(), "Unreachable code")
+}
+
+func WarnReadBeforeWrite(tok lexer.Token) diag.Diagnostic {
+	return diag.NewWarning(CodeReadBeforeWrite, diag.SpanOf(tok), "Variable may be read before it is assigned: %s", tok.Lexeme)
+}
+
+func WarnUnusedVariable(tok lexer.Token) diag.Diagnostic {
+	return diag.NewWarning(CodeUnusedVariable, diag.SpanOf(tok), "Variable is never read: %s", tok.Lexeme)
+}
+
+func WarnUnusedParameter(tok lexer.Token) diag.Diagnostic {
+	return diag.NewWarning(CodeUnusedVariable, diag.SpanOf(tok), "Parameter is never read: %s", tok.Lexeme)
+}
+
+func WarnUnusedAssignment(node *ast.ASTAssignmentNode) diag.Diagnostic {
+	return diag.NewWarning(CodeUnusedAssignment, node.Span(), "Value assigned to %s is never read", node.Id.Token.Lexeme)
+}
+
+func WarnUnusedFunction(tok lexer.Token) diag.Diagnostic {
+	return diag.NewWarning(CodeUnusedFunction, diag.SpanOf(tok), "Function is never called: %s", tok.Lexeme)
+}
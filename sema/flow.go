@@ -0,0 +1,65 @@
+package sema
+
+import (
+	"github.com/giuszeppe/compiler-theory/ast"
+	"github.com/giuszeppe/compiler-theory/flow"
+)
+
+// checkFunctionFlow reports a function that can reach the end of its body
+// without returning, and code in it that can never run.
+func (v *SemanticVisitor) checkFunctionFlow(node *ast.ASTFuncDeclNode, body *ast.ASTBlockNode) {
+	g := flow.NewGraph(body)
+	reachable := g.Reachable()
+	for _, pred := range g.Preds[g.Exit] {
+		if reachable[pred] && !pred.Returns() {
+			v.report(ErrFunctionMustHaveReturn(node.Token))
+			break
+		}
+	}
+	v.reportUnreachable(g, reachable, body.Stmts)
+}
+
+// checkProgramFlow reports top-level code that can never run.
+func (v *SemanticVisitor) checkProgramFlow(body *ast.ASTBlockNode) {
+	g := flow.NewGraph(body)
+	v.reportUnreachable(g, g.Reachable(), body.Stmts)
+}
+
+// reportUnreachable warns about the first statement that cannot run in each
+// list of statements under stmts. The statements after it cannot run
+// either, so they are not reported again.
+func (v *SemanticVisitor) reportUnreachable(g *flow.Graph, reachable map[*flow.Block]bool, stmts []ast.ASTNode) {
+	for _, stmt := range stmts {
+		start, ok := g.Start[stmt]
+		if !ok {
+			continue
+		}
+		if !reachable[start] {
+			v.report(WarnUnreachableCode(stmt))
+			return
+		}
+		switch n := stmt.(type) {
+		case *ast.ASTBlockNode:
+			v.reportUnreachable(g, reachable, n.Stmts)
+		case *ast.ASTIfNode:
+			v.reportUnreachable(g, reachable, blockStmts(n.ThenBlock))
+			v.reportUnreachable(g, reachable, blockStmts(n.ElseBlock))
+		case *ast.ASTWhileNode:
+			v.reportUnreachable(g, reachable, blockStmts(n.Block))
+		case *ast.ASTForNode:
+			v.reportUnreachable(g, reachable, blockStmts(n.Block))
+		}
+	}
+}
+
+// blockStmts returns the statements of a block, or node itself if it is a
+// single statement.
+func blockStmts(node ast.ASTNode) []ast.ASTNode {
+	switch n := node.(type) {
+	case nil:
+		return nil
+	case *ast.ASTBlockNode:
+		return n.Stmts
+	}
+	return []ast.ASTNode{node}
+}
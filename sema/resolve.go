@@ -0,0 +1,355 @@
+package sema
+
+import (
+	"github.com/giuszeppe/compiler-theory/ast"
+	"github.com/giuszeppe/compiler-theory/diag"
+	"github.com/giuszeppe/compiler-theory/types"
+)
+
+// resolver is the name resolution pass. It binds every variable use,
+// assignment target and function call to its declaration, reports names
+// that are undeclared or declared twice, and gives each variable a slot.
+// The type checker, the data flow checks and the IR builder all work from
+// these bindings rather than looking names up again.
+//
+// Slots are laid out in the frames the IR builder opens: one for the
+// program, for each nested block and for each if, while and for
+// statement, and one for each function. The branches and bodies of if,
+// while and for statements are scopes of their own in the statement's
+// frame. A slot's depth counts the frames that enclose it in the source.
+//
+// At run time a call opens the function's frame on top of its caller's
+// frames, whatever they are, so code in a function can only address its
+// own frame and those opened inside it. The variables declared outside a
+// function that it uses, itself or through the functions it calls, are
+// its captures: they are passed in after its arguments, and those it
+// assigns are passed back when it returns, for the caller to store.
+type resolver struct {
+	scope *scope
+	uses  map[ast.ASTNode]ast.ASTNode
+	diags []diag.Diagnostic
+
+	// fn is the function being resolved, or nil at the top level.
+	fn *function
+	// funcs are the functions declared in this pass, and frames the
+	// frames opened in it, both in source order.
+	funcs  []*function
+	frames []*frame
+}
+
+// scope holds the names declared in one PArL block. Entering a block links
+// a new scope to the enclosing one, so lookups walk outwards.
+type scope struct {
+	names  map[string]ast.ASTNode
+	parent *scope
+	frame  *frame
+}
+
+type frame struct {
+	depth int
+	// decls are the variables declared in the frame, in source order.
+	decls []*ast.ASTVarDeclNode
+	// fn is the function whose frame this is, if any.
+	fn *function
+}
+
+// function is what the resolver learns about a function declaration while
+// resolving it.
+type function struct {
+	node  *ast.ASTFuncDeclNode
+	frame *frame
+	// calls are the functions called in the body.
+	calls []*ast.ASTFuncDeclNode
+}
+
+func newScope(parent *scope, f *frame) *scope {
+	return &scope{names: make(map[string]ast.ASTNode), parent: parent, frame: f}
+}
+
+func (r *resolver) report(d diag.Diagnostic) {
+	r.diags = append(r.diags, d)
+}
+
+// push enters a scope in the current frame.
+func (r *resolver) push() {
+	r.scope = newScope(r.scope, r.scope.frame)
+}
+
+// pushFrame enters a scope in a new frame.
+func (r *resolver) pushFrame() {
+	f := &frame{depth: r.scope.frame.depth + 1}
+	r.frames = append(r.frames, f)
+	r.scope = newScope(r.scope, f)
+}
+
+func (r *resolver) pop() {
+	r.scope = r.scope.parent
+}
+
+// lookup returns the declaration name refers to in the current scope.
+func (r *resolver) lookup(name string) (ast.ASTNode, bool) {
+	for s := r.scope; s != nil; s = s.parent {
+		if decl, ok := s.names[name]; ok {
+			return decl, true
+		}
+	}
+	return nil, false
+}
+
+// declare adds decl to the current scope as name. PArL has no shadowing,
+// so it fails if the name is visible already, returning the declaration
+// that has it.
+func (r *resolver) declare(name string, decl ast.ASTNode) (ast.ASTNode, bool) {
+	if prev, ok := r.lookup(name); ok {
+		return prev, false
+	}
+	r.scope.names[name] = decl
+	return nil, true
+}
+
+// declareVar declares a variable in the current frame. Its slot index is
+// chosen by layout, once the captures of the frame's function are known.
+func (r *resolver) declareVar(node *ast.ASTVarDeclNode) {
+	if prev, ok := r.declare(node.Token.Lexeme, node); !ok {
+		r.report(ErrVariableAlreadyDeclared(node.Token).WithNote(declSpan(prev), "%s first declared here", node.Token.Lexeme))
+		return
+	}
+	f := r.scope.frame
+	node.Slot = ast.Slot{Depth: f.depth}
+	f.decls = append(f.decls, node)
+}
+
+// outside reports whether decl is declared outside the function being
+// resolved.
+func (r *resolver) outside(decl *ast.ASTVarDeclNode) bool {
+	return r.fn != nil && decl.Slot.Depth < r.fn.frame.depth
+}
+
+// capture adds decl to the captures of fn unless it has it already, and
+// marks it written if written is set. It reports whether fn's captures
+// changed.
+func capture(fn *ast.ASTFuncDeclNode, decl *ast.ASTVarDeclNode, written bool) bool {
+	for i, c := range fn.Captures {
+		if c.Decl == decl {
+			if written && !c.Written {
+				fn.Captures[i].Written = true
+				return true
+			}
+			return false
+		}
+	}
+	fn.Captures = append(fn.Captures, ast.Capture{Decl: decl, Written: written})
+	return true
+}
+
+func (r *resolver) stmts(stmts []ast.ASTNode) {
+	for _, stmt := range stmts {
+		r.resolve(stmt)
+	}
+}
+
+// body resolves the branch or body of a statement in a scope of its own.
+func (r *resolver) body(node ast.ASTNode) {
+	r.push()
+	if blk, ok := node.(*ast.ASTBlockNode); ok {
+		r.stmts(blk.Stmts)
+	} else {
+		r.resolve(node)
+	}
+	r.pop()
+}
+
+func (r *resolver) resolve(node ast.ASTNode) {
+	switch n := node.(type) {
+	case *ast.ASTProgramNode:
+		r.stmts(n.Block.Stmts)
+	case *ast.ASTBlockNode:
+		r.pushFrame()
+		r.stmts(n.Stmts)
+		r.pop()
+	case *ast.ASTVarDeclNode:
+		r.resolve(n.Expression)
+		r.declareVar(n)
+	case *ast.ASTAssignmentNode:
+		r.resolve(n.Expr)
+		if decl := r.resolveVar(&n.Id); decl != nil && r.outside(decl) {
+			capture(r.fn.node, decl, true)
+		}
+		r.resolve(n.Id.Offset)
+	case *ast.ASTVariableNode:
+		if decl := r.resolveVar(n); decl != nil && r.outside(decl) {
+			capture(r.fn.node, decl, false)
+		}
+		r.resolve(n.Offset)
+	case *ast.ASTFuncCallNode:
+		r.resolveCall(n)
+		r.resolve(n.Params)
+	case *ast.ASTIfNode:
+		r.pushFrame()
+		r.resolve(n.Condition)
+		r.body(n.ThenBlock)
+		if n.ElseBlock != nil {
+			r.body(n.ElseBlock)
+		}
+		r.pop()
+	case *ast.ASTWhileNode:
+		r.pushFrame()
+		r.resolve(n.Condition)
+		r.body(n.Block)
+		r.pop()
+	case *ast.ASTForNode:
+		r.pushFrame()
+		r.resolve(n.VarDecl)
+		r.resolve(n.Condition)
+		r.resolve(n.Increment)
+		r.body(n.Block)
+		r.pop()
+	case *ast.ASTFuncDeclNode:
+		r.funcDecl(n)
+	case *ast.ASTFormalParamNode:
+		if _, ok := r.declare(n.Name, n); !ok {
+			r.report(ErrParameterAlreadyDeclared(n.Name, n.Span()))
+		}
+	case *ast.ASTFormalParamsNode:
+		r.stmts(n.Params)
+	case *ast.ASTActualParamsNode:
+		r.stmts(n.Params)
+	case *ast.ASTActualParamNode:
+		r.resolve(n.Value)
+	case *ast.ASTExpressionNode:
+		r.resolve(n.Expr)
+	case *ast.ASTBinaryOpNode:
+		r.resolve(n.Left)
+		r.resolve(n.Right)
+	case *ast.ASTUnaryOpNode:
+		r.resolve(n.Operand)
+	case *ast.ASTTypeCastNode:
+		r.resolve(n.Expr)
+	case *ast.ASTArrayNode:
+		r.stmts(n.Items)
+	case *ast.ASTBuiltinFuncNode:
+		r.stmts(n.Args)
+	case *ast.ASTPrintNode:
+		r.resolve(&n.Expr)
+	case *ast.ASTReturnNode:
+		r.resolve(n.Expr)
+	}
+}
+
+// resolveVar binds n and returns the variable it refers to, or nil.
+func (r *resolver) resolveVar(n *ast.ASTVariableNode) *ast.ASTVarDeclNode {
+	decl, ok := r.lookup(n.Token.Lexeme)
+	if !ok {
+		r.report(r.errVariableNotDeclared(n.Token))
+		return nil
+	}
+	r.uses[n] = decl
+	varDecl, ok := decl.(*ast.ASTVarDeclNode)
+	if !ok {
+		r.report(ErrNotVariableDeclaration(n.Token))
+		return nil
+	}
+	n.Decl = varDecl
+	return varDecl
+}
+
+func (r *resolver) resolveCall(n *ast.ASTFuncCallNode) {
+	decl, ok := r.lookup(n.Name.Lexeme)
+	if !ok {
+		r.report(r.errFunctionNotDeclared(n.Name))
+		return
+	}
+	r.uses[n] = decl
+	funcDecl, ok := decl.(*ast.ASTFuncDeclNode)
+	if !ok {
+		r.report(r.errFunctionNotDeclared(n.Name))
+		return
+	}
+	n.Decl = funcDecl
+	if r.fn != nil {
+		r.fn.calls = append(r.fn.calls, funcDecl)
+	}
+}
+
+// funcDecl declares a function before resolving its body, so that it can
+// call itself. Its parameters take the first slots of its frame.
+func (r *resolver) funcDecl(n *ast.ASTFuncDeclNode) {
+	if prev, ok := r.declare(n.Token.Lexeme, n); !ok {
+		r.report(ErrFunctionAlreadyDeclared(n.Token).WithNote(declSpan(prev), "%s first declared here", n.Token.Lexeme))
+	}
+	outer := r.fn
+	r.pushFrame()
+	r.fn = &function{node: n, frame: r.scope.frame}
+	r.scope.frame.fn = r.fn
+	r.funcs = append(r.funcs, r.fn)
+	n.Captures = nil
+	r.resolve(n.Params)
+	if blk, ok := n.Block.(*ast.ASTBlockNode); ok {
+		r.stmts(blk.Stmts)
+	} else {
+		r.resolve(n.Block)
+	}
+	r.pop()
+	r.fn = outer
+}
+
+// layout completes the captures of the functions resolved, which take
+// those of the functions they call, written or not, and then gives every variable declared
+// in global or in the frames opened its slot index. A function's frame
+// holds its parameters, then its captures, then its locals, so that the
+// arguments of a call fill the start of it.
+func (r *resolver) layout(global *frame) {
+	for changed := true; changed; {
+		changed = false
+		for _, fn := range r.funcs {
+			for _, callee := range fn.calls {
+				for _, c := range callee.Captures {
+					if c.Decl.Slot.Depth < fn.frame.depth && capture(fn.node, c.Decl, c.Written) {
+						changed = true
+					}
+				}
+			}
+		}
+	}
+
+	for _, f := range append([]*frame{global}, r.frames...) {
+		index, params := 0, 0
+		if f.fn != nil {
+			params = len(f.fn.node.Params.(*ast.ASTFormalParamsNode).Params)
+		}
+		for i, decl := range f.decls {
+			if f.fn != nil && i == params {
+				index = layoutCaptures(f.fn.node, index)
+			}
+			decl.Slot.Index = index
+			index += types.Size(decl.Type)
+		}
+		if f.fn != nil && len(f.decls) <= params {
+			layoutCaptures(f.fn.node, index)
+		}
+	}
+}
+
+// layoutCaptures gives the captures of fn the slots from index on, and
+// returns the index after them.
+func layoutCaptures(fn *ast.ASTFuncDeclNode, index int) int {
+	for i := range fn.Captures {
+		fn.Captures[i].Index = index
+		index += types.Size(fn.Captures[i].Decl.Type)
+	}
+	return index
+}
+
+// mergeDiagnostics merges the diagnostics of two passes, each in the order
+// its pass reported them, into one list in source order.
+func mergeDiagnostics(a, b []diag.Diagnostic) []diag.Diagnostic {
+	merged := make([]diag.Diagnostic, 0, len(a)+len(b))
+	for len(a) > 0 && len(b) > 0 {
+		if a[0].Span.Start.Offset <= b[0].Span.Start.Offset {
+			merged, a = append(merged, a[0]), a[1:]
+		} else {
+			merged, b = append(merged, b[0]), b[1:]
+		}
+	}
+	return append(append(merged, a...), b...)
+}
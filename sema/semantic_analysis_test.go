@@ -0,0 +1,766 @@
+package sema
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/giuszeppe/compiler-theory/ast"
+	"github.com/giuszeppe/compiler-theory/diag"
+	"github.com/giuszeppe/compiler-theory/parser"
+	"github.com/giuszeppe/compiler-theory/types"
+)
+
+func expectDiagnostics(t *testing.T, rootAST ast.ASTNode, codes ...string) {
+	visitor := NewSemanticVisitor()
+	diags := visitor.Analyze(rootAST)
+	got := []string{}
+	for _, d := range diags {
+		got = append(got, d.Code)
+	}
+	if len(got) != len(codes) {
+		t.Fatalf("Expected diagnostics %v, got %v (%v)", codes, got, diags)
+	}
+	for i := range codes {
+		if got[i] != codes[i] {
+			t.Fatalf("Expected diagnostics %v, got %v (%v)", codes, got, diags)
+		}
+	}
+}
+
+func TestDoubleVariableDeclaration(t *testing.T) {
+	program := `let x:int = 5; let x:float = 10.0;
+	`
+	p := parser.NewParser(program)
+	grammar := parser.NewGrammar()
+	rootAST, err := p.Parse(grammar)
+	if err != nil {
+		t.Fatalf("Failed to parse program: %v", err)
+	}
+	expectDiagnostics(t, rootAST, CodeVariableAlreadyDeclared)
+}
+
+func TestUndeclaredVariable(t *testing.T) {
+	program := `let x:int = 5; let y:int = x + z;
+	`
+	p := parser.NewParser(program)
+	grammar := parser.NewGrammar()
+	rootAST, err := p.Parse(grammar)
+	if err != nil {
+		t.Fatalf("Failed to parse program: %v", err)
+	}
+	expectDiagnostics(t, rootAST, CodeVariableNotDeclared)
+}
+
+func TestValidVariableDeclaration(t *testing.T) {
+	program := `let x:int = 5; let y:float = 10.0; __print x; __print y;
+	`
+	p := parser.NewParser(program)
+	grammar := parser.NewGrammar()
+	rootAST, err := p.Parse(grammar)
+	if err != nil {
+		t.Fatalf("Failed to parse program: %v", err)
+	}
+	expectDiagnostics(t, rootAST)
+}
+
+func TestValidVariableAssignment(t *testing.T) {
+	program := `let x:int = 5; x = 10; __print x;
+	`
+	p := parser.NewParser(program)
+	grammar := parser.NewGrammar()
+	rootAST, err := p.Parse(grammar)
+	if err != nil {
+		t.Fatalf("Failed to parse program: %v", err)
+	}
+	expectDiagnostics(t, rootAST)
+}
+
+func TestInvalidVariableAssignment(t *testing.T) {
+	program := `let x:int = 5; y = 10;
+	`
+	p := parser.NewParser(program)
+	grammar := parser.NewGrammar()
+	rootAST, err := p.Parse(grammar)
+	if err != nil {
+		t.Fatalf("Failed to parse program: %v", err)
+	}
+	expectDiagnostics(t, rootAST, CodeVariableNotDeclared)
+}
+
+func TestValidVariableUsage(t *testing.T) {
+	program := `let x:int = 5; let y:int = x + 10; __print y;
+	`
+	p := parser.NewParser(program)
+	grammar := parser.NewGrammar()
+	rootAST, err := p.Parse(grammar)
+	if err != nil {
+		t.Fatalf("Failed to parse program: %v", err)
+	}
+	expectDiagnostics(t, rootAST)
+}
+
+func TestDoubleFuncDeclaration(t *testing.T) {
+	program := `fun foo() -> int { return 1; } fun foo() -> float { return 1.0; }
+	`
+	p := parser.NewParser(program)
+	grammar := parser.NewGrammar()
+	rootAST, err := p.Parse(grammar)
+	if err != nil {
+		t.Fatalf("Failed to parse program: %v", err)
+	}
+	expectDiagnostics(t, rootAST, CodeFunctionAlreadyDeclared)
+}
+
+func TestUndeclaredFunc(t *testing.T) {
+	program := `fun foo() -> int { return 1; } let x:int = bar();
+	`
+	p := parser.NewParser(program)
+	grammar := parser.NewGrammar()
+	rootAST, err := p.Parse(grammar)
+	if err != nil {
+		t.Fatalf("Failed to parse program: %v", err)
+	}
+	expectDiagnostics(t, rootAST, CodeFunctionNotDeclared)
+}
+func TestValidFuncDeclaration(t *testing.T) {
+	program := `fun foo() -> int { return 1; } fun bar() -> float { return 1.0; } __print foo(); __print bar();
+	`
+	p := parser.NewParser(program)
+	grammar := parser.NewGrammar()
+	rootAST, err := p.Parse(grammar)
+	if err != nil {
+		t.Fatalf("Failed to parse program: %v", err)
+	}
+	expectDiagnostics(t, rootAST)
+}
+
+func TestOuterVariableAreSeenByInnerScopes(t *testing.T) {
+	program := `let x:int = 5; fun foo() -> int { let y:int = x + 1; return y; } let z:int = foo(); __print z;
+	`
+	p := parser.NewParser(program)
+	grammar := parser.NewGrammar()
+	rootAST, err := p.Parse(grammar)
+	if err != nil {
+		t.Fatalf("Failed to parse program: %v", err)
+	}
+	expectDiagnostics(t, rootAST)
+}
+
+func TestInnerVariableAreNotSeenByOuterScopes(t *testing.T) {
+	program := `let x:int = 5; fun foo() -> int { let y:int = 10; return y; } let z:int = x + y;
+	`
+	p := parser.NewParser(program)
+	grammar := parser.NewGrammar()
+	rootAST, err := p.Parse(grammar)
+	if err != nil {
+		t.Fatalf("Failed to parse program: %v", err)
+	}
+	expectDiagnostics(t, rootAST, CodeVariableNotDeclared)
+}
+
+func TestValidBlock(t *testing.T) {
+	program := `let x:int = 5; { let y:int = x + 1; __print y; }
+	`
+	p := parser.NewParser(program)
+	grammar := parser.NewGrammar()
+	rootAST, err := p.Parse(grammar)
+	if err != nil {
+		t.Fatalf("Failed to parse program: %v", err)
+	}
+	expectDiagnostics(t, rootAST)
+}
+
+func TestInvalidBlock(t *testing.T) {
+	program := `let x:int = 5; { let y:int = x + 1; } { let z:int = y + 1; }
+	`
+	p := parser.NewParser(program)
+	grammar := parser.NewGrammar()
+	rootAST, err := p.Parse(grammar)
+	if err != nil {
+		t.Fatalf("Failed to parse program: %v", err)
+	}
+	expectDiagnostics(t, rootAST, CodeVariableNotDeclared)
+}
+
+func TestTypeMismatchOnVariableDeclaration(t *testing.T) {
+	program := `let x:int = 5.0;
+	`
+	p := parser.NewParser(program)
+	grammar := parser.NewGrammar()
+	rootAST, err := p.Parse(grammar)
+	if err != nil {
+		t.Fatalf("Failed to parse program: %v", err)
+	}
+	expectDiagnostics(t, rootAST, CodeTypeMismatch)
+}
+
+func TestTypeMismatchOnAssignment(t *testing.T) {
+	program := `let x:int = 5; x = 10.0;
+	`
+	p := parser.NewParser(program)
+	grammar := parser.NewGrammar()
+	rootAST, err := p.Parse(grammar)
+	if err != nil {
+		t.Fatalf("Failed to parse program: %v", err)
+	}
+	expectDiagnostics(t, rootAST, CodeTypeMismatch)
+}
+
+func TestTypeMismatchOnFormalAndActualParams(t *testing.T) {
+	program := `fun foo(x:int) -> int { return x; } let y:float = foo(5.0);
+	`
+	p := parser.NewParser(program)
+	grammar := parser.NewGrammar()
+	rootAST, err := p.Parse(grammar)
+	if err != nil {
+		t.Fatalf("Failed to parse program: %v", err)
+	}
+	expectDiagnostics(t, rootAST, CodeTypeMismatch, CodeTypeMismatch)
+}
+
+func TestArgumentNumberMismatch(t *testing.T) {
+	program := `fun foo(x:int, y:int) -> int { return x + y; } let z:int = foo(5);
+	`
+	p := parser.NewParser(program)
+	grammar := parser.NewGrammar()
+	rootAST, err := p.Parse(grammar)
+	if err != nil {
+		t.Fatalf("Failed to parse program: %v", err)
+	}
+	expectDiagnostics(t, rootAST, CodeArgumentCountMismatch)
+}
+
+// TestArgumentErrorsAtCall checks that a bad call is reported where it is
+// made, so that each one is, rather than at the declaration it shares with
+// the others.
+func TestArgumentErrorsAtCall(t *testing.T) {
+	program := `fun foo(x:int) -> int { return x; }
+let a:int = foo(true);
+let b:int = foo(1.5);
+let c:int = foo(1, 2);`
+	p := parser.NewParser(program)
+	rootAST, err := p.Parse(parser.NewGrammar())
+	if err != nil {
+		t.Fatalf("Failed to parse program: %v", err)
+	}
+	diags := NewSemanticVisitor().Analyze(rootAST)
+	want := []struct {
+		code      string
+		line, col int
+	}{{CodeTypeMismatch, 2, 17}, {CodeTypeMismatch, 3, 17}, {CodeArgumentCountMismatch, 4, 13}}
+	if len(diags) != len(want) {
+		t.Fatalf("Expected %d diagnostics, got %v", len(want), diags)
+	}
+	for i, w := range want {
+		if start := diags[i].Span.Start; diags[i].Code != w.code || start.Line != w.line || start.Column != w.col {
+			t.Errorf("Expected %s at %d:%d, got %s at %d:%d", w.code, w.line, w.col, diags[i].Code, start.Line, start.Column)
+		}
+	}
+}
+
+func TestEveryBadArgumentIsReported(t *testing.T) {
+	program := `__write 1.0, true, 3; let xs:int[2] = [true, 1.5];
+	`
+	p := parser.NewParser(program)
+	grammar := parser.NewGrammar()
+	rootAST, err := p.Parse(grammar)
+	if err != nil {
+		t.Fatalf("Failed to parse program: %v", err)
+	}
+	expectDiagnostics(t, rootAST, CodeTypeMismatch, CodeTypeMismatch, CodeTypeMismatch, CodeTypeMismatch, CodeTypeMismatch)
+}
+
+func TestReturnTypeMismatch(t *testing.T) {
+	program := `fun foo() -> int { return 5.0; }
+	`
+	p := parser.NewParser(program)
+	grammar := parser.NewGrammar()
+	rootAST, err := p.Parse(grammar)
+	if err != nil {
+		t.Fatalf("Failed to parse program: %v", err)
+	}
+	expectDiagnostics(t, rootAST, CodeReturnTypeMismatch)
+}
+
+func TestFunctionUsedAsOperandMismatchType(t *testing.T) {
+	program := `fun foo() -> int { return 5; } let x:int = foo() + 10.0;
+	`
+	p := parser.NewParser(program)
+	grammar := parser.NewGrammar()
+	rootAST, err := p.Parse(grammar)
+	if err != nil {
+		t.Fatalf("Failed to parse program: %v", err)
+	}
+	expectDiagnostics(t, rootAST, CodeTypeMismatch)
+}
+
+func TestArrayWithInvalidSize(t *testing.T) {
+	program := `let x:int[5] = [1, 2, 3, 4, 5]; let y:int[3] = [1, 2, 3, 4];
+	`
+	p := parser.NewParser(program)
+	grammar := parser.NewGrammar()
+	rootAST, err := p.Parse(grammar)
+	if err != nil {
+		t.Fatalf("Failed to parse program: %v", err)
+	}
+	expectDiagnostics(t, rootAST, CodeArraySize)
+}
+
+func TestArrayWithInvalidTypeElement(t *testing.T) {
+	program := `let x:int[5] = [1, 2, 3, 4, 5]; let y:int[3] = [1, 2, 3.0];
+	`
+	p := parser.NewParser(program)
+	grammar := parser.NewGrammar()
+	rootAST, err := p.Parse(grammar)
+	if err != nil {
+		t.Fatalf("Failed to parse program: %v", err)
+	}
+	expectDiagnostics(t, rootAST, CodeTypeMismatch)
+}
+
+func TestArrayAccessWithInvalidType(t *testing.T) {
+	program := `let x:int[5] = [1, 2, 3, 4, 5]; let y:int = x[1.0];
+	`
+	p := parser.NewParser(program)
+	grammar := parser.NewGrammar()
+	rootAST, err := p.Parse(grammar)
+	if err != nil {
+		t.Fatalf("Failed to parse program: %v", err)
+	}
+	expectDiagnostics(t, rootAST, CodeInvalidOffsetType)
+}
+
+func TestAllErrorsAreReported(t *testing.T) {
+	program := `let x:int = 5.0; let x:int = 1; y = 2; fun foo() -> int { __print 1; } let z:bool = foo();
+	`
+	p := parser.NewParser(program)
+	grammar := parser.NewGrammar()
+	rootAST, err := p.Parse(grammar)
+	if err != nil {
+		t.Fatalf("Failed to parse program: %v", err)
+	}
+	expectDiagnostics(t, rootAST,
+		CodeTypeMismatch,
+		CodeVariableAlreadyDeclared,
+		CodeVariableNotDeclared,
+		CodeFunctionMustHaveReturn,
+		CodeTypeMismatch,
+	)
+}
+
+func TestReturnInsideLoopIsNotEnough(t *testing.T) {
+	program := `fun f(x:int) -> int { while (x > 0) { return 1; } }
+	`
+	p := parser.NewParser(program)
+	grammar := parser.NewGrammar()
+	rootAST, err := p.Parse(grammar)
+	if err != nil {
+		t.Fatalf("Failed to parse program: %v", err)
+	}
+	expectDiagnostics(t, rootAST, CodeFunctionMustHaveReturn)
+}
+
+func TestIfWithoutElseIsNotEnough(t *testing.T) {
+	program := `fun f(x:int) -> int { if (x > 0) { return 1; } }
+	`
+	p := parser.NewParser(program)
+	grammar := parser.NewGrammar()
+	rootAST, err := p.Parse(grammar)
+	if err != nil {
+		t.Fatalf("Failed to parse program: %v", err)
+	}
+	expectDiagnostics(t, rootAST, CodeFunctionMustHaveReturn)
+}
+
+func TestReturnOnEveryPath(t *testing.T) {
+	program := `fun f(x:int) -> int { if (x > 0) { return 1; } else { while (true) { x = x - 1; if (x < 0) { return x; } } } } __print f(1);
+	`
+	p := parser.NewParser(program)
+	grammar := parser.NewGrammar()
+	rootAST, err := p.Parse(grammar)
+	if err != nil {
+		t.Fatalf("Failed to parse program: %v", err)
+	}
+	expectDiagnostics(t, rootAST)
+}
+
+func TestUnreachableCodeAfterReturn(t *testing.T) {
+	program := `fun f(x:int) -> int { return x; __print 2; __print 3; } __print f(1);
+	`
+	p := parser.NewParser(program)
+	grammar := parser.NewGrammar()
+	rootAST, err := p.Parse(grammar)
+	if err != nil {
+		t.Fatalf("Failed to parse program: %v", err)
+	}
+	expectDiagnostics(t, rootAST, CodeUnreachableCode)
+}
+
+func TestUnreachableCodeAfterIfElse(t *testing.T) {
+	program := `fun f(x:int) -> int { if (x > 0) { return 1; } else { return 2; } __print 3; } __print f(1);
+	`
+	p := parser.NewParser(program)
+	grammar := parser.NewGrammar()
+	rootAST, err := p.Parse(grammar)
+	if err != nil {
+		t.Fatalf("Failed to parse program: %v", err)
+	}
+	expectDiagnostics(t, rootAST, CodeUnreachableCode)
+}
+
+func TestReturnTypeCheckedInScope(t *testing.T) {
+	program := `fun f(x:int) -> float { if (x > 0) { let y:float = 1.0; return y; } return 0; }
+	`
+	p := parser.NewParser(program)
+	grammar := parser.NewGrammar()
+	rootAST, err := p.Parse(grammar)
+	if err != nil {
+		t.Fatalf("Failed to parse program: %v", err)
+	}
+	expectDiagnostics(t, rootAST, CodeReturnTypeMismatch)
+}
+
+func TestUnusedVariablesAndParameters(t *testing.T) {
+	program := `fun f(a:int, b:int) -> int { let t:int = a; return a; } let x:int = f(1, 2);
+	`
+	p := parser.NewParser(program)
+	grammar := parser.NewGrammar()
+	rootAST, err := p.Parse(grammar)
+	if err != nil {
+		t.Fatalf("Failed to parse program: %v", err)
+	}
+	expectDiagnostics(t, rootAST, CodeUnusedVariable, CodeUnusedVariable, CodeUnusedVariable)
+}
+
+func TestUnusedAssignment(t *testing.T) {
+	program := `let x:int = 0; x = 1; x = 2; __print x;
+	`
+	p := parser.NewParser(program)
+	grammar := parser.NewGrammar()
+	rootAST, err := p.Parse(grammar)
+	if err != nil {
+		t.Fatalf("Failed to parse program: %v", err)
+	}
+	expectDiagnostics(t, rootAST, CodeUnusedAssignment)
+}
+
+func TestAssignmentsReadByLaterIterations(t *testing.T) {
+	program := `let w:int = 1; while (w < 10) { w = w * 2; } for (let i:int = 0; i < 3; i = i + 1) { __print i; }
+	`
+	p := parser.NewParser(program)
+	grammar := parser.NewGrammar()
+	rootAST, err := p.Parse(grammar)
+	if err != nil {
+		t.Fatalf("Failed to parse program: %v", err)
+	}
+	expectDiagnostics(t, rootAST)
+}
+
+func TestAssignmentReadByFunction(t *testing.T) {
+	program := `let c:int = 0; fun f() -> int { return c; } c = 5; __print f();
+	`
+	p := parser.NewParser(program)
+	grammar := parser.NewGrammar()
+	rootAST, err := p.Parse(grammar)
+	if err != nil {
+		t.Fatalf("Failed to parse program: %v", err)
+	}
+	expectDiagnostics(t, rootAST)
+}
+
+func TestUnusedFunction(t *testing.T) {
+	program := `fun f(n:int) -> int { if (n > 0) { return f(n - 1); } return 0; }
+	`
+	p := parser.NewParser(program)
+	grammar := parser.NewGrammar()
+	rootAST, err := p.Parse(grammar)
+	if err != nil {
+		t.Fatalf("Failed to parse program: %v", err)
+	}
+	expectDiagnostics(t, rootAST, CodeUnusedFunction)
+}
+
+// withoutInitialiser removes the initialiser of the first statement of the
+// first function in rootAST, since every declaration PArL parses has one.
+func withoutInitialiser(rootAST ast.ASTNode) {
+	fn := rootAST.(*ast.ASTProgramNode).Block.Stmts[0].(*ast.ASTFuncDeclNode)
+	decl := fn.Block.(*ast.ASTBlockNode).Stmts[0].(*ast.ASTVarDeclNode)
+	decl.Expression = &ast.ASTExpressionNode{Expr: &ast.ASTEpsilon{}}
+}
+
+func TestReadBeforeWrite(t *testing.T) {
+	program := `fun f(c:bool) -> int { let x:int = 0; if (c) { x = 1; } return x; } __print f(true);
+	`
+	p := parser.NewParser(program)
+	grammar := parser.NewGrammar()
+	rootAST, err := p.Parse(grammar)
+	if err != nil {
+		t.Fatalf("Failed to parse program: %v", err)
+	}
+	withoutInitialiser(rootAST)
+	expectDiagnostics(t, rootAST, CodeReadBeforeWrite)
+}
+
+func TestAssignedOnEveryPath(t *testing.T) {
+	program := `fun f(c:bool) -> int { let x:int = 0; if (c) { x = 1; } else { x = 2; } return x; } __print f(true);
+	`
+	p := parser.NewParser(program)
+	grammar := parser.NewGrammar()
+	rootAST, err := p.Parse(grammar)
+	if err != nil {
+		t.Fatalf("Failed to parse program: %v", err)
+	}
+	withoutInitialiser(rootAST)
+	expectDiagnostics(t, rootAST)
+}
+
+func TestReadBeforeWriteInBranchesAndLoops(t *testing.T) {
+	tests := []struct {
+		name    string
+		program string
+		codes   []string
+	}{
+		{"read in a branch", `fun f(c:bool) -> int { let x:int = 0; if (c) { __print x; } x = 1; return x; } __print f(true);`, []string{CodeReadBeforeWrite}},
+		{"read in a while body", `fun f(c:bool) -> int { let x:int = 0; while (c) { __print x; x = 1; } return 0; } __print f(true);`, []string{CodeReadBeforeWrite}},
+		{"assigned in a for body", `fun f(n:int) -> int { let x:int = 0; for (let i:int = 0; i < n; i = i + 1) { x = i; } return x; } __print f(2);`, []string{CodeReadBeforeWrite}},
+		{"assigned before the loop", `fun f(c:bool) -> int { let x:int = 0; x = 1; while (c) { __print x; x = 2; } return 0; } __print f(true);`, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := parser.NewParser(tt.program)
+			rootAST, err := p.Parse(parser.NewGrammar())
+			if err != nil {
+				t.Fatalf("Failed to parse program: %v", err)
+			}
+			withoutInitialiser(rootAST)
+			expectDiagnostics(t, rootAST, tt.codes...)
+		})
+	}
+}
+
+func TestSuggestionsAndNotes(t *testing.T) {
+	tests := []struct {
+		program string
+		notes   []string
+	}{
+		{`let p1_score:int = 0; __print p1_scor;`, []string{"did you mean `p1_score`?"}},
+		{`let p1_score:int = 0; __print total;`, nil},
+		{`fun score() -> int { return 1; } __print scores();`, []string{"did you mean `score`?"}},
+		{`let x:int = 5; let x:float = 1.0;`, []string{"x first declared here"}},
+		{`let x:int = 5; x = 2.5;`, []string{"cast the value with `as int`", "x declared here as int"}},
+		{`let x:bool = 5;`, nil},
+		{`fun foo(x:int, y:int) -> int { return x + y; } let z:int = foo(5);`, []string{"foo declared here"}},
+		{`fun foo(x:bool) -> bool { return x; } let z:bool = foo(1);`, []string{"x declared here as bool"}},
+	}
+	for _, tt := range tests {
+		p := parser.NewParser(tt.program)
+		grammar := parser.NewGrammar()
+		rootAST, err := p.Parse(grammar)
+		if err != nil {
+			t.Fatalf("Failed to parse program: %v", err)
+		}
+		diags := NewSemanticVisitor().Analyze(rootAST)
+		if len(diags) == 0 {
+			t.Fatalf("%s: expected a diagnostic", tt.program)
+		}
+		got := []string{}
+		for _, n := range diags[0].Notes {
+			got = append(got, n.Message)
+		}
+		if strings.Join(got, "\n") != strings.Join(tt.notes, "\n") {
+			t.Errorf("%s: expected notes %q, got %q", tt.program, tt.notes, got)
+		}
+	}
+}
+
+func TestAnalyzeIncremental(t *testing.T) {
+	v := NewSemanticVisitor()
+	check := func(program string) []diag.Diagnostic {
+		p := parser.NewParser(program)
+		rootAST, err := p.Parse(parser.NewGrammar())
+		if err != nil {
+			t.Fatalf("Failed to parse program: %v", err)
+		}
+		return v.AnalyzeIncremental(rootAST.(*ast.ASTProgramNode).Block.Stmts)
+	}
+
+	if diags := check(`let x:int = 1; fun f(n:int) -> int { return n + x; }`); len(diags) != 0 {
+		t.Fatalf("Expected no diagnostics, got %v", diags)
+	}
+	if diags := check(`let y:float = f(x) as float; let z:int = true;`); len(diags) != 1 || diags[0].Code != CodeTypeMismatch {
+		t.Fatalf("Expected a single type mismatch, got %v", diags)
+	}
+	// nothing from the input with an error is kept
+	if diags := check(`__print y;`); len(diags) != 1 || diags[0].Code != CodeVariableNotDeclared {
+		t.Fatalf("Expected y to be undeclared, got %v", diags)
+	}
+	if diags := check(`let x:int = 2;`); len(diags) != 1 || diags[0].Code != CodeVariableAlreadyDeclared {
+		t.Fatalf("Expected x to be declared already, got %v", diags)
+	}
+}
+
+func TestExpressionTypesAreRecorded(t *testing.T) {
+	program := `fun f(n:int) -> float { return n as float; }
+	let a:int[3] = [1, 2, 3];
+	let c:colour = __read(0, a[1]);
+	for (let i:int = 0; i < 3; i = i + 1) {
+		if ((a[i] != 2) and not (f(i) >= 1.5)) { __print -a[i]; }
+	}
+	__write 0, __random_int(__width), c;`
+	p := parser.NewParser(program)
+	rootAST, err := p.Parse(parser.NewGrammar())
+	if err != nil {
+		t.Fatalf("Failed to parse program: %v", err)
+	}
+	if diags := NewSemanticVisitor().Analyze(rootAST); len(diags) != 0 {
+		t.Fatalf("Expected no diagnostics, got %v", diags)
+	}
+
+	want := map[string]types.Type{"<": types.Bool, "!=": types.Bool, ">=": types.Bool, "and": types.Bool, "+": types.Int}
+	inspect(rootAST, func(node ast.ASTNode) bool {
+		// parameters have an empty initialiser, which has no value
+		if _, ok := node.(*ast.ASTFormalParamsNode); ok {
+			return false
+		}
+		expr, ok := node.(ast.Expr)
+		if !ok {
+			return true
+		}
+		if _, isBuiltin := node.(*ast.ASTBuiltinFuncNode); !isBuiltin && expr.ExprType() == nil {
+			t.Errorf("%T at %v has no type", node, node.Span().Start)
+		}
+		if bin, ok := node.(*ast.ASTBinaryOpNode); ok && !bin.ExprType().Equal(want[bin.Operator]) {
+			t.Errorf("Expected %s to be %v, got %v", bin.Operator, want[bin.Operator], bin.ExprType())
+		}
+		return true
+	})
+}
+
+func TestResolveBindsNamesAndSlots(t *testing.T) {
+	program := `let a:int = 1;
+	let arr:int[3] = [1, 2, 3];
+	fun f(n:int) -> int {
+		let m:int = n + a;
+		return m;
+	}
+	if (a < 2) { let t:int = f(arr[0]); a = t; } else { let u:float = 1.0; __print u; }
+	{ let b:bool = true; __print b; }`
+	p := parser.NewParser(program)
+	rootAST, err := p.Parse(parser.NewGrammar())
+	if err != nil {
+		t.Fatalf("Failed to parse program: %v", err)
+	}
+	if diags := NewSemanticVisitor().Analyze(rootAST); diag.HasErrors(diags) {
+		t.Fatalf("Expected no errors, got %v", diags)
+	}
+
+	// the branches of the if share its frame, a function's frame holds
+	// its parameters, then what it captures, then its locals, and arrays
+	// take a slot per element
+	want := map[string]ast.Slot{
+		"a": {Depth: 0, Index: 0}, "arr": {Depth: 0, Index: 1},
+		"n": {Depth: 1, Index: 0}, "m": {Depth: 1, Index: 2},
+		"t": {Depth: 1, Index: 0}, "u": {Depth: 1, Index: 1},
+		"b": {Depth: 1, Index: 0},
+	}
+	decls := map[string]*ast.ASTVarDeclNode{}
+	inspect(rootAST, func(node ast.ASTNode) bool {
+		switch n := node.(type) {
+		case *ast.ASTVarDeclNode:
+			decls[n.Token.Lexeme] = n
+			if n.Slot != want[n.Token.Lexeme] {
+				t.Errorf("Expected %s in slot %v, got %v", n.Token.Lexeme, want[n.Token.Lexeme], n.Slot)
+			}
+		case *ast.ASTVariableNode:
+			if n.Decl == nil || n.Decl.Token.Lexeme != n.Token.Lexeme {
+				t.Errorf("%s at %v is bound to %v", n.Token.Lexeme, n.Span().Start, n.Decl)
+			}
+		case *ast.ASTFuncCallNode:
+			if n.Decl == nil || n.Decl.Token.Lexeme != "f" {
+				t.Errorf("Expected the call to be bound to f, got %v", n.Decl)
+			}
+		}
+		return true
+	})
+	if len(decls) != len(want) {
+		t.Errorf("Expected %d declarations, found %d", len(want), len(decls))
+	}
+}
+
+func TestCaptures(t *testing.T) {
+	program := `let base:int = 100;
+	let scale:int[2] = [2, 3];
+	fun g(n:int) -> int { return n * scale[1]; }
+	fun f(n:int) -> int {
+		if (n <= 0) { return base; }
+		let r:int = f(n - 1);
+		return g(r);
+	}
+	__print f(2);`
+	p := parser.NewParser(program)
+	rootAST, err := p.Parse(parser.NewGrammar())
+	if err != nil {
+		t.Fatalf("Failed to parse program: %v", err)
+	}
+	if diags := NewSemanticVisitor().Analyze(rootAST); diag.HasErrors(diags) {
+		t.Fatalf("Expected no errors, got %v", diags)
+	}
+
+	// f reads base itself and scale through g; captures follow the
+	// parameters and come before the locals
+	want := map[string]string{"g": "scale@1", "f": "base@1 scale@2"}
+	inspect(rootAST, func(node ast.ASTNode) bool {
+		switch n := node.(type) {
+		case *ast.ASTFuncDeclNode:
+			var got []string
+			for _, c := range n.Captures {
+				got = append(got, fmt.Sprintf("%s@%d", c.Decl.Token.Lexeme, c.Index))
+			}
+			if strings.Join(got, " ") != want[n.Token.Lexeme] {
+				t.Errorf("Expected %s to capture %q, got %q", n.Token.Lexeme, want[n.Token.Lexeme], got)
+			}
+		case *ast.ASTVarDeclNode:
+			if n.Token.Lexeme == "r" && n.Slot.Index != 4 {
+				t.Errorf("Expected r in slot 4, got %d", n.Slot.Index)
+			}
+		}
+		return true
+	})
+}
+
+func TestWrittenCaptures(t *testing.T) {
+	program := `let total:int = 0;
+	let xs:int[2] = [0, 0];
+	fun add(n:int) -> int { total = total + n; return total; }
+	fun mark(i:int) -> int { xs[i] = 1; return xs[0]; }
+	fun both(n:int) -> int { let m:int = mark(0); return add(n) + m; }
+	__print both(1);`
+	p := parser.NewParser(program)
+	rootAST, err := p.Parse(parser.NewGrammar())
+	if err != nil {
+		t.Fatalf("Failed to parse program: %v", err)
+	}
+	if diags := NewSemanticVisitor().Analyze(rootAST); diag.HasErrors(diags) {
+		t.Fatalf("Expected no errors, got %v", diags)
+	}
+
+	// both writes xs and total through the functions it calls
+	want := map[string]string{"add": "total!", "mark": "xs!", "both": "xs! total!"}
+	inspect(rootAST, func(node ast.ASTNode) bool {
+		if n, ok := node.(*ast.ASTFuncDeclNode); ok {
+			var got []string
+			for _, c := range n.Captures {
+				name := c.Decl.Token.Lexeme
+				if c.Written {
+					name += "!"
+				}
+				got = append(got, name)
+			}
+			if strings.Join(got, " ") != want[n.Token.Lexeme] {
+				t.Errorf("Expected %s to capture %q, got %q", n.Token.Lexeme, want[n.Token.Lexeme], got)
+			}
+		}
+		return true
+	})
+}
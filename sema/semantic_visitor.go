@@ -0,0 +1,473 @@
+// Package sema performs scope and type checking on a parsed program.
+package sema
+
+import (
+	"fmt"
+	"maps"
+
+	"github.com/giuszeppe/compiler-theory/ast"
+	"github.com/giuszeppe/compiler-theory/diag"
+	"github.com/giuszeppe/compiler-theory/types"
+)
+
+type SemanticVisitor struct {
+	Diagnostics []diag.Diagnostic
+	// Uses maps every identifier that resolved (an *ast.ASTVariableNode or
+	// *ast.ASTFuncCallNode) to the declaration it refers to, including a
+	// declaration of the wrong kind, which the Decl field of the node does
+	// not record.
+	Uses map[ast.ASTNode]ast.ASTNode
+
+	// reported holds the code and span of each diagnostic reported, see
+	// report.
+	reported map[reportKey]bool
+
+	// global is the outermost scope, which AnalyzeIncremental keeps from
+	// one call to the next.
+	global *scope
+
+	// returnTypes holds the return type of each function being checked,
+	// innermost last.
+	returnTypes []types.Type
+}
+
+func NewSemanticVisitor() *SemanticVisitor {
+	return &SemanticVisitor{
+		Uses:     make(map[ast.ASTNode]ast.ASTNode),
+		reported: make(map[reportKey]bool),
+		global:   newScope(nil, &frame{}),
+	}
+}
+
+type reportKey struct {
+	code string
+	span diag.Span
+}
+
+// report records d unless a diagnostic with the same code was already
+// reported at the same place, which happens when a subtree is type checked
+// more than once.
+func (v *SemanticVisitor) report(d diag.Diagnostic) {
+	key := reportKey{d.Code, d.Span}
+	if v.reported[key] {
+		return
+	}
+	v.reported[key] = true
+	v.Diagnostics = append(v.Diagnostics, d)
+}
+
+// Analyze checks the program rooted at node and returns every diagnostic found.
+// Names are resolved first, in a pass of their own, and the tree is then
+// type checked using the bindings on its nodes.
+func (v *SemanticVisitor) Analyze(node ast.ASTNode) []diag.Diagnostic {
+	r := v.resolver()
+	r.resolve(node)
+	r.layout(v.global.frame)
+	node.Accept(v)
+	v.Diagnostics = mergeDiagnostics(r.diags, v.Diagnostics)
+	if program, ok := node.(*ast.ASTProgramNode); ok {
+		v.checkDataFlow(program)
+	}
+	return v.Diagnostics
+}
+
+func (v *SemanticVisitor) resolver() *resolver {
+	return &resolver{scope: v.global, uses: v.Uses}
+}
+
+// AnalyzeIncremental checks stmts as if they followed the statements of
+// earlier calls in one program, sharing their global scope, and returns the
+// diagnostics for stmts alone. If they hold errors, nothing they declare is
+// kept. Warnings that need the whole program, such as W003 for a variable
+// that is never read, are not reported.
+func (v *SemanticVisitor) AnalyzeIncremental(stmts []ast.ASTNode) []diag.Diagnostic {
+	saved, decls := maps.Clone(v.global.names), len(v.global.frame.decls)
+
+	v.Diagnostics = nil
+	clear(v.reported)
+	r := v.resolver()
+	r.stmts(stmts)
+	r.layout(v.global.frame)
+	block := &ast.ASTBlockNode{Stmts: stmts}
+	block.Accept(v)
+	v.checkProgramFlow(block)
+	v.Diagnostics = mergeDiagnostics(r.diags, v.Diagnostics)
+	if diag.HasErrors(v.Diagnostics) {
+		v.global.names, v.global.frame.decls = saved, v.global.frame.decls[:decls]
+	}
+	return v.Diagnostics
+}
+
+func (v *SemanticVisitor) VisitIntegerNode(node *ast.ASTIntegerNode) {
+	v.getExpressionType(node)
+}
+
+func (v *SemanticVisitor) VisitVariableNode(node *ast.ASTVariableNode) {
+	if node.Decl == nil {
+		// the resolver reported why
+		node.SetExprType(types.Invalid)
+		return
+	}
+	if _, isEpsilon := node.Offset.(*ast.ASTEpsilon); !isEpsilon {
+		node.Offset.Accept(v)
+		offsetType := v.getExpressionType(node.Offset)
+		if !types.AssignableTo(offsetType, types.Int) {
+			v.report(ErrInvalidOffsetType(types.Int, offsetType, node.Token))
+		}
+	}
+	// reports an offset on a scalar
+	v.getExpressionType(node)
+}
+
+// getExpressionType returns the type of the expression node, nil if it has
+// no value, or types.Invalid if it is in error. The type of an expression
+// is worked out once and recorded on the node for later passes; asking
+// again returns the recorded type. Every Visit method of an expression node
+// calls it, so after Analyze each expression carries its type.
+func (v *SemanticVisitor) getExpressionType(node ast.ASTNode) types.Type {
+	expr, ok := node.(ast.Expr)
+	if !ok {
+		return v.inferType(node)
+	}
+	if typ := expr.ExprType(); typ != nil {
+		return typ
+	}
+	typ := v.inferType(node)
+	expr.SetExprType(typ)
+	return typ
+}
+
+// inferType checks the expression node and returns its type.
+func (v *SemanticVisitor) inferType(node ast.ASTNode) types.Type {
+	switch n := node.(type) {
+	case *ast.ASTIntegerNode:
+		return types.Int
+	case *ast.ASTFloatNode:
+		return types.Float
+	case *ast.ASTBooleanNode:
+		return types.Bool
+	case *ast.ASTColorNode:
+		return types.Colour
+	case *ast.ASTVariableNode:
+		varDeclNode := n.Decl
+		if varDeclNode == nil {
+			return types.Invalid
+		}
+		if _, isEpsilon := n.Offset.(*ast.ASTEpsilon); !isEpsilon {
+			if !types.IsArray(varDeclNode.Type) {
+				v.report(ErrNotAnArray(n.Token))
+				return types.Invalid
+			}
+			return types.Elem(varDeclNode.Type)
+		}
+		return varDeclNode.Type
+	case *ast.ASTBinaryOpNode:
+		leftType := v.getExpressionType(n.Left)
+		rightType := v.getExpressionType(n.Right)
+		if leftType == types.Invalid || rightType == types.Invalid {
+			return types.Invalid
+		}
+		if !types.AssignableTo(rightType, leftType) {
+			v.report(ErrTypeMismatch(leftType, rightType, n.Token))
+			return types.Invalid
+		}
+		if n.Operator == "<" || n.Operator == ">" || n.Operator == "<=" || n.Operator == ">=" || n.Operator == "==" || n.Operator == "!=" {
+			return types.Bool
+		}
+		return leftType
+	case *ast.ASTUnaryOpNode:
+		return v.getExpressionType(n.Operand)
+	case *ast.ASTAssignmentNode:
+		return v.getExpressionType(n.Expr)
+	case *ast.ASTFuncCallNode:
+		funcDeclNode := n.Decl
+		if funcDeclNode == nil {
+			return types.Invalid
+		}
+		formalParamsNode, _ := funcDeclNode.Params.(*ast.ASTFormalParamsNode)
+		actualParamsNode, _ := n.Params.(*ast.ASTActualParamsNode)
+		if len(actualParamsNode.Params) != len(formalParamsNode.Params) {
+			v.report(ErrArgumentCountMismatch(len(formalParamsNode.Params), len(actualParamsNode.Params), n.Span()).WithNote(declSpan(funcDeclNode), "%s declared here", funcDeclNode.Token.Lexeme))
+			return funcDeclNode.ReturnType
+		}
+		// each argument is reported where it is passed, since the
+		// parameter is shared by every call
+		for i, param := range actualParamsNode.Params {
+			paramType := v.getExpressionType(param)
+			formParamNode := formalParamsNode.Params[i].(*ast.ASTVarDeclNode)
+			funcParamType := formParamNode.Type
+			if !types.AssignableTo(paramType, funcParamType) {
+				v.report(ErrTypeMismatchAt(funcParamType, paramType, param.Span()).WithNote(declSpan(formParamNode), "%s declared here as %v", formParamNode.Token.Lexeme, funcParamType))
+			}
+		}
+		return funcDeclNode.ReturnType
+	case *ast.ASTReturnNode:
+		return v.getExpressionType(n.Expr)
+	case *ast.ASTExpressionNode:
+		return v.getExpressionType(n.Expr)
+	case *ast.ASTTypeCastNode:
+		return n.Type
+	case *ast.ASTArrayNode:
+		return n.Type
+	case *ast.ASTEpsilon:
+		return nil
+	case *ast.ASTBuiltinFuncNode:
+		switch n.Token.Lexeme {
+		case "__random_int":
+			v.checkBuiltinArgs(n, types.Int)
+			return types.Int
+		case "__delay":
+			v.checkBuiltinArgs(n, types.Int)
+			return nil
+		case "__height", "__width":
+			v.checkBuiltinArgs(n)
+			return types.Int
+		case "__write":
+			v.checkBuiltinArgs(n, types.Int, types.Int, types.Colour)
+			return nil
+		case "__print":
+			if len(n.Args) != 1 {
+				v.report(ErrArgumentCountMismatch(1, len(n.Args), diag.SpanOf(n.Token)))
+			}
+			return nil
+		case "__write_box":
+			v.checkBuiltinArgs(n, types.Int, types.Int, types.Int, types.Int, types.Colour)
+			return nil
+		case "__read":
+			v.checkBuiltinArgs(n, types.Int, types.Int)
+			return types.Colour
+		case "__clear":
+			v.checkBuiltinArgs(n, types.Colour)
+			return nil
+		default:
+			v.report(ErrUnknownExpressionType(n))
+			return types.Invalid
+		}
+	default:
+		v.report(ErrUnknownExpressionType(node))
+		return types.Invalid
+	}
+}
+
+// checkBuiltinArgs reports a diagnostic, at the argument, for every argument
+// of n whose type differs from the expected one.
+func (v *SemanticVisitor) checkBuiltinArgs(n *ast.ASTBuiltinFuncNode, expected ...types.Type) {
+	if len(n.Args) != len(expected) {
+		v.report(ErrArgumentCountMismatch(len(expected), len(n.Args), diag.SpanOf(n.Token)))
+		return
+	}
+	for i, arg := range n.Args {
+		argType := v.getExpressionType(arg)
+		if !types.AssignableTo(argType, expected[i]) {
+			v.report(ErrTypeMismatchAt(expected[i], argType, arg.Span()))
+		}
+	}
+}
+
+func (v *SemanticVisitor) VisitAssignmentNode(node *ast.ASTAssignmentNode) {
+	node.Expr.Accept(v)
+	varDeclNode := node.Id.Decl
+	if varDeclNode == nil {
+		return
+	}
+	exprType := v.getExpressionType(node.Expr)
+	targetType := varDeclNode.Type
+	if _, isEpsilon := node.Id.Offset.(*ast.ASTEpsilon); !isEpsilon {
+		node.Id.Offset.Accept(v)
+		offsetType := v.getExpressionType(node.Id.Offset)
+		if !types.AssignableTo(offsetType, types.Int) {
+			v.report(ErrInvalidOffsetType(types.Int, offsetType, node.Id.Token))
+		}
+		if !types.IsArray(targetType) {
+			v.report(ErrNotAnArray(node.Id.Token))
+			return
+		}
+		targetType = types.Elem(targetType)
+	}
+	if !types.AssignableTo(exprType, targetType) {
+		v.report(ErrTypeMismatch(targetType, exprType, node.Id.Token).
+			WithNote(diag.SpanOf(varDeclNode.Token), "%s declared here as %s", varDeclNode.Token.Lexeme, varDeclNode.Type))
+	}
+}
+
+func (v *SemanticVisitor) VisitVarDeclNode(node *ast.ASTVarDeclNode) {
+	node.Expression.Accept(v)
+	nodeType := v.getExpressionType(node.Expression)
+	if nodeType != nil && !types.AssignableTo(nodeType, node.Type) {
+		v.report(ErrTypeMismatch(node.Type, nodeType, node.Token))
+	}
+}
+func (v *SemanticVisitor) VisitBlockNode(node *ast.ASTBlockNode) {
+	for _, stmt := range node.Stmts {
+		stmt.Accept(v)
+	}
+}
+func (v *SemanticVisitor) VisitTypeNode(node *ast.ASTTypeNode) {
+	// Do nothing
+}
+func (v *SemanticVisitor) VisitFunctionNode(node *ast.ASTFuncDeclNode) {
+	// Check if the function is already declared in the current scope
+}
+
+func (v *SemanticVisitor) VisitProgramNode(node *ast.ASTProgramNode) {
+	// Visit the block node
+	node.Block.Accept(v)
+	v.checkProgramFlow(&node.Block)
+}
+func (v *SemanticVisitor) VisitIfNode(node *ast.ASTIfNode) {
+	// Visit the condition and the block
+	node.Condition.Accept(v)
+	node.ThenBlock.Accept(v)
+	if node.ElseBlock != nil {
+		node.ElseBlock.Accept(v)
+	}
+}
+
+func (v *SemanticVisitor) VisitWhileNode(node *ast.ASTWhileNode) {
+	// Visit the condition and the block
+	node.Condition.Accept(v)
+	node.Block.Accept(v)
+}
+
+func (v *SemanticVisitor) VisitForNode(node *ast.ASTForNode) {
+	// Visit the initialization, condition, and block
+	node.VarDecl.Accept(v)
+	node.Condition.Accept(v)
+	node.Increment.Accept(v)
+	node.Block.Accept(v)
+}
+
+func (v *SemanticVisitor) VisitTypeCastNode(node *ast.ASTTypeCastNode) {
+	// Visit the expression
+	node.Expr.Accept(v)
+	v.getExpressionType(node)
+}
+
+func (v *SemanticVisitor) VisitFormalParamsNode(node *ast.ASTFormalParamsNode) {
+	// Visit each parameter
+	for _, param := range node.Params {
+		param.Accept(v)
+	}
+}
+func (v *SemanticVisitor) VisitEpsilon(node *ast.ASTEpsilon) {
+	// Do nothing
+}
+func (v *SemanticVisitor) VisitActualParamNode(node *ast.ASTActualParamNode) {
+	// Visit the expression
+	node.Value.Accept(v)
+}
+
+func (v *SemanticVisitor) VisitFuncCallNode(node *ast.ASTFuncCallNode) {
+	node.Params.Accept(v)
+	v.getExpressionType(node)
+}
+
+func (v *SemanticVisitor) VisitPrintNode(node *ast.ASTPrintNode) {
+	// Visit the expression
+	node.Expr.Accept(v)
+}
+func (v *SemanticVisitor) VisitBinaryOpNode(node *ast.ASTBinaryOpNode) {
+	// Visit the left and right operands
+	node.Left.Accept(v)
+	node.Right.Accept(v)
+
+	// Check if type is the same
+	v.getExpressionType(node)
+}
+func (v *SemanticVisitor) VisitUnaryOpNode(node *ast.ASTUnaryOpNode) {
+	// Visit the operand
+	node.Operand.Accept(v)
+	v.getExpressionType(node)
+}
+
+func (v *SemanticVisitor) VisitBooleanNode(node *ast.ASTBooleanNode) {
+	v.getExpressionType(node)
+}
+func (v *SemanticVisitor) VisitColorNode(node *ast.ASTColorNode) {
+	hexValue := node.Value
+	if (len(hexValue) != 7 && len(hexValue) != 4) || hexValue[0] != '#' {
+		v.report(ErrInvalidColorValue(hexValue, node.Token))
+		node.SetExprType(types.Invalid)
+		return
+	}
+	// Check if the color value is valid
+	if _, err := fmt.Sscanf(hexValue, "#%x", new(int)); err != nil {
+		v.report(ErrInvalidColorValue(hexValue, node.Token))
+		node.SetExprType(types.Invalid)
+		return
+	}
+	v.getExpressionType(node)
+}
+func (v *SemanticVisitor) VisitBuiltinFuncNode(node *ast.ASTBuiltinFuncNode) {
+	// Visit the arguments
+	for _, arg := range node.Args {
+		arg.Accept(v)
+	}
+	v.getExpressionType(node)
+}
+func (v *SemanticVisitor) VisitReturnNode(node *ast.ASTReturnNode) {
+	// Visit the expression
+	node.Expr.Accept(v)
+	if len(v.returnTypes) == 0 {
+		return
+	}
+	expectedType := v.returnTypes[len(v.returnTypes)-1]
+	returnType := v.getExpressionType(node.Expr)
+	if !types.AssignableTo(returnType, expectedType) {
+		v.report(ErrReturnTypeMismatch(expectedType, returnType, node.Token))
+	}
+}
+
+func (v *SemanticVisitor) VisitActualParamsNode(node *ast.ASTActualParamsNode) {
+	// Visit each actual parameter
+	for _, param := range node.Params {
+		param.Accept(v)
+	}
+}
+
+func (v *SemanticVisitor) VisitExpressionNode(node *ast.ASTExpressionNode) {
+	// Visit the expression
+	node.Expr.Accept(v)
+	v.getExpressionType(node)
+}
+
+func (v *SemanticVisitor) VisitFloatNode(node *ast.ASTFloatNode) {
+	v.getExpressionType(node)
+}
+
+func (v *SemanticVisitor) VisitFormalParamNode(node *ast.ASTFormalParamNode) {
+	// Declared by the resolver
+}
+
+func (v *SemanticVisitor) VisitFuncDeclNode(node *ast.ASTFuncDeclNode) {
+	v.returnTypes = append(v.returnTypes, node.ReturnType)
+	defer func() { v.returnTypes = v.returnTypes[:len(v.returnTypes)-1] }()
+	node.Params.Accept(v)
+	node.Block.Accept(v)
+	if funcBlock, ok := node.Block.(*ast.ASTBlockNode); ok {
+		v.checkFunctionFlow(node, funcBlock)
+	}
+}
+
+func (v *SemanticVisitor) VisitSimpleExpressionNode(node *ast.ASTSimpleExpression) {
+}
+
+func (v *SemanticVisitor) VisitArrayNode(node *ast.ASTArrayNode) {
+	if node.Size < 0 {
+		v.report(ErrArraySizeNegative(node.Size, node.Token))
+	}
+	if node.Size < len(node.Items) {
+		v.report(ErrArraySize(node.Size, len(node.Items), node.Token))
+	}
+	for _, item := range node.Items {
+		item.Accept(v)
+		itemType := v.getExpressionType(item)
+		if elemType := types.Elem(node.Type); !types.AssignableTo(itemType, elemType) {
+			v.report(ErrTypeMismatchAt(elemType, itemType, item.Span()))
+		}
+	}
+}
+
+func (v *SemanticVisitor) VisitErrorNode(node *ast.ASTErrorNode) {
+	// Already reported by the parser
+}
@@ -0,0 +1,90 @@
+package sema
+
+import (
+	"sort"
+
+	"github.com/giuszeppe/compiler-theory/ast"
+	"github.com/giuszeppe/compiler-theory/diag"
+	"github.com/giuszeppe/compiler-theory/lexer"
+)
+
+// errVariableNotDeclared is ErrVariableNotDeclared with a suggestion of a
+// variable in scope whose name is close to tok's.
+func (r *resolver) errVariableNotDeclared(tok lexer.Token) diag.Diagnostic {
+	d := ErrVariableNotDeclared(tok)
+	if name, ok := r.similarName(tok.Lexeme, func(n ast.ASTNode) bool {
+		_, ok := n.(*ast.ASTVarDeclNode)
+		return ok
+	}); ok {
+		d = d.WithHelp("did you mean `%s`?", name)
+	}
+	return d
+}
+
+// errFunctionNotDeclared is ErrFunctionNotDeclared with a suggestion of a
+// function in scope whose name is close to tok's.
+func (r *resolver) errFunctionNotDeclared(tok lexer.Token) diag.Diagnostic {
+	d := ErrFunctionNotDeclared(tok)
+	if name, ok := r.similarName(tok.Lexeme, func(n ast.ASTNode) bool {
+		_, ok := n.(*ast.ASTFuncDeclNode)
+		return ok
+	}); ok {
+		d = d.WithHelp("did you mean `%s`?", name)
+	}
+	return d
+}
+
+// similarName returns the name in scope closest to name, among those whose
+// declarations match keep. Names more than a third of name's length away,
+// counting at least one edit, are not considered close.
+func (r *resolver) similarName(name string, keep func(ast.ASTNode) bool) (string, bool) {
+	var names []string
+	for s := r.scope; s != nil; s = s.parent {
+		for n, decl := range s.names {
+			if keep(decl) {
+				names = append(names, n)
+			}
+		}
+	}
+	sort.Strings(names)
+
+	best, bestDist := "", max(1, len(name)/3)+1
+	for _, n := range names {
+		if d := editDistance(name, n); d < bestDist {
+			best, bestDist = n, d
+		}
+	}
+	return best, best != ""
+}
+
+// editDistance returns the Levenshtein distance between a and b.
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
+
+// declSpan returns the span of the name a declaration declares.
+func declSpan(decl ast.ASTNode) diag.Span {
+	switch d := decl.(type) {
+	case *ast.ASTVarDeclNode:
+		return diag.SpanOf(d.Token)
+	case *ast.ASTFuncDeclNode:
+		return diag.SpanOf(d.Token)
+	}
+	return decl.Span()
+}
@@ -0,0 +1,115 @@
+// Package types is the model of PArL types that every phase shares, from
+// the lexer's type keywords to the slots the code generator allocates.
+package types
+
+import "fmt"
+
+// Type is a PArL type: a Primitive, an Array, or Invalid.
+type Type interface {
+	String() string
+	// Equal reports whether the type is identical to u.
+	Equal(u Type) bool
+	isType()
+}
+
+// Primitive is one of the scalar types.
+type Primitive int
+
+const (
+	Int Primitive = iota + 1
+	Float
+	Bool
+	Colour
+)
+
+var primitives = map[string]Primitive{
+	"int":    Int,
+	"float":  Float,
+	"bool":   Bool,
+	"colour": Colour,
+}
+
+// Lookup returns the primitive type spelled name in the source.
+func Lookup(name string) (Primitive, bool) {
+	p, ok := primitives[name]
+	return p, ok
+}
+
+func (p Primitive) String() string {
+	switch p {
+	case Int:
+		return "int"
+	case Float:
+		return "float"
+	case Bool:
+		return "bool"
+	case Colour:
+		return "colour"
+	default:
+		return fmt.Sprintf("Primitive(%d)", int(p))
+	}
+}
+
+func (p Primitive) Equal(u Type) bool { return u == Type(p) }
+
+func (Primitive) isType() {}
+
+// Array is a fixed-length array, such as int[8].
+type Array struct {
+	Elem Type
+	Len  int
+}
+
+func (a Array) String() string { return fmt.Sprintf("%s[%d]", a.Elem, a.Len) }
+
+func (a Array) Equal(u Type) bool {
+	b, ok := u.(Array)
+	return ok && a.Len == b.Len && a.Elem.Equal(b.Elem)
+}
+
+func (Array) isType() {}
+
+// Invalid is the type of an expression that already produced a diagnostic.
+// It is assignable to and from every type, so that one mistake is reported
+// once rather than at every use of its result.
+var Invalid Type = invalid{}
+
+type invalid struct{}
+
+func (invalid) String() string    { return "<error>" }
+func (invalid) Equal(u Type) bool { return u == Invalid }
+func (invalid) isType()           {}
+
+// AssignableTo reports whether a value of type v can be stored where a
+// value of type t is expected: in a variable, a parameter, an array
+// element or a return value. PArL has no implicit conversions, so the
+// types must be identical.
+func AssignableTo(v, t Type) bool {
+	if v == Invalid || t == Invalid {
+		return true
+	}
+	return v != nil && t != nil && v.Equal(t)
+}
+
+// Elem returns the element type of an array type, and t itself otherwise.
+func Elem(t Type) Type {
+	if a, ok := t.(Array); ok {
+		return a.Elem
+	}
+	return t
+}
+
+// Size returns the number of values a value of type t is made of: the
+// length of an array, and 1 for anything else.
+func Size(t Type) int {
+	if a, ok := t.(Array); ok {
+		return a.Len
+	}
+	return 1
+}
+
+// IsArray reports whether t is an array type.
+func IsArray(t Type) bool {
+	_, ok := t.(Array)
+	return ok
+}
@@ -0,0 +1,68 @@
+package types
+
+import "testing"
+
+func TestString(t *testing.T) {
+	tests := []struct {
+		typ  Type
+		want string
+	}{
+		{Int, "int"},
+		{Colour, "colour"},
+		{Array{Elem: Float, Len: 8}, "float[8]"},
+		{Invalid, "<error>"},
+	}
+	for _, tt := range tests {
+		if got := tt.typ.String(); got != tt.want {
+			t.Errorf("Expected %q, got %q", tt.want, got)
+		}
+	}
+}
+
+func TestLookup(t *testing.T) {
+	for _, p := range []Primitive{Int, Float, Bool, Colour} {
+		if got, ok := Lookup(p.String()); !ok || got != p {
+			t.Errorf("Lookup(%q) = %v, %v", p.String(), got, ok)
+		}
+	}
+	if _, ok := Lookup("color"); ok {
+		t.Errorf("Expected color not to be a type")
+	}
+}
+
+func TestEqualAndAssignable(t *testing.T) {
+	tests := []struct {
+		v, t       Type
+		equal      bool
+		assignable bool
+	}{
+		{Int, Int, true, true},
+		{Int, Float, false, false},
+		{Array{Int, 3}, Array{Int, 3}, true, true},
+		{Array{Int, 3}, Array{Int, 4}, false, false},
+		{Array{Int, 3}, Array{Float, 3}, false, false},
+		{Array{Int, 1}, Int, false, false},
+		{Invalid, Int, false, true},
+		{Array{Colour, 2}, Invalid, false, true},
+		{nil, Int, false, false},
+	}
+	for _, tt := range tests {
+		if tt.v != nil {
+			if got := tt.v.Equal(tt.t); got != tt.equal {
+				t.Errorf("%v.Equal(%v) = %v", tt.v, tt.t, got)
+			}
+		}
+		if got := AssignableTo(tt.v, tt.t); got != tt.assignable {
+			t.Errorf("AssignableTo(%v, %v) = %v", tt.v, tt.t, got)
+		}
+	}
+}
+
+func TestSize(t *testing.T) {
+	if Size(Int) != 1 || Size(Array{Bool, 5}) != 5 {
+		t.Errorf("Expected sizes 1 and 5, got %d and %d", Size(Int), Size(Array{Bool, 5}))
+	}
+	if Elem(Array{Colour, 2}) != Colour || Elem(Float) != Float {
+		t.Errorf("Expected Elem to unwrap arrays only")
+	}
+}
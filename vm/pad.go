@@ -0,0 +1,78 @@
+package vm
+
+import (
+	"fmt"
+	"io"
+)
+
+const (
+	DefaultPadWidth  = 36
+	DefaultPadHeight = 36
+)
+
+// Pad is an in-memory emulation of the PArIR display. Pixel (0, 0) is the
+// bottom-left corner, as in the web simulator.
+type Pad struct {
+	Width  int
+	Height int
+	Pixels []int
+}
+
+func NewPad(width, height int) *Pad {
+	return &Pad{
+		Width:  width,
+		Height: height,
+		Pixels: make([]int, width*height),
+	}
+}
+
+func (p *Pad) inBounds(x, y int) bool {
+	return x >= 0 && y >= 0 && x < p.Width && y < p.Height
+}
+
+// Write sets the colour of pixel (x, y). Writes outside the pad are ignored.
+func (p *Pad) Write(x, y, colour int) {
+	if p.inBounds(x, y) {
+		p.Pixels[y*p.Width+x] = colour
+	}
+}
+
+// WriteBox sets the colour of the w*h region whose bottom-left corner is (x, y).
+func (p *Pad) WriteBox(x, y, w, h, colour int) {
+	for i := x; i < x+w; i++ {
+		for j := y; j < y+h; j++ {
+			p.Write(i, j, colour)
+		}
+	}
+}
+
+// Read returns the colour of pixel (x, y), or 0 outside the pad.
+func (p *Pad) Read(x, y int) int {
+	if !p.inBounds(x, y) {
+		return 0
+	}
+	return p.Pixels[y*p.Width+x]
+}
+
+// Clear paints the whole pad with colour.
+func (p *Pad) Clear(colour int) {
+	for i := range p.Pixels {
+		p.Pixels[i] = colour
+	}
+}
+
+// WritePPM dumps the pad as a plain (P3) PPM image, top row first.
+func (p *Pad) WritePPM(w io.Writer) error {
+	if _, err := fmt.Fprintf(w, "P3\n%d %d\n255\n", p.Width, p.Height); err != nil {
+		return err
+	}
+	for y := p.Height - 1; y >= 0; y-- {
+		for x := 0; x < p.Width; x++ {
+			c := p.Read(x, y)
+			if _, err := fmt.Fprintf(w, "%d %d %d\n", (c>>16)&0xff, (c>>8)&0xff, c&0xff); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,503 @@
+// Package vm executes PArIR programs on an emulated pixel pad.
+package vm
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/giuszeppe/compiler-theory/parir"
+)
+
+// Value is a single PArIR operand. As in the web simulator every value
+// (int, float, bool, colour, address) is a number.
+type Value float64
+
+func (val Value) String() string {
+	return strconv.FormatFloat(float64(val), 'f', -1, 64)
+}
+
+func (val Value) truthy() bool {
+	return val != 0
+}
+
+func boolValue(b bool) Value {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// VM executes the PArIR code produced by GeneratorVisitor.
+type VM struct {
+	Program []parir.Instruction
+	Labels  map[string]int
+
+	PC       int
+	Operands []Value
+	Frames   [][]Value
+	Calls    []int
+
+	Pad      *Pad
+	Out      io.Writer
+	Rand     *rand.Rand
+	Sleep    func(time.Duration) // nil turns __delay into a no-op
+	MaxSteps int                 // 0 means no limit
+	Steps    int
+	Halted   bool
+}
+
+// NewVM returns a VM ready to run prog on a default-sized pad.
+func NewVM(prog []parir.Instruction) (*VM, error) {
+	vm := &VM{
+		Program: prog,
+		Labels:  make(map[string]int),
+		Pad:     NewPad(DefaultPadWidth, DefaultPadHeight),
+		Out:     os.Stdout,
+		Rand:    rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+	for i, instr := range prog {
+		if instr.Op == parir.OpLabel {
+			vm.Labels[instr.Arg.Name] = i
+		}
+	}
+	for i, instr := range prog {
+		switch {
+		case instr.Op == parir.OpMark || instr.Arg.Kind == parir.KindLocal:
+			return nil, fmt.Errorf("instruction %d: unassembled label @%s", i, instr.Arg.Name)
+		case instr.Arg.Kind == parir.KindLabel:
+			if _, ok := vm.Labels[instr.Arg.Name]; !ok {
+				return nil, fmt.Errorf("instruction %d: undefined label .%s", i, instr.Arg.Name)
+			}
+		}
+	}
+	return vm, nil
+}
+
+func (vm *VM) push(val Value) {
+	vm.Operands = append(vm.Operands, val)
+}
+
+func (vm *VM) pop() (Value, error) {
+	if len(vm.Operands) == 0 {
+		return 0, fmt.Errorf("operand stack underflow")
+	}
+	val := vm.Operands[len(vm.Operands)-1]
+	vm.Operands = vm.Operands[:len(vm.Operands)-1]
+	return val, nil
+}
+
+func (vm *VM) popN(n int) ([]Value, error) {
+	vals := make([]Value, n)
+	for i := range vals {
+		val, err := vm.pop()
+		if err != nil {
+			return nil, err
+		}
+		vals[i] = val
+	}
+	return vals, nil
+}
+
+func (vm *VM) popInt() (int, error) {
+	val, err := vm.pop()
+	return int(val), err
+}
+
+// frame returns the frame l levels below the top of the memory stack.
+func (vm *VM) frame(l int) ([]Value, error) {
+	if l < 0 || l >= len(vm.Frames) {
+		return nil, fmt.Errorf("frame level %d out of range (%d frames open)", l, len(vm.Frames))
+	}
+	return vm.Frames[len(vm.Frames)-1-l], nil
+}
+
+func (vm *VM) load(i, l int) (Value, error) {
+	f, err := vm.frame(l)
+	if err != nil {
+		return 0, err
+	}
+	if i < 0 || i >= len(f) {
+		return 0, fmt.Errorf("slot [%d:%d] out of range (frame size %d)", i, l, len(f))
+	}
+	return f[i], nil
+}
+
+func (vm *VM) store(i, l int, val Value) error {
+	f, err := vm.frame(l)
+	if err != nil {
+		return err
+	}
+	if i < 0 || i >= len(f) {
+		return fmt.Errorf("slot [%d:%d] out of range (frame size %d)", i, l, len(f))
+	}
+	f[i] = val
+	return nil
+}
+
+func (vm *VM) jump(addr int) error {
+	if addr < 0 || addr >= len(vm.Program) {
+		return fmt.Errorf("jump target %d outside program", addr)
+	}
+	vm.PC = addr
+	return nil
+}
+
+// Run executes the program from the first instruction until halt or until
+// the PC falls off the end of the program.
+func (vm *VM) Run() error {
+	vm.PC = 0
+	for !vm.Halted && vm.PC < len(vm.Program) {
+		if vm.MaxSteps > 0 && vm.Steps >= vm.MaxSteps {
+			return fmt.Errorf("step limit of %d exceeded", vm.MaxSteps)
+		}
+		vm.Steps++
+		pc := vm.PC
+		if err := vm.Step(); err != nil {
+			return fmt.Errorf("runtime error at instruction %d (%s): %v", pc, vm.Program[pc], err)
+		}
+	}
+	return nil
+}
+
+// Step executes the instruction at PC.
+func (vm *VM) Step() error {
+	instr := vm.Program[vm.PC]
+	next := vm.PC + 1
+
+	switch instr.Op {
+	case parir.OpNop, parir.OpLabel:
+	case parir.OpHalt:
+		vm.Halted = true
+	case parir.OpPush:
+		arg := instr.Arg
+		switch arg.Kind {
+		case parir.KindInt, parir.KindFloat, parir.KindColour:
+			n, _ := arg.Number()
+			vm.push(Value(n))
+		case parir.KindPC:
+			vm.push(Value(vm.PC + arg.Int))
+		case parir.KindLabel:
+			vm.push(Value(vm.Labels[arg.Name]))
+		case parir.KindSlot:
+			val, err := vm.load(arg.Index, arg.Level)
+			if err != nil {
+				return err
+			}
+			vm.push(val)
+		case parir.KindIndexedSlot:
+			off, err := vm.popInt()
+			if err != nil {
+				return err
+			}
+			val, err := vm.load(arg.Index+off, arg.Level)
+			if err != nil {
+				return err
+			}
+			vm.push(val)
+		default:
+			return fmt.Errorf("push needs an operand")
+		}
+	case parir.OpPushA:
+		if instr.Arg.Kind != parir.KindSlot {
+			return fmt.Errorf("pusha needs a frame slot operand")
+		}
+		n, err := vm.popInt()
+		if err != nil {
+			return err
+		}
+		// element 0 ends up on top, mirroring how array literals are pushed
+		for i := n - 1; i >= 0; i-- {
+			val, err := vm.load(instr.Arg.Index+i, instr.Arg.Level)
+			if err != nil {
+				return err
+			}
+			vm.push(val)
+		}
+	case parir.OpSt:
+		l, err := vm.popInt()
+		if err != nil {
+			return err
+		}
+		i, err := vm.popInt()
+		if err != nil {
+			return err
+		}
+		val, err := vm.pop()
+		if err != nil {
+			return err
+		}
+		if err := vm.store(i, l, val); err != nil {
+			return err
+		}
+	case parir.OpStA:
+		l, err := vm.popInt()
+		if err != nil {
+			return err
+		}
+		i, err := vm.popInt()
+		if err != nil {
+			return err
+		}
+		n, err := vm.popInt()
+		if err != nil {
+			return err
+		}
+		vals, err := vm.popN(n)
+		if err != nil {
+			return err
+		}
+		for k, val := range vals {
+			if err := vm.store(i+k, l, val); err != nil {
+				return err
+			}
+		}
+	case parir.OpDrop:
+		if _, err := vm.pop(); err != nil {
+			return err
+		}
+	case parir.OpDup:
+		val, err := vm.pop()
+		if err != nil {
+			return err
+		}
+		vm.push(val)
+		vm.push(val)
+
+	// ===== Frames =====
+	case parir.OpOFrame:
+		n, err := vm.popInt()
+		if err != nil {
+			return err
+		}
+		vm.Frames = append(vm.Frames, make([]Value, n))
+	case parir.OpCFrame:
+		if len(vm.Frames) == 0 {
+			return fmt.Errorf("cframe with no open frame")
+		}
+		vm.Frames = vm.Frames[:len(vm.Frames)-1]
+	case parir.OpAlloc:
+		n, err := vm.popInt()
+		if err != nil {
+			return err
+		}
+		if len(vm.Frames) == 0 {
+			return fmt.Errorf("alloc with no open frame")
+		}
+		top := len(vm.Frames) - 1
+		vm.Frames[top] = append(vm.Frames[top], make([]Value, n)...)
+
+	// ===== Control flow =====
+	case parir.OpJmp:
+		addr, err := vm.popInt()
+		if err != nil {
+			return err
+		}
+		return vm.jump(addr)
+	case parir.OpCJmp:
+		addr, err := vm.popInt()
+		if err != nil {
+			return err
+		}
+		cond, err := vm.pop()
+		if err != nil {
+			return err
+		}
+		if cond.truthy() {
+			return vm.jump(addr)
+		}
+	case parir.OpCall:
+		addr, err := vm.popInt()
+		if err != nil {
+			return err
+		}
+		n, err := vm.popInt()
+		if err != nil {
+			return err
+		}
+		args, err := vm.popN(n)
+		if err != nil {
+			return err
+		}
+		vm.Frames = append(vm.Frames, args)
+		vm.Calls = append(vm.Calls, next)
+		return vm.jump(addr)
+	case parir.OpRet, parir.OpRetA:
+		if instr.Op == parir.OpRetA {
+			// the array items stay on the operand stack, only the count goes
+			if _, err := vm.pop(); err != nil {
+				return err
+			}
+		}
+		if len(vm.Frames) == 0 {
+			return fmt.Errorf("%s with no open frame", instr.Op)
+		}
+		if len(vm.Calls) == 0 {
+			return fmt.Errorf("%s outside of a function call", instr.Op)
+		}
+		vm.Frames = vm.Frames[:len(vm.Frames)-1]
+		addr := vm.Calls[len(vm.Calls)-1]
+		vm.Calls = vm.Calls[:len(vm.Calls)-1]
+		return vm.jump(addr)
+
+	// ===== Arithmetic and logic =====
+	case parir.OpAdd, parir.OpSub, parir.OpMul, parir.OpDiv, parir.OpMod, parir.OpMax, parir.OpMin,
+		parir.OpLt, parir.OpLe, parir.OpGt, parir.OpGe, parir.OpEq, parir.OpAnd, parir.OpOr:
+		a, err := vm.pop()
+		if err != nil {
+			return err
+		}
+		b, err := vm.pop()
+		if err != nil {
+			return err
+		}
+		res, err := BinaryOp(instr.Op, a, b)
+		if err != nil {
+			return err
+		}
+		vm.push(res)
+	case parir.OpNot, parir.OpInc, parir.OpDec:
+		a, err := vm.pop()
+		if err != nil {
+			return err
+		}
+		res, err := UnaryOp(instr.Op, a)
+		if err != nil {
+			return err
+		}
+		vm.push(res)
+	case parir.OpIRnd:
+		max, err := vm.popInt()
+		if err != nil {
+			return err
+		}
+		if max <= 0 {
+			vm.push(0)
+		} else {
+			vm.push(Value(vm.Rand.Intn(max)))
+		}
+
+	// ===== Output and pad =====
+	case parir.OpPrint:
+		val, err := vm.pop()
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(vm.Out, val)
+	case parir.OpPrintA:
+		n, err := vm.popInt()
+		if err != nil {
+			return err
+		}
+		vals, err := vm.popN(n)
+		if err != nil {
+			return err
+		}
+		items := make([]string, len(vals))
+		for i, val := range vals {
+			items[i] = val.String()
+		}
+		fmt.Fprintf(vm.Out, "[%s]\n", strings.Join(items, ", "))
+	case parir.OpDelay:
+		ms, err := vm.popInt()
+		if err != nil {
+			return err
+		}
+		if vm.Sleep != nil && ms > 0 {
+			vm.Sleep(time.Duration(ms) * time.Millisecond)
+		}
+	case parir.OpWidth:
+		vm.push(Value(vm.Pad.Width))
+	case parir.OpHeight:
+		vm.push(Value(vm.Pad.Height))
+	case parir.OpClear:
+		c, err := vm.popInt()
+		if err != nil {
+			return err
+		}
+		vm.Pad.Clear(c)
+	case parir.OpWrite:
+		vals, err := vm.popN(3)
+		if err != nil {
+			return err
+		}
+		vm.Pad.Write(int(vals[0]), int(vals[1]), int(vals[2]))
+	case parir.OpWriteBox:
+		vals, err := vm.popN(5)
+		if err != nil {
+			return err
+		}
+		vm.Pad.WriteBox(int(vals[0]), int(vals[1]), int(vals[2]), int(vals[3]), int(vals[4]))
+	case parir.OpRead:
+		vals, err := vm.popN(2)
+		if err != nil {
+			return err
+		}
+		vm.push(Value(vm.Pad.Read(int(vals[0]), int(vals[1]))))
+	default:
+		return fmt.Errorf("cannot run %s", instr)
+	}
+
+	vm.PC = next
+	return nil
+}
+
+// BinaryOp applies the two-operand instruction op to a (popped first) and b
+// (popped second).
+func BinaryOp(op parir.Op, a, b Value) (Value, error) {
+	switch op {
+	case parir.OpAdd:
+		return a + b, nil
+	case parir.OpSub:
+		return a - b, nil
+	case parir.OpMul:
+		return a * b, nil
+	case parir.OpDiv:
+		if b == 0 {
+			return 0, fmt.Errorf("division by zero")
+		}
+		return a / b, nil
+	case parir.OpMod:
+		if b == 0 {
+			return 0, fmt.Errorf("division by zero")
+		}
+		return Value(math.Mod(float64(a), float64(b))), nil
+	case parir.OpMax:
+		return Value(math.Max(float64(a), float64(b))), nil
+	case parir.OpMin:
+		return Value(math.Min(float64(a), float64(b))), nil
+	case parir.OpLt:
+		return boolValue(a < b), nil
+	case parir.OpLe:
+		return boolValue(a <= b), nil
+	case parir.OpGt:
+		return boolValue(a > b), nil
+	case parir.OpGe:
+		return boolValue(a >= b), nil
+	case parir.OpEq:
+		return boolValue(a == b), nil
+	case parir.OpAnd:
+		return boolValue(a.truthy() && b.truthy()), nil
+	case parir.OpOr:
+		return boolValue(a.truthy() || b.truthy()), nil
+	}
+	return 0, fmt.Errorf("%s is not an operator", op)
+}
+
+// UnaryOp applies the one-operand instruction op to a.
+func UnaryOp(op parir.Op, a Value) (Value, error) {
+	switch op {
+	case parir.OpNot:
+		return boolValue(!a.truthy()), nil
+	case parir.OpInc:
+		return a + 1, nil
+	case parir.OpDec:
+		return a - 1, nil
+	}
+	return 0, fmt.Errorf("%s is not an operator", op)
+}
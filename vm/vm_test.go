@@ -0,0 +1,133 @@
+package vm
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/giuszeppe/compiler-theory/codegen"
+	"github.com/giuszeppe/compiler-theory/diag"
+	"github.com/giuszeppe/compiler-theory/ir"
+	"github.com/giuszeppe/compiler-theory/parir"
+	"github.com/giuszeppe/compiler-theory/parser"
+	"github.com/giuszeppe/compiler-theory/sema"
+)
+
+func runProgram(t *testing.T, program string) (*VM, string) {
+	p := parser.NewParser(program)
+	grammar := parser.NewGrammar()
+	rootAST, err := p.Parse(grammar)
+	if err != nil {
+		t.Fatalf("Failed to parse program: %v", err)
+	}
+	if diags := sema.NewSemanticVisitor().Analyze(rootAST); diag.HasErrors(diags) {
+		t.Fatalf("Failed to check program: %v", diags)
+	}
+	instrs := codegen.Generate(ir.Build(rootAST))
+
+	vm, err := NewVM(instrs)
+	if err != nil {
+		t.Fatalf("Failed to load program: %v", err)
+	}
+	var out bytes.Buffer
+	vm.Out = &out
+	vm.MaxSteps = 100000
+	if err := vm.Run(); err != nil {
+		var code strings.Builder
+		parir.Print(&code, instrs)
+		t.Fatalf("Failed to run program: %v\n%s", err, code.String())
+	}
+	return vm, out.String()
+}
+
+func expectOutput(t *testing.T, got string, expected ...string) {
+	want := strings.Join(expected, "\n") + "\n"
+	if got != want {
+		t.Errorf("Expected output %q, got %q", want, got)
+	}
+}
+
+func TestVMArithmetic(t *testing.T) {
+	_, out := runProgram(t, `let x:int = 7; let y:int = x * 2 - 3; __print y; __print -x; __print x < y;`)
+	expectOutput(t, out, "11", "-7", "1")
+}
+
+func TestVMFunctionCall(t *testing.T) {
+	_, out := runProgram(t, `fun add(a:int, b:int) -> int { return a + b; } let z:int = add(3, 4); __print z;`)
+	expectOutput(t, out, "7")
+}
+
+func TestVMNestedReturn(t *testing.T) {
+	_, out := runProgram(t, `fun a() -> int { if (1 < 2) { return 0; } return 1; } let b:int = a(); __print b;`)
+	expectOutput(t, out, "0")
+}
+
+func TestVMIfElse(t *testing.T) {
+	_, out := runProgram(t, `let x:int = 5; if (x > 10) { __print 1; } else { __print 2; } __print 3;`)
+	expectOutput(t, out, "2", "3")
+}
+
+func TestVMWhileLoop(t *testing.T) {
+	_, out := runProgram(t, `let i:int = 0; while (i < 3) { __print i; i = i + 1; }`)
+	expectOutput(t, out, "0", "1", "2")
+}
+
+func TestVMForLoop(t *testing.T) {
+	_, out := runProgram(t, `for (let i:int = 0; i < 3; i = i + 1) { __print i; }`)
+	expectOutput(t, out, "0", "1", "2")
+}
+
+func TestVMArrayAccess(t *testing.T) {
+	_, out := runProgram(t, `let xs:int[] = [4, 5, 6]; __print xs[2]; __print xs;`)
+	expectOutput(t, out, "6", "[4, 5, 6]")
+}
+
+func TestVMPad(t *testing.T) {
+	vm, out := runProgram(t, `__clear #000011; __write 1, 2, #ff0000; __write_box 3, 3, 2, 2, #00ff00; __print __read(1, 2); __print __width;`)
+	expectOutput(t, out, "16711680", "36")
+	if c := vm.Pad.Read(4, 4); c != 0x00ff00 {
+		t.Errorf("Expected pixel (4, 4) to be #00ff00, got #%06x", c)
+	}
+	if c := vm.Pad.Read(0, 0); c != 0x000011 {
+		t.Errorf("Expected pixel (0, 0) to be #000011, got #%06x", c)
+	}
+}
+
+func TestVMRandomInt(t *testing.T) {
+	_, out := runProgram(t, `let r:int = __random_int(5); __print r < 5;`)
+	expectOutput(t, out, "1")
+}
+
+func TestVMUndefinedLabel(t *testing.T) {
+	prog, _ := parir.Parse(".main\npush 0\npush .missing\ncall")
+	_, err := NewVM(prog)
+	if err == nil {
+		t.Fatalf("Expected an error for an undefined label")
+	}
+}
+
+func TestVMStackUnderflow(t *testing.T) {
+	vm, err := NewVM([]parir.Instruction{{Op: parir.OpLabel, Arg: parir.Label("main")}, {Op: parir.OpAdd}})
+	if err != nil {
+		t.Fatalf("Failed to load program: %v", err)
+	}
+	if err := vm.Run(); err == nil {
+		t.Fatalf("Expected a runtime error on an empty operand stack")
+	}
+}
+
+func TestVMLoopConditions(t *testing.T) {
+	_, out := runProgram(t, `for (let i:int = 0; i < 1 + 2; i = i + 1) { __print i; }
+		let j:int = 0; while (not (j >= 2)) { j = j + 1; __print j; }`)
+	expectOutput(t, out, "0", "1", "2", "1", "2")
+}
+
+func TestVMArrayElementAssignment(t *testing.T) {
+	_, out := runProgram(t, `let xs:int[3] = [4, 5, 6]; let i:int = 2; xs[i] = 9; xs[0] = xs[1]; __print xs;`)
+	expectOutput(t, out, "[5, 5, 9]")
+}
+
+func TestVMNestedBlocks(t *testing.T) {
+	_, out := runProgram(t, `let x:int = 1; { let y:int = x + 1; { x = y * 10; } __print y; } __print x != 20; __print x;`)
+	expectOutput(t, out, "2", "0", "20")
+}